@@ -0,0 +1,298 @@
+// Command sdptool inspects and rewrites SDP session descriptions.
+//
+// Usage:
+//
+//	sdptool lint [-profile rfc8866|jsep|sip] file
+//	sdptool fmt file
+//	sdptool diff old new
+//	sdptool json file
+//	sdptool yaml file
+//	sdptool convert [-to unified|plan-b] [-strip-candidates] file
+//	sdptool schema
+//
+// A file argument of "-" reads from standard input.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const (
+	candidateAttribute       = "candidate"
+	endOfCandidatesAttribute = "end-of-candidates"
+)
+
+var errViolations = errors.New("validation failed")
+
+var profiles = map[string]sdp.ValidationProfile{
+	"rfc8866": sdp.ProfileRFC8866,
+	"jsep":    sdp.ProfileJSEP,
+	"sip":     sdp.ProfileSIP,
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, errViolations) {
+		fmt.Fprintln(os.Stderr, "sdptool:", err)
+	}
+	os.Exit(1)
+}
+
+func usage() error {
+	return fmt.Errorf("usage: sdptool lint|fmt|diff|json|yaml|convert|schema [flags] file...")
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return usage()
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "lint":
+		return lint(args, stdin, stdout)
+	case "fmt":
+		return format(args, stdin, stdout)
+	case "diff":
+		return diff(args, stdin, stdout)
+	case "json":
+		return dumpJSON(args, stdin, stdout)
+	case "yaml":
+		return dumpYAML(args, stdin, stdout)
+	case "convert":
+		return convert(args, stdin, stdout)
+	case "schema":
+		return schema(args, stdout)
+	}
+	return usage()
+}
+
+func parseFlags(set *flag.FlagSet, args []string, files int) ([]string, error) {
+	set.SetOutput(io.Discard)
+	if err := set.Parse(args); err != nil {
+		return nil, err
+	}
+	if set.NArg() != files {
+		return nil, fmt.Errorf("%v: expected %v file arguments, got %v", set.Name(), files, set.NArg())
+	}
+	stdin := 0
+	for _, name := range set.Args() {
+		if name == "-" {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return nil, fmt.Errorf("%v: standard input (-) can be given only once", set.Name())
+	}
+	return set.Args(), nil
+}
+
+func readFile(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func decodeFile(name string, stdin io.Reader) (*sdp.Session, []byte, error) {
+	data, err := readFile(name, stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, err := sdp.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", name, err)
+	}
+	return s, data, nil
+}
+
+func lint(args []string, stdin io.Reader, stdout io.Writer) error {
+	set := flag.NewFlagSet("lint", flag.ContinueOnError)
+	profileName := set.String("profile", "rfc8866", "validation profile: rfc8866, jsep or sip")
+	files, err := parseFlags(set, args, 1)
+	if err != nil {
+		return err
+	}
+	profile, ok := profiles[*profileName]
+	if !ok {
+		return fmt.Errorf("unknown profile %v", *profileName)
+	}
+
+	s, data, err := decodeFile(files[0], stdin)
+	if err != nil {
+		return err
+	}
+
+	err = s.Validate(profile)
+	var violations sdp.ValidationErrors
+	if !errors.As(err, &violations) {
+		return err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for _, violation := range violations {
+		fmt.Fprintf(stdout, "%v:%v: %v\n", files[0], violationLine(lines, violation), violation)
+	}
+	return errViolations
+}
+
+// violationLine returns the 1-based number of the line a violation refers
+// to: the offending line if it exists, otherwise the first line of the
+// section it is missing from.
+func violationLine(lines []string, violation *sdp.ValidationError) int {
+	var mediaLines []int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "m=") {
+			mediaLines = append(mediaLines, i)
+		}
+	}
+
+	start, end := 0, len(lines)
+	if violation.Media == sdp.SessionLevel {
+		if len(mediaLines) > 0 {
+			end = mediaLines[0]
+		}
+	} else if violation.Media < len(mediaLines) {
+		start = mediaLines[violation.Media]
+		if violation.Media+1 < len(mediaLines) {
+			end = mediaLines[violation.Media+1]
+		}
+	}
+
+	if violation.Field != 0 {
+		prefix := string(violation.Field) + "=" + violation.Attribute
+		for i := start; i < end; i++ {
+			line := lines[i]
+			if !strings.HasPrefix(line, prefix) {
+				continue
+			}
+			if violation.Attribute == "" || len(line) == len(prefix) || line[len(prefix)] == ':' {
+				return i + 1
+			}
+		}
+	}
+
+	return start + 1
+}
+
+func format(args []string, stdin io.Reader, stdout io.Writer) error {
+	files, err := parseFlags(flag.NewFlagSet("fmt", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	s, _, err := decodeFile(files[0], stdin)
+	if err != nil {
+		return err
+	}
+	return sdp.NewEncoder(stdout).Encode(s)
+}
+
+func diff(args []string, stdin io.Reader, stdout io.Writer) error {
+	files, err := parseFlags(flag.NewFlagSet("diff", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	a, _, err := decodeFile(files[0], stdin)
+	if err != nil {
+		return err
+	}
+	b, _, err := decodeFile(files[1], stdin)
+	if err != nil {
+		return err
+	}
+	for _, change := range sdp.Diff(a, b) {
+		fmt.Fprintln(stdout, change)
+	}
+	return nil
+}
+
+func dumpJSON(args []string, stdin io.Reader, stdout io.Writer) error {
+	files, err := parseFlags(flag.NewFlagSet("json", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	s, _, err := decodeFile(files[0], stdin)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+func dumpYAML(args []string, stdin io.Reader, stdout io.Writer) error {
+	files, err := parseFlags(flag.NewFlagSet("yaml", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	s, _, err := decodeFile(files[0], stdin)
+	if err != nil {
+		return err
+	}
+	data, err := sdp.MarshalYAML(s)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(data)
+	return err
+}
+
+func convert(args []string, stdin io.Reader, stdout io.Writer) error {
+	set := flag.NewFlagSet("convert", flag.ContinueOnError)
+	to := set.String("to", "", "target plan: unified or plan-b")
+	stripCandidates := set.Bool("strip-candidates", false, "remove ICE candidates")
+	files, err := parseFlags(set, args, 1)
+	if err != nil {
+		return err
+	}
+	s, _, err := decodeFile(files[0], stdin)
+	if err != nil {
+		return err
+	}
+
+	switch *to {
+	case "":
+	case "unified":
+		s, err = sdp.ToUnifiedPlan(s)
+	case "plan-b":
+		s, err = sdp.ToPlanB(s)
+	default:
+		err = fmt.Errorf("unknown plan %v", *to)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *stripCandidates {
+		s.Attributes.Remove(candidateAttribute, endOfCandidatesAttribute)
+		for _, desc := range s.MediaDescs {
+			desc.Attributes.Remove(candidateAttribute, endOfCandidatesAttribute)
+		}
+	}
+
+	return sdp.NewEncoder(stdout).Encode(s)
+}
+
+func schema(args []string, stdout io.Writer) error {
+	if _, err := parseFlags(flag.NewFlagSet("schema", flag.ContinueOnError), args, 0); err != nil {
+		return err
+	}
+	data, err := sdp.JSONSchema()
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(data)
+	return err
+}
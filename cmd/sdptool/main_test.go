@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const offer = `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+a=group:BUNDLE 0
+m=audio 9 UDP/TLS/RTP/SAVPF 0
+a=mid:0
+a=setup:client
+a=candidate:1 1 udp 2122260223 192.168.0.2 54321 typ host
+a=rtcp-mux
+`
+
+func writeFile(t *testing.T, data string) string {
+	name := filepath.Join(t.TempDir(), "offer.sdp")
+	if err := os.WriteFile(name, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLint(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"lint", "-profile", "jsep", "-"}, strings.NewReader(offer), &out)
+	if !errors.Is(err, errViolations) {
+		t.Fatalf("violations were expected, got %v", err)
+	}
+
+	expected := `-:7: media 0 a=ice-ufrag: ice-ufrag is required
+-:7: media 0 a=ice-pwd: ice-pwd is required
+-:7: media 0 a=fingerprint: fingerprint is required
+-:9: media 0 a=setup: wrong setup role client
+`
+	if out.String() != expected {
+		t.Fatal(cmp.Diff(out.String(), expected))
+	}
+
+	out.Reset()
+	if err := run([]string{"lint", "-"}, strings.NewReader(offer), &out); err != nil || out.Len() != 0 {
+		t.Fatalf("no violations were expected: %v, %v", err, out.String())
+	}
+}
+
+func TestConvert(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"convert", "-strip-candidates", writeFile(t, offer)}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "a=candidate") || !strings.Contains(out.String(), "a=rtcp-mux") {
+		t.Fatalf("wrong converted session: %v", out.String())
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var out bytes.Buffer
+	a := writeFile(t, offer)
+	b := writeFile(t, strings.Replace(offer, "o=- 0 1", "o=- 0 2", 1))
+	if err := run([]string{"diff", a, b}, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "session: origin version changed: 1 -> 2\n" {
+		t.Fatalf("wrong diff: %v", out.String())
+	}
+
+	err := run([]string{"diff", "-", "-"}, strings.NewReader(offer), &out)
+	if err == nil || err.Error() != "diff: standard input (-) can be given only once" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	var out bytes.Buffer
+	if err := run([]string{"yaml", "-"}, strings.NewReader(offer), &out); err != nil {
+		t.Fatal(err)
+	}
+	var sess sdp.Session
+	if err := sdp.UnmarshalYAML(out.Bytes(), &sess); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := sdp.NewDecoder(strings.NewReader(offer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(&sess, expected) {
+		t.Fatal(cmp.Diff(&sess, expected))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}, {"fmt"}, {"lint", "-profile", "x", "-"}} {
+		if err := run(args, strings.NewReader(offer), &bytes.Buffer{}); err == nil {
+			t.Fatalf("%v: error was expected", args)
+		}
+	}
+}
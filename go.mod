@@ -2,4 +2,7 @@ module github.com/nostressdev/webrtc
 
 go 1.18
 
-require github.com/google/go-cmp v0.5.8
+require (
+	github.com/google/go-cmp v0.5.8
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,131 @@
+package rtsp
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// authenticator answers the Basic or Digest challenge of a server (RFC 2617).
+type authenticator struct {
+	username string
+	password string
+
+	digest bool
+	realm  string
+	nonce  string
+	opaque string
+	qop    string
+	nc     int
+}
+
+// newAuthenticator picks the Digest challenge of the WWW-Authenticate
+// headers if there is one, the Basic one otherwise.
+func newAuthenticator(username, password string, challenges []string) (*authenticator, error) {
+	var basic *authenticator
+	for _, challenge := range challenges {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+		switch strings.ToLower(scheme) {
+		case "digest":
+			values := parseAuthParams(params)
+			if algorithm := values["algorithm"]; algorithm != "" && !strings.EqualFold(algorithm, "MD5") {
+				continue
+			}
+			auth := &authenticator{username: username, password: password, digest: true,
+				realm: values["realm"], nonce: values["nonce"], opaque: values["opaque"]}
+			for _, qop := range strings.Split(values["qop"], ",") {
+				if strings.TrimSpace(qop) == "auth" {
+					auth.qop = "auth"
+				}
+			}
+			return auth, nil
+		case "basic":
+			basic = &authenticator{username: username, password: password}
+		}
+	}
+	if basic == nil {
+		return nil, fmt.Errorf("no supported authentication scheme in %q", challenges)
+	}
+	return basic, nil
+}
+
+// authorization returns the Authorization header of a request.
+func (a *authenticator) authorization(method, uri string) (string, error) {
+	if !a.digest {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.username+":"+a.password)), nil
+	}
+
+	ha1 := md5Hex(a.username + ":" + a.realm + ":" + a.password)
+	ha2 := md5Hex(method + ":" + uri)
+	params := []string{
+		authParam("username", a.username),
+		authParam("realm", a.realm),
+		authParam("nonce", a.nonce),
+		authParam("uri", uri),
+	}
+	var response string
+	if a.qop != "" {
+		a.nc++
+		nc := fmt.Sprintf("%08x", a.nc)
+		cnonce, err := newCnonce()
+		if err != nil {
+			return "", err
+		}
+		response = md5Hex(ha1 + ":" + a.nonce + ":" + nc + ":" + cnonce + ":" + a.qop + ":" + ha2)
+		params = append(params, "qop="+a.qop, "nc="+nc, authParam("cnonce", cnonce))
+	} else {
+		response = md5Hex(ha1 + ":" + a.nonce + ":" + ha2)
+	}
+	params = append(params, authParam("response", response))
+	if a.opaque != "" {
+		params = append(params, authParam("opaque", a.opaque))
+	}
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+// newCnonce is a variable so that tests can fix the client nonce.
+var newCnonce = func() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func md5Hex(value string) string {
+	sum := md5.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func authParam(name, value string) string {
+	return name + `="` + value + `"`
+}
+
+// parseAuthParams parses the comma separated name=value pairs of a challenge,
+// with values optionally quoted.
+func parseAuthParams(value string) map[string]string {
+	res := make(map[string]string)
+	for {
+		value = strings.TrimLeft(value, " ,")
+		name, rest, ok := strings.Cut(value, "=")
+		if !ok {
+			return res
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				res[name] = rest[1:]
+				return res
+			}
+			res[name], value = rest[1:end+1], rest[end+2:]
+		} else {
+			res[name], value, _ = strings.Cut(rest, ",")
+			res[name] = strings.TrimSpace(res[name])
+		}
+	}
+}
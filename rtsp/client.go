@@ -0,0 +1,638 @@
+// Package rtsp implements an RTSP 1.0 client (RFC 2326) that receives RTP
+// streams described with the sdp package.
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const (
+	DefaultPort      = 554
+	DefaultTimeout   = 10 * time.Second
+	DefaultUserAgent = "webrtc-rtsp"
+
+	// defaultSessionTimeout is the session timeout of RFC 2326 if the
+	// server does not give one.
+	defaultSessionTimeout = 60 * time.Second
+)
+
+// TransportMode is the lower transport of the RTP packets of a track.
+type TransportMode int
+
+const (
+	// TransportTCP interleaves the packets with the RTSP messages.
+	TransportTCP TransportMode = iota
+	TransportUDP
+)
+
+// Client is a connection to an RTSP server. Its methods must not be called
+// concurrently, except for Close and the methods of its tracks.
+type Client struct {
+	// Username and Password answer the Basic or Digest challenge of the
+	// server. Dial takes them from the user info of the URL.
+	Username string
+	Password string
+	// Timeout is how long to wait for a response, DefaultTimeout if zero.
+	Timeout   time.Duration
+	UserAgent string
+
+	conn    net.Conn
+	url     *url.URL
+	auth    *authenticator
+	cseq    int
+	session string
+	// sessionTimeout is the timeout the server gave with the session.
+	sessionTimeout time.Duration
+
+	description *sdp.Session
+	base        *url.URL
+
+	tracksMu sync.Mutex
+	tracks   []*Track
+
+	writeMu   sync.Mutex
+	responses chan *response
+	closeOnce sync.Once
+	closed    chan struct{}
+	err       error
+}
+
+// Track is a media description set up by the client.
+type Track struct {
+	Media *sdp.MediaDesc
+	URL   *url.URL
+	Mode  TransportMode
+
+	client  *Client
+	channel int
+	rtp     net.PacketConn
+	rtcp    net.PacketConn
+	// source and rtcpSource are the addresses the server sends UDP packets
+	// from. Their ports are 0 when the server did not give them.
+	source     *net.UDPAddr
+	rtcpSource *net.UDPAddr
+	packets    chan []byte
+	stats      *receiverStats
+}
+
+// packetQueueLength is the number of received packets a track buffers.
+// Packets are dropped when a track is not read fast enough, rather than
+// blocking the RTSP connection.
+const packetQueueLength = 256
+
+// Dial connects to the server of an rtsp:// URL.
+func Dial(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "rtsp" {
+		return nil, fmt.Errorf("wrong rtsp url scheme: %v", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), strconv.Itoa(DefaultPort))
+	}
+	conn, err := net.DialTimeout("tcp", host, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewClient(conn, u)
+	if u.User != nil {
+		c.Username = u.User.Username()
+		c.Password, _ = u.User.Password()
+	}
+	return c, nil
+}
+
+// NewClient returns a client for the URL over an established connection. The
+// user info of the URL is not sent to the server.
+func NewClient(conn net.Conn, u *url.URL) *Client {
+	res := *u
+	res.User = nil
+	c := &Client{
+		conn:      conn,
+		url:       &res,
+		responses: make(chan *response, 8),
+		closed:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(conn))
+	return c
+}
+
+// Close closes the connection and the UDP sockets of the tracks without a
+// TEARDOWN.
+func (c *Client) Close() error {
+	c.fail(net.ErrClosed)
+	return nil
+}
+
+func (c *Client) fail(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.closed)
+		c.conn.Close()
+		c.tracksMu.Lock()
+		for _, track := range c.tracks {
+			track.close()
+		}
+		c.tracksMu.Unlock()
+	})
+}
+
+// readLoop reads the responses and the interleaved packets until the
+// connection fails.
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if b[0] == '$' {
+			channel, data, err := readInterleaved(r)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			c.deliver(channel, data)
+			continue
+		}
+
+		res, err := readMessage(r)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if res.method != "" {
+			if err := c.answer(res); err != nil {
+				c.fail(err)
+				return
+			}
+			continue
+		}
+		select {
+		case c.responses <- res:
+		default:
+			// Nobody waits for so many responses.
+		}
+	}
+}
+
+// answer replies to a request of the server: OPTIONS and GET_PARAMETER,
+// which servers send as keep-alives, succeed, and the other methods, e.g.
+// ANNOUNCE, are not implemented.
+func (c *Client) answer(req *response) error {
+	headers := []header{{name: "CSeq", value: req.header.Get("CSeq")}}
+	if session := req.header.Get("Session"); session != "" {
+		headers = append(headers, header{name: "Session", value: session})
+	}
+	statusCode, reason := 200, "OK"
+	switch req.method {
+	case "OPTIONS":
+		headers = append(headers, header{name: "Public", value: "OPTIONS, GET_PARAMETER"})
+	case "GET_PARAMETER":
+	default:
+		statusCode, reason = 501, "Not Implemented"
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout()))
+	return writeResponse(c.conn, statusCode, reason, headers)
+}
+
+// deliver passes an interleaved packet to its track: RTP packets are queued
+// and RTCP packets, on the odd channels, update the reception statistics.
+func (c *Client) deliver(channel int, data []byte) {
+	c.tracksMu.Lock()
+	tracks := c.tracks
+	c.tracksMu.Unlock()
+	for _, track := range tracks {
+		if track.Mode != TransportTCP {
+			continue
+		}
+		switch channel {
+		case track.channel:
+			track.received(data)
+		case track.channel + 1:
+			track.stats.rtcpReceived(data, time.Now())
+		}
+	}
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+// do sends a request and waits for its response, answering an authentication
+// challenge once.
+func (c *Client) do(method string, u *url.URL, header ...header) (*response, error) {
+	res, err := c.roundTrip(method, u, header)
+	if err != nil {
+		return nil, err
+	}
+	if res.statusCode == 401 {
+		c.auth, err = newAuthenticator(c.Username, c.Password, res.header.Values("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+		res, err = c.roundTrip(method, u, header)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if res.statusCode < 200 || res.statusCode > 299 {
+		return nil, fmt.Errorf("rtsp %v failed: %v %v", method, res.statusCode, res.reason)
+	}
+	return res, nil
+}
+
+func (c *Client) roundTrip(method string, u *url.URL, headers []header) (*response, error) {
+	c.cseq++
+	req := &request{method: method, url: u.String()}
+	req.add("CSeq", strconv.Itoa(c.cseq))
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.add("User-Agent", userAgent)
+	if c.auth != nil {
+		authorization, err := c.auth.authorization(method, req.url)
+		if err != nil {
+			return nil, err
+		}
+		req.add("Authorization", authorization)
+	}
+	if c.session != "" {
+		req.add("Session", c.session)
+	}
+	req.header = append(req.header, headers...)
+
+	c.writeMu.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout()))
+	err := req.write(c.conn)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.fail(err)
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.timeout())
+	defer timer.Stop()
+	for {
+		select {
+		case res := <-c.responses:
+			if res.cseq() == c.cseq {
+				return res, nil
+			}
+		case <-c.closed:
+			return nil, c.err
+		case <-timer.C:
+			return nil, fmt.Errorf("rtsp %v: no response in %v", method, c.timeout())
+		}
+	}
+}
+
+// Describe retrieves the session description.
+func (c *Client) Describe() (*sdp.Session, error) {
+	res, err := c.do("DESCRIBE", c.url, header{name: "Accept", value: "application/sdp"})
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := sdp.NewDecoder(bytes.NewReader(res.body)).Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	c.base = c.url
+	for _, name := range []string{"Content-Base", "Content-Location"} {
+		if value := res.header.Get(name); value != "" {
+			if base, err := c.url.Parse(value); err == nil {
+				c.base = base
+				break
+			}
+		}
+	}
+	c.description = s
+	return s, nil
+}
+
+func (c *Client) controlURL() (*url.URL, error) {
+	if c.description == nil {
+		return nil, fmt.Errorf("rtsp: DESCRIBE was not done")
+	}
+	return c.description.ControlURL(c.base)
+}
+
+// Setup sets up a media description of the session returned by Describe,
+// receiving its packets over the given transport.
+func (c *Client) Setup(m *sdp.MediaDesc, mode TransportMode) (*Track, error) {
+	if c.description == nil {
+		return nil, fmt.Errorf("rtsp: DESCRIBE was not done")
+	}
+	u, err := c.description.MediaControlURL(m, c.base)
+	if err != nil {
+		return nil, err
+	}
+
+	track := &Track{Media: m, URL: u, Mode: mode, client: c, packets: make(chan []byte, packetQueueLength)}
+	cname, _, _ := net.SplitHostPort(c.conn.LocalAddr().String())
+	track.stats, err = newReceiverStats(m, cname)
+	if err != nil {
+		return nil, err
+	}
+	var transport string
+	if mode == TransportTCP {
+		c.tracksMu.Lock()
+		track.channel = 2 * len(c.tracks)
+		c.tracksMu.Unlock()
+		transport = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", track.channel, track.channel+1)
+	} else {
+		port, err := track.listenUDP()
+		if err != nil {
+			return nil, err
+		}
+		transport = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", port, port+1)
+	}
+
+	res, err := c.do("SETUP", u, header{name: "Transport", value: transport})
+	if err != nil {
+		track.close()
+		return nil, err
+	}
+	if session := res.header.Get("Session"); session != "" {
+		var params map[string]string
+		c.session, params = parseParams(session)
+		c.sessionTimeout = defaultSessionTimeout
+		if timeout, err := strconv.Atoi(params["timeout"]); err == nil && timeout > 0 {
+			c.sessionTimeout = time.Duration(timeout) * time.Second
+		}
+	}
+	if mode == TransportTCP {
+		// The server may pick other channels.
+		_, params := parseParams(res.header.Get("Transport"))
+		if interleaved, ok := params["interleaved"]; ok {
+			channel, _, err := parsePortRange(interleaved)
+			if err != nil {
+				return nil, err
+			}
+			track.channel = channel
+		}
+	} else {
+		track.source, track.rtcpSource, err = c.serverSource(res.header.Get("Transport"))
+		if err != nil {
+			track.close()
+			return nil, err
+		}
+	}
+
+	c.tracksMu.Lock()
+	select {
+	case <-c.closed:
+		// The client was closed during the SETUP, without the track.
+		c.tracksMu.Unlock()
+		track.close()
+		return nil, c.err
+	default:
+	}
+	c.tracks = append(c.tracks, track)
+	c.tracksMu.Unlock()
+	if mode == TransportUDP {
+		go track.readUDP()
+		go track.readRTCP()
+	}
+	go track.sendReports()
+	return track, nil
+}
+
+// Play starts the delivery of the packets of the tracks, or resumes it
+// where it was paused. While playing, the session must be kept alive with
+// KeepAlive.
+func (c *Client) Play() error {
+	return c.PlayRange(nil)
+}
+
+// PlayRange is like Play but asks for the given range of the presentation,
+// e.g. to seek. A nil range continues from the current position.
+func (c *Client) PlayRange(r *sdp.Range) error {
+	u, err := c.controlURL()
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		_, err = c.do("PLAY", u)
+		return err
+	}
+	_, err = c.do("PLAY", u, header{name: "Range", value: r.String()})
+	return err
+}
+
+func (c *Client) Pause() error {
+	u, err := c.controlURL()
+	if err != nil {
+		return err
+	}
+	_, err = c.do("PAUSE", u)
+	return err
+}
+
+// Teardown ends the session and closes the client.
+func (c *Client) Teardown() error {
+	u, err := c.controlURL()
+	if err != nil {
+		return err
+	}
+	_, err = c.do("TEARDOWN", u)
+	c.Close()
+	return err
+}
+
+// KeepAlive sends an OPTIONS request, which servers take as a sign that the
+// client is still there. It must be called more often than SessionTimeout.
+func (c *Client) KeepAlive() error {
+	_, err := c.do("OPTIONS", c.url)
+	return err
+}
+
+// SessionTimeout returns the time after which the server ends a session
+// without requests, as given in the response to SETUP.
+func (c *Client) SessionTimeout() time.Duration {
+	return c.sessionTimeout
+}
+
+// ReadRTP returns the next RTP packet of the track. It fails once the client
+// is closed.
+func (t *Track) ReadRTP() ([]byte, error) {
+	select {
+	case packet := <-t.packets:
+		return packet, nil
+	case <-t.client.closed:
+		return nil, t.client.err
+	}
+}
+
+// received takes an RTP packet of the server.
+func (t *Track) received(packet []byte) {
+	t.stats.rtpReceived(packet, time.Now())
+	t.queue(packet)
+}
+
+func (t *Track) queue(packet []byte) {
+	select {
+	case t.packets <- packet:
+	default:
+	}
+}
+
+// listenUDP listens on an even port for RTP and the next one for RTCP, and
+// returns the RTP port.
+func (t *Track) listenUDP() (int, error) {
+	for i := 0; i < 16; i++ {
+		rtp, err := net.ListenPacket("udp", ":0")
+		if err != nil {
+			return 0, err
+		}
+		addr, ok := rtp.LocalAddr().(*net.UDPAddr)
+		if !ok {
+			rtp.Close()
+			return 0, fmt.Errorf("rtsp: wrong udp address type: %T", rtp.LocalAddr())
+		}
+		port := addr.Port
+		if port%2 != 0 {
+			rtp.Close()
+			continue
+		}
+		rtcp, err := net.ListenPacket("udp", ":"+strconv.Itoa(port+1))
+		if err != nil {
+			rtp.Close()
+			continue
+		}
+		t.rtp, t.rtcp = rtp, rtcp
+		return port, nil
+	}
+	return 0, fmt.Errorf("rtsp: no free udp port pair")
+}
+
+// serverSource returns the addresses the server sends the RTP and RTCP
+// packets of a UDP track from: the source of the Transport header, or else
+// the address of the server, with the ports of server_port. They are nil if
+// the address of the server is not known.
+func (c *Client) serverSource(transport string) (rtp, rtcp *net.UDPAddr, err error) {
+	_, params := parseParams(transport)
+	rtp, rtcp = &net.UDPAddr{}, &net.UDPAddr{}
+	if source, ok := params["source"]; ok {
+		rtp.IP = net.ParseIP(source)
+	} else if addr, ok := c.conn.RemoteAddr().(*net.TCPAddr); ok {
+		rtp.IP = addr.IP
+	}
+	if rtp.IP == nil {
+		return nil, nil, nil
+	}
+	rtcp.IP = rtp.IP
+	if ports, ok := params["server_port"]; ok {
+		rtp.Port, rtcp.Port, err = parsePortRange(ports)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return rtp, rtcp, nil
+}
+
+func (t *Track) readUDP() {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := t.rtp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if !fromServer(t.source, addr) {
+			continue
+		}
+		t.received(append([]byte(nil), buf[:n]...))
+	}
+}
+
+func (t *Track) readRTCP() {
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := t.rtcp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if !fromServer(t.rtcpSource, addr) {
+			continue
+		}
+		t.stats.rtcpReceived(buf[:n], time.Now())
+	}
+}
+
+// fromServer reports whether a UDP packet from addr was sent by the server
+// from source.
+func fromServer(source *net.UDPAddr, addr net.Addr) bool {
+	if source == nil {
+		return true
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	return ok && source.IP.Equal(udpAddr.IP) && (source.Port == 0 || source.Port == udpAddr.Port)
+}
+
+// sendReports sends a receiver report every rtcpInterval until the client
+// is closed.
+func (t *Track) sendReports() {
+	ticker := time.NewTicker(rtcpInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if report := t.stats.report(now); report != nil {
+				t.writeRTCP(report)
+			}
+		case <-t.client.closed:
+			return
+		}
+	}
+}
+
+// writeRTCP sends an RTCP packet to the server, interleaved or to the
+// second port of server_port. It is not sent over UDP if the server did not
+// give its ports.
+func (t *Track) writeRTCP(packet []byte) {
+	if t.Mode == TransportUDP {
+		if t.rtcpSource != nil && t.rtcpSource.Port != 0 {
+			t.rtcp.WriteTo(packet, t.rtcpSource)
+		}
+		return
+	}
+
+	c := t.client
+	frame := append([]byte{'$', byte(t.channel + 1), byte(len(packet) >> 8), byte(len(packet))}, packet...)
+	c.writeMu.Lock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout()))
+	_, err := c.conn.Write(frame)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.fail(err)
+	}
+}
+
+func (t *Track) close() {
+	if t.rtp != nil {
+		t.rtp.Close()
+		t.rtcp.Close()
+	}
+}
@@ -0,0 +1,509 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const testDescription = "v=0\r\n" +
+	"o=- 1 1 IN IP4 127.0.0.1\r\n" +
+	"s=Camera\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"t=0 0\r\n" +
+	"a=control:*\r\n" +
+	"a=range:npt=0-\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=control:trackID=0\r\n" +
+	"m=audio 0 RTP/AVP 0\r\n" +
+	"a=control:trackID=1\r\n"
+
+type serverRequest struct {
+	method string
+	url    string
+	header textproto.MIMEHeader
+}
+
+// fakeServer is an RTSP server that serves testDescription and sends a sender
+// report and one RTP packet on each track when playing.
+type fakeServer struct {
+	listener net.Listener
+	// udp and rtcp send the packets of UDP tracks from the server_port.
+	udp  net.PacketConn
+	rtcp net.PacketConn
+	// digest makes the server require Digest authentication of user:secret.
+	digest bool
+	status int
+
+	mu       sync.Mutex
+	requests []*serverRequest
+	// replies are the status and CSeq of the responses of the client to
+	// the requests of the server.
+	replies []string
+	// reports are the RTCP packets the client sent.
+	reports [][]byte
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtcp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, udp: udp, rtcp: rtcp, status: 200}
+	t.Cleanup(func() {
+		listener.Close()
+		udp.Close()
+		rtcp.Close()
+	})
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, _, err := rtcp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.reports = append(s.reports, append([]byte(nil), buf[:n]...))
+			s.mu.Unlock()
+		}
+	}()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "rtsp://" + s.listener.Addr().String() + "/stream"
+}
+
+func (s *fakeServer) methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []string
+	for _, req := range s.requests {
+		res = append(res, req.method)
+	}
+	return res
+}
+
+func (s *fakeServer) request(method string) *serverRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.requests {
+		if req.method == method {
+			return req
+		}
+	}
+	return nil
+}
+
+func (s *fakeServer) requestsOf(method string) []*serverRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*serverRequest
+	for _, req := range s.requests {
+		if req.method == method {
+			res = append(res, req)
+		}
+	}
+	return res
+}
+
+// receiverReports waits for a receiver report of the client on each track.
+func (s *fakeServer) receiverReports(t *testing.T, tracks int) [][]byte {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		reports := s.reports
+		s.mu.Unlock()
+		if len(reports) >= tracks {
+			return reports
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no receiver reports")
+	return nil
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reader := textproto.NewReader(r)
+	var tracks []string
+	for {
+		if b, err := r.Peek(1); err == nil && b[0] == '$' {
+			_, data, err := readInterleaved(r)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.reports = append(s.reports, data)
+			s.mu.Unlock()
+			continue
+		}
+		line, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		header, err := reader.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		if strings.HasPrefix(line, "RTSP/1.0 ") {
+			s.mu.Lock()
+			s.replies = append(s.replies, line+" "+header.Get("CSeq"))
+			s.mu.Unlock()
+			continue
+		}
+		fields := strings.Fields(line)
+		req := &serverRequest{method: fields[0], url: fields[1], header: header}
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+
+		response := fmt.Sprintf("RTSP/1.0 %d OK\r\nCSeq: %v\r\n", s.status, header.Get("CSeq"))
+		var body string
+		switch {
+		case s.status != 200:
+		case s.digest && !s.authorized(req):
+			response = fmt.Sprintf("RTSP/1.0 401 Unauthorized\r\nCSeq: %v\r\n", header.Get("CSeq")) +
+				"WWW-Authenticate: Basic realm=\"camera\"\r\n" +
+				"WWW-Authenticate: Digest realm=\"camera\", nonce=\"4f1c2a\", qop=\"auth\", opaque=\"x\"\r\n"
+		case req.method == "DESCRIBE":
+			response += "Content-Base: " + s.url() + "/\r\nContent-Type: application/sdp\r\n"
+			body = testDescription
+		case req.method == "SETUP":
+			transport := header.Get("Transport")
+			tracks = append(tracks, transport)
+			if strings.Contains(transport, "interleaved=2-3") {
+				// The server picks other channels.
+				transport = strings.Replace(transport, "interleaved=2-3", "interleaved=6-7", 1)
+			}
+			if strings.Contains(transport, "client_port") {
+				transport += fmt.Sprintf(";server_port=%d-%d",
+					s.udp.LocalAddr().(*net.UDPAddr).Port, s.rtcp.LocalAddr().(*net.UDPAddr).Port)
+			}
+			response += "Session: 12345678;timeout=30\r\nTransport: " + transport + "\r\n"
+		}
+		if body != "" {
+			response += fmt.Sprintf("Content-Length: %d\r\n", len(body))
+		}
+		if _, err := conn.Write([]byte(response + "\r\n" + body)); err != nil {
+			return
+		}
+
+		if req.method == "PLAY" && s.status == 200 {
+			for i, transport := range tracks {
+				s.sendPacket(conn, transport, testSenderReport(byte(i)), true)
+				s.sendPacket(conn, transport, []byte{0x80, 96, 0, byte(i), 0, 0, 0, 0, 0, 0, 0, byte(i + 1)}, false)
+			}
+		}
+		if req.method == "OPTIONS" {
+			// Keep-alives and an announcement of the server.
+			conn.Write([]byte("GET_PARAMETER " + s.url() + " RTSP/1.0\r\nCSeq: 1\r\nSession: 12345678\r\n\r\n" +
+				"ANNOUNCE " + s.url() + " RTSP/1.0\r\nCSeq: 2\r\nContent-Length: 3\r\n\r\nv=0" +
+				"OPTIONS * RTSP/1.0\r\nCSeq: 3\r\n\r\n"))
+		}
+	}
+}
+
+// testSenderReport is a sender report of the source of track i, with the NTP
+// timestamp 0x00010002_00030004.
+func testSenderReport(i byte) []byte {
+	return []byte{0x80, rtcpSenderReport, 0, 6, 0, 0, 0, i + 1,
+		0, 1, 0, 2, 0, 3, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+}
+
+func (s *fakeServer) sendPacket(conn net.Conn, transport string, packet []byte, rtcp bool) {
+	_, params := parseParams(transport)
+	if interleaved, ok := params["interleaved"]; ok {
+		channel, _, _ := parsePortRange(interleaved)
+		if channel == 2 {
+			channel = 6
+		}
+		if rtcp {
+			channel++
+		}
+		conn.Write(append([]byte{'$', byte(channel), 0, byte(len(packet))}, packet...))
+		return
+	}
+	port, _, _ := parsePortRange(params["client_port"])
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+	if rtcp {
+		addr.Port++
+		s.rtcp.WriteTo(packet, addr)
+		return
+	}
+	// The client must drop packets that do not come from the server_port.
+	if stray, err := net.ListenPacket("udp", "127.0.0.1:0"); err == nil {
+		stray.WriteTo([]byte{0x80, 96, 0, 0xff}, addr)
+		stray.Close()
+	}
+	s.udp.WriteTo(packet, addr)
+}
+
+func (s *fakeServer) authorized(req *serverRequest) bool {
+	value := req.header.Get("Authorization")
+	if !strings.HasPrefix(value, "Digest ") {
+		return false
+	}
+	params := parseAuthParams(value[len("Digest "):])
+	sum := func(value string) string {
+		sum := md5.Sum([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	ha1 := sum("user:camera:secret")
+	ha2 := sum(req.method + ":" + params["uri"])
+	expected := sum(ha1 + ":4f1c2a:" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+	return params["username"] == "user" && params["uri"] == req.url && params["opaque"] == "x" &&
+		params["response"] == expected
+}
+
+func readPacket(t *testing.T, track *Track) []byte {
+	t.Helper()
+	done := make(chan []byte, 1)
+	go func() {
+		packet, err := track.ReadRTP()
+		if err != nil {
+			t.Error(err)
+		}
+		done <- packet
+	}()
+	select {
+	case packet := <-done:
+		return packet
+	case <-time.After(5 * time.Second):
+		t.Fatal("no packet")
+		return nil
+	}
+}
+
+func TestClient(t *testing.T) {
+	defer func(interval time.Duration) { rtcpInterval = interval }(rtcpInterval)
+	rtcpInterval = 20 * time.Millisecond
+
+	for _, mode := range []TransportMode{TransportTCP, TransportUDP} {
+		mode := mode
+		t.Run(fmt.Sprint(mode), func(t *testing.T) {
+			server := newFakeServer(t)
+			server.digest = true
+			c, err := Dial(strings.Replace(server.url(), "rtsp://", "rtsp://user:secret@", 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			s, err := c.Describe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tracks []*Track
+			for _, m := range s.MediaDescs {
+				track, err := c.Setup(m, mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tracks = append(tracks, track)
+			}
+			if tracks[1].URL.String() != server.url()+"/trackID=1" {
+				t.Fatalf("wrong track url: %v", tracks[1].URL)
+			}
+			if c.SessionTimeout() != 30*time.Second {
+				t.Fatalf("wrong session timeout: %v", c.SessionTimeout())
+			}
+
+			play := func(r *sdp.Range) {
+				if err := c.PlayRange(r); err != nil {
+					t.Fatal(err)
+				}
+				for i, track := range tracks {
+					if packet := readPacket(t, track); len(packet) != 12 || packet[3] != byte(i) {
+						t.Fatalf("wrong packet of track %v: %v", i, packet)
+					}
+				}
+			}
+			play(nil)
+			for _, report := range server.receiverReports(t, len(tracks)) {
+				// The report block: the source, the highest sequence number
+				// and the middle of the NTP timestamp of the sender report.
+				if len(report) < 32 || report[1] != rtcpReceiverReport || report[11] == 0 ||
+					report[19] != report[11]-1 || !bytes.Equal(report[24:28], []byte{0, 2, 0, 3}) {
+					t.Fatalf("wrong receiver report: %v", report)
+				}
+			}
+			if err := c.KeepAlive(); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Pause(); err != nil {
+				t.Fatal(err)
+			}
+			play(nil)
+			play(&sdp.Range{Unit: sdp.RangeNPT, Start: 10 * time.Second, HasStart: true})
+			if err := c.Teardown(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tracks[0].ReadRTP(); err == nil {
+				t.Fatal("read after teardown")
+			}
+
+			expected := "DESCRIBE DESCRIBE SETUP SETUP PLAY OPTIONS PAUSE PLAY PLAY TEARDOWN"
+			if methods := strings.Join(server.methods(), " "); methods != expected {
+				t.Fatalf("wrong requests: %v", methods)
+			}
+			server.mu.Lock()
+			replies := strings.Join(server.replies, ", ")
+			server.mu.Unlock()
+			if replies != "RTSP/1.0 200 OK 1, RTSP/1.0 501 Not Implemented 2, RTSP/1.0 200 OK 3" {
+				t.Fatalf("wrong replies: %v", replies)
+			}
+			req := server.request("PLAY")
+			if req.url != server.url()+"/" || req.header.Get("Session") != "12345678" {
+				t.Fatalf("wrong play request: %+v", req)
+			}
+			if strings.Contains(req.url, "user") {
+				t.Fatal("user info was sent")
+			}
+			// Resuming after PAUSE must not restart the stream.
+			var ranges []string
+			for _, req := range server.requestsOf("PLAY") {
+				ranges = append(ranges, req.header.Get("Range"))
+			}
+			if strings.Join(ranges, ",") != ",,npt=10-" {
+				t.Fatalf("wrong play ranges: %q", ranges)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := newFakeServer(t)
+	server.status = 404
+	c, err := Dial(server.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Describe(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if err := c.Play(); err == nil {
+		t.Fatal("play without describe")
+	}
+	c.Close()
+	if err := c.KeepAlive(); err == nil {
+		t.Fatal("request after close")
+	}
+
+	if _, err := Dial("http://example.com/stream"); err == nil {
+		t.Fatal("wrong scheme was accepted")
+	}
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	// The example of RFC 2617, section 3.5.
+	defer func(f func() (string, error)) { newCnonce = f }(newCnonce)
+	newCnonce = func() (string, error) { return "0a4f113b", nil }
+
+	auth, err := newAuthenticator("Mufasa", "Circle Of Life", []string{
+		`Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", ` +
+			`opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Digest username="Mufasa", realm="testrealm@host.com", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", ` +
+		`uri="/dir/index.html", qop=auth, nc=00000001, cnonce="0a4f113b", response="6629fae49393a05397450978507c4ef1", ` +
+		`opaque="5ccc069c403ebaf9f0171e9517f40e41"`
+	if value, err := auth.authorization("GET", "/dir/index.html"); err != nil || value != expected {
+		t.Fatalf("wrong authorization:\n%v\n%v", value, expected)
+	}
+
+	newCnonce = func() (string, error) { return "", errors.New("no randomness") }
+	if _, err := auth.authorization("GET", "/dir/index.html"); err == nil {
+		t.Fatal("authorization without a client nonce")
+	}
+
+	basic, err := newAuthenticator("Aladdin", "open sesame", []string{`Basic realm="WallyWorld"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := basic.authorization("GET", "/"); err != nil || value != "Basic QWxhZGRpbjpvcGVuIHNlc2FtZQ==" {
+		t.Fatalf("wrong authorization: %v", value)
+	}
+
+	if _, err := newAuthenticator("a", "b", []string{`Negotiate`}); err == nil {
+		t.Fatal("unsupported scheme was accepted")
+	}
+}
+
+func TestReceiverStats(t *testing.T) {
+	description, err := sdp.NewDecoder(strings.NewReader(testDescription)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := newReceiverStats(description.MediaDescs[0], "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if stats.report(start) != nil {
+		t.Fatal("report before the first packet")
+	}
+
+	packet := func(seq uint16, timestamp uint32) []byte {
+		return []byte{0x80, 96, byte(seq >> 8), byte(seq), byte(timestamp >> 24), byte(timestamp >> 16),
+			byte(timestamp >> 8), byte(timestamp), 0, 0, 0, 7}
+	}
+	// Two of the six packets are lost across the wrap of the sequence
+	// number.
+	for i, seq := range []uint16{0xfffd, 0xffff, 0x0000, 0x0002} {
+		stats.rtpReceived(packet(seq, uint32(i)*3000), start.Add(time.Duration(i)*time.Second/30))
+	}
+	stats.rtcpReceived(testSenderReport(6), start)
+
+	report := stats.report(start.Add(time.Second))
+	if len(report) != 52 || report[1] != rtcpReceiverReport || report[33] != rtcpSourceDescription {
+		t.Fatalf("wrong report: %v", report)
+	}
+	expected := []byte{
+		0, 0, 0, 7, // source
+		85, 0, 0, 2, // fraction and number lost
+		0, 1, 0, 2, // extended highest sequence number
+		0, 0, 0, 0, // jitter
+		0, 2, 0, 3, // last sender report
+		0, 1, 0, 0, // delay since the last sender report
+	}
+	if !bytes.Equal(report[8:32], expected) {
+		t.Fatalf("wrong report block: %v", report[8:32])
+	}
+	if string(report[42:42+9]) != "127.0.0.1" {
+		t.Fatalf("wrong cname: %q", report[42:])
+	}
+}
@@ -0,0 +1,152 @@
+package rtsp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+const protocol = "RTSP/1.0"
+
+// maxBodySize bounds the body of a response.
+const maxBodySize = 1 << 20
+
+type header struct {
+	name  string
+	value string
+}
+
+type request struct {
+	method string
+	url    string
+	// header is written in order, with names as they are, since some servers
+	// do not accept e.g. "Cseq" for "CSeq".
+	header []header
+}
+
+func (r *request) add(name, value string) {
+	r.header = append(r.header, header{name: name, value: value})
+}
+
+func (r *request) write(w io.Writer) error {
+	return writeMessage(w, r.method+" "+r.url+" "+protocol, r.header)
+}
+
+// writeResponse answers a request of the server.
+func writeResponse(w io.Writer, statusCode int, reason string, header []header) error {
+	return writeMessage(w, protocol+" "+strconv.Itoa(statusCode)+" "+reason, header)
+}
+
+func writeMessage(w io.Writer, startLine string, header []header) error {
+	var b strings.Builder
+	b.WriteString(startLine + "\r\n")
+	for _, h := range header {
+		b.WriteString(h.name + ": " + h.value + "\r\n")
+	}
+	b.WriteString("\r\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// response is a response, or a request sent by the server, which has a
+// method and no status.
+type response struct {
+	method     string
+	statusCode int
+	reason     string
+	header     textproto.MIMEHeader
+	body       []byte
+}
+
+func (r *response) cseq() int {
+	cseq, _ := strconv.Atoi(r.header.Get("CSeq"))
+	return cseq
+}
+
+// readMessage reads a response or a request sent by the server.
+func readMessage(r *bufio.Reader) (*response, error) {
+	reader := textproto.NewReader(r)
+	line, err := reader.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	header, err := reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("wrong rtsp message format: %v", err)
+	}
+
+	var body []byte
+	if value := header.Get("Content-Length"); value != "" {
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 || length > maxBodySize {
+			return nil, fmt.Errorf("wrong rtsp message format: Content-Length %v", value)
+		}
+		body = make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+	}
+
+	if !strings.HasPrefix(line, protocol+" ") {
+		// A request of the server, e.g. a GET_PARAMETER keep-alive.
+		method, _, _ := strings.Cut(line, " ")
+		if method == "" {
+			return nil, fmt.Errorf("wrong rtsp request line: %v", line)
+		}
+		return &response{method: method, header: header, body: body}, nil
+	}
+	status, reason, _ := strings.Cut(line[len(protocol)+1:], " ")
+	statusCode, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("wrong rtsp status line: %v", line)
+	}
+	return &response{statusCode: statusCode, reason: reason, header: header, body: body}, nil
+}
+
+// interleavedHeaderLength is the length of the header of RTP and RTCP packets
+// interleaved with the RTSP messages: '$', the channel and the length.
+const interleavedHeaderLength = 4
+
+func readInterleaved(r *bufio.Reader) (channel int, data []byte, err error) {
+	var header [interleavedHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	data = make([]byte, int(header[2])<<8|int(header[3]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return int(header[1]), data, nil
+}
+
+// parseParams parses the ';' separated parameters of a Transport or Session
+// header into the value before the first ';' and the parameters.
+func parseParams(value string) (string, map[string]string) {
+	fields := strings.Split(value, ";")
+	params := make(map[string]string, len(fields)-1)
+	for _, field := range fields[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		params[strings.ToLower(name)] = value
+	}
+	return strings.TrimSpace(fields[0]), params
+}
+
+// parsePortRange parses "5000-5001" or "5000".
+func parsePortRange(value string) (int, int, error) {
+	first, second, ok := strings.Cut(value, "-")
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, fmt.Errorf("wrong port range: %v", value)
+	}
+	if !ok {
+		return from, from + 1, nil
+	}
+	to, err := strconv.Atoi(second)
+	if err != nil {
+		return 0, 0, fmt.Errorf("wrong port range: %v", value)
+	}
+	return from, to, nil
+}
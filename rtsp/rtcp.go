@@ -0,0 +1,201 @@
+package rtsp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+// RTCP packet types of RFC 3550.
+const (
+	rtcpSenderReport      = 200
+	rtcpReceiverReport    = 201
+	rtcpSourceDescription = 202
+
+	rtcpCNAME = 1
+)
+
+// rtcpInterval is how often a track sends a receiver report. It is a
+// variable so that tests can shorten it.
+var rtcpInterval = 5 * time.Second
+
+// maxRTPDropout is the largest forward jump of a sequence number that is
+// taken as a packet loss rather than a restart of the sender (RFC 3550,
+// appendix A.1).
+const maxRTPDropout = 3000
+
+// receiverStats are the reception statistics of a track, from which its
+// receiver reports are made (RFC 3550, section 6.4.2 and appendix A).
+type receiverStats struct {
+	media *sdp.MediaDesc
+	// ssrc and cname identify the client in its reports.
+	ssrc  uint32
+	cname string
+
+	mu sync.Mutex
+	reception
+	// lastSR is the middle 32 bits of the NTP timestamp of the last sender
+	// report, received at lastSRTime.
+	lastSR     uint32
+	lastSRTime time.Time
+}
+
+// reception are the statistics of the packets of one source, which start
+// again when the source changes.
+type reception struct {
+	started bool
+	source  uint32
+	baseSeq uint32
+	maxSeq  uint16
+	cycles  uint32
+	// received is the number of packets received since the first one.
+	received      uint32
+	expectedPrior uint32
+	receivedPrior uint32
+
+	payloadType uint8
+	clockRate   uint32
+	epoch       time.Time
+	transit     uint32
+	jitter      float64
+}
+
+func newReceiverStats(media *sdp.MediaDesc, cname string) (*receiverStats, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	return &receiverStats{media: media, ssrc: binary.BigEndian.Uint32(b[:]), cname: cname}, nil
+}
+
+// rtpReceived counts an RTP packet received at now.
+func (s *receiverStats) rtpReceived(packet []byte, now time.Time) {
+	if len(packet) < 12 || packet[0]>>6 != 2 {
+		return
+	}
+	seq := binary.BigEndian.Uint16(packet[2:])
+	timestamp := binary.BigEndian.Uint32(packet[4:])
+	ssrc := binary.BigEndian.Uint32(packet[8:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started || ssrc != s.source || seq-s.maxSeq > maxRTPDropout && s.maxSeq-seq > maxRTPDropout {
+		s.reception = reception{started: true, source: ssrc, baseSeq: uint32(seq), maxSeq: seq, epoch: now}
+	} else if delta := seq - s.maxSeq; delta != 0 && delta <= maxRTPDropout {
+		if seq < s.maxSeq {
+			s.cycles += 1 << 16
+		}
+		s.maxSeq = seq
+	}
+	first := s.received == 0
+	s.received++
+
+	clockRate := s.rate(packet[1] & 0x7f)
+	if clockRate == 0 {
+		return
+	}
+	arrival := uint32(now.Sub(s.epoch).Seconds() * float64(clockRate))
+	transit := arrival - timestamp
+	if !first {
+		d := float64(int32(transit - s.transit))
+		s.jitter += (math.Abs(d) - s.jitter) / 16
+	}
+	s.transit = transit
+}
+
+// rate returns the clock rate of a payload type of the track, 0 if it is
+// not known.
+func (s *receiverStats) rate(pt uint8) uint32 {
+	if s.clockRate != 0 && s.payloadType == pt {
+		return s.clockRate
+	}
+	s.payloadType, s.clockRate = pt, 0
+	if s.media != nil {
+		if codec, err := s.media.Codec(pt); err == nil {
+			s.clockRate = codec.ClockRate
+		}
+	}
+	return s.clockRate
+}
+
+// rtcpReceived takes the time of the sender reports of a compound RTCP
+// packet received at now.
+func (s *receiverStats) rtcpReceived(data []byte, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(data) >= 4 {
+		length := (int(binary.BigEndian.Uint16(data[2:])) + 1) * 4
+		if length > len(data) {
+			return
+		}
+		if data[1] == rtcpSenderReport && length >= 28 {
+			s.lastSR = binary.BigEndian.Uint32(data[10:])
+			s.lastSRTime = now
+		}
+		data = data[length:]
+	}
+}
+
+// report returns a compound RTCP packet of a receiver report and the CNAME
+// of the client, nil if no RTP packet was received yet.
+func (s *receiverStats) report(now time.Time) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return nil
+	}
+
+	extendedMax := s.cycles + uint32(s.maxSeq)
+	expected := extendedMax - s.baseSeq + 1
+	lost := int64(expected) - int64(s.received)
+	if lost > 0x7fffff {
+		lost = 0x7fffff
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := s.received - s.receivedPrior
+	s.expectedPrior, s.receivedPrior = expected, s.received
+	var fraction uint8
+	if lostInterval := int64(expectedInterval) - int64(receivedInterval); expectedInterval != 0 && lostInterval > 0 {
+		fraction = uint8(lostInterval << 8 / int64(expectedInterval))
+	}
+	var delay uint32
+	if s.lastSR != 0 {
+		delay = uint32(now.Sub(s.lastSRTime).Seconds() * 65536)
+	}
+
+	res := make([]byte, 32, 32+16+len(s.cname))
+	res[0], res[1] = 0x81, rtcpReceiverReport
+	binary.BigEndian.PutUint16(res[2:], 7)
+	binary.BigEndian.PutUint32(res[4:], s.ssrc)
+	binary.BigEndian.PutUint32(res[8:], s.source)
+	binary.BigEndian.PutUint32(res[12:], uint32(lost)&0xffffff)
+	res[12] = fraction
+	binary.BigEndian.PutUint32(res[16:], extendedMax)
+	binary.BigEndian.PutUint32(res[20:], uint32(s.jitter))
+	binary.BigEndian.PutUint32(res[24:], s.lastSR)
+	binary.BigEndian.PutUint32(res[28:], delay)
+	return appendSourceDescription(res, s.ssrc, s.cname)
+}
+
+// appendSourceDescription appends an SDES packet with the CNAME of ssrc,
+// which every compound RTCP packet must have.
+func appendSourceDescription(b []byte, ssrc uint32, cname string) []byte {
+	if len(cname) > 255 {
+		cname = cname[:255]
+	}
+	// The chunk ends with at least one null octet, padded to 32 bits.
+	chunkLength := (4 + 2 + len(cname) + 4) &^ 3
+	start := len(b)
+	b = append(b, 0x81, rtcpSourceDescription, 0, 0, 0, 0, 0, 0, rtcpCNAME, byte(len(cname)))
+	binary.BigEndian.PutUint32(b[start+4:], ssrc)
+	b = append(b, cname...)
+	b = append(b, make([]byte, chunkLength-6-len(cname))...)
+	binary.BigEndian.PutUint16(b[start+2:], uint16((len(b)-start)/4-1))
+	return b
+}
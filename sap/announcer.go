@@ -0,0 +1,144 @@
+package sap
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const (
+	// DefaultBandwidth is the bandwidth in bits per second that RFC 2974
+	// suggests for all announcements of a scope.
+	DefaultBandwidth = 4000
+
+	minInterval = 300 * time.Second
+)
+
+// Announcer periodically announces sessions to a SAP address.
+type Announcer struct {
+	// Interval is the time between two announcements of the sessions. If it
+	// is zero, the interval is computed as in RFC 2974 from Bandwidth and
+	// the size of the announcements, at least 5 minutes, and randomized by
+	// up to a third.
+	Interval time.Duration
+	// Bandwidth is the bandwidth for the announcements in bits per second,
+	// DefaultBandwidth if zero.
+	Bandwidth int
+	// Compress makes the Announcer compress the packets with zlib.
+	Compress bool
+
+	conn   net.PacketConn
+	addr   net.Addr
+	source net.IP
+
+	mu       sync.Mutex
+	sessions map[sessionKey][]byte
+}
+
+// NewAnnouncer returns an Announcer sending packets to addr over conn with
+// source as the originating source.
+func NewAnnouncer(conn net.PacketConn, addr net.Addr, source net.IP) *Announcer {
+	return &Announcer{conn: conn, addr: addr, source: source, sessions: make(map[sessionKey][]byte)}
+}
+
+// Announce adds the session, or replaces a session with the same o= line,
+// and sends its announcement at once.
+func (a *Announcer) Announce(s *sdp.Session) error {
+	data, err := a.marshal(Announcement, s)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.sessions[newSessionKey(s.Originator)] = data
+	a.mu.Unlock()
+
+	_, err = a.conn.WriteTo(data, a.addr)
+	return err
+}
+
+// Delete removes the session and sends its deletion.
+func (a *Announcer) Delete(s *sdp.Session) error {
+	data, err := a.marshal(Deletion, s)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	delete(a.sessions, newSessionKey(s.Originator))
+	a.mu.Unlock()
+
+	_, err = a.conn.WriteTo(data, a.addr)
+	return err
+}
+
+func (a *Announcer) marshal(messageType MessageType, s *sdp.Session) ([]byte, error) {
+	packet, err := NewPacket(messageType, s, a.source)
+	if err != nil {
+		return nil, err
+	}
+	packet.Compressed = a.Compress
+	return packet.MarshalBinary()
+}
+
+// Run announces the sessions every interval until the context is done.
+func (a *Announcer) Run(ctx context.Context) error {
+	for {
+		timer := time.NewTimer(a.interval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if err := a.announceAll(); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Announcer) announceAll() error {
+	a.mu.Lock()
+	packets := make([][]byte, 0, len(a.sessions))
+	for _, data := range a.sessions {
+		packets = append(packets, data)
+	}
+	a.mu.Unlock()
+
+	for _, data := range packets {
+		if _, err := a.conn.WriteTo(data, a.addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Announcer) interval() time.Duration {
+	if a.Interval > 0 {
+		return a.Interval
+	}
+
+	bandwidth := a.Bandwidth
+	if bandwidth <= 0 {
+		bandwidth = DefaultBandwidth
+	}
+	size := 0
+	a.mu.Lock()
+	for _, data := range a.sessions {
+		size += len(data)
+	}
+	a.mu.Unlock()
+
+	interval := time.Duration(8*size) * time.Second / time.Duration(bandwidth)
+	if interval < minInterval {
+		interval = minInterval
+	}
+	// The announcements of different sources should not synchronize.
+	offset := time.Duration(rand.Int63n(int64(interval)*2/3+1)) - interval/3
+	return interval + offset
+}
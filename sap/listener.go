@@ -0,0 +1,161 @@
+package sap
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+// DefaultTimeout is the shortest time a session stays in the directory of a
+// Listener without being announced, see Listener.Timeout.
+const DefaultTimeout = time.Hour
+
+const maxPacketSize = 65536
+
+// Entry is a session in the directory of a Listener.
+type Entry struct {
+	Session       *sdp.Session
+	Source        net.IP
+	MessageIDHash uint16
+	LastSeen      time.Time
+	// interval is the time between the last two announcements.
+	interval time.Duration
+}
+
+// Listener receives SAP packets and keeps the directory of the sessions they
+// announce.
+type Listener struct {
+	// Timeout is how long a session stays in the directory without being
+	// announced, DefaultTimeout if zero. As in RFC 2974 it is extended to ten
+	// times the interval between the announcements of the session if that
+	// is longer.
+	Timeout time.Duration
+
+	conn net.PacketConn
+	now  func() time.Time
+
+	mu       sync.Mutex
+	sessions map[entryKey]*Entry
+}
+
+// entryKey identifies a session in the directory by its o= line and the
+// originating source of its packets, so that a host cannot replace or
+// delete the session of another one (RFC 2974, section 5).
+type entryKey struct {
+	session sessionKey
+	source  string
+}
+
+func newEntryKey(origin *sdp.Origin, source net.IP) entryKey {
+	return entryKey{session: newSessionKey(origin), source: string(source.To16())}
+}
+
+func NewListener(conn net.PacketConn) *Listener {
+	return &Listener{conn: conn, now: time.Now, sessions: make(map[entryKey]*Entry)}
+}
+
+// Serve reads packets from the connection until reading fails and returns
+// the error. Packets that cannot be decoded are ignored.
+func (l *Listener) Serve() error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		l.handle(buf[:n])
+	}
+}
+
+func (l *Listener) handle(data []byte) {
+	var packet Packet
+	if err := packet.UnmarshalBinary(data); err != nil || packet.Encrypted {
+		return
+	}
+	now := l.now()
+
+	if packet.Type == Deletion {
+		origin, err := payloadOrigin(packet.Payload)
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		delete(l.sessions, newEntryKey(origin, packet.Source))
+		l.mu.Unlock()
+		return
+	}
+
+	origin, err := payloadOrigin(packet.Payload)
+	if err != nil {
+		return
+	}
+	key := newEntryKey(origin, packet.Source)
+
+	l.mu.Lock()
+	entry, ok := l.sessions[key]
+	if ok && packet.MessageIDHash != 0 && entry.MessageIDHash == packet.MessageIDHash {
+		// The session did not change and is not decoded again. A zero hash,
+		// which SAPv0 allows, says nothing.
+		entry.interval = now.Sub(entry.LastSeen)
+		entry.LastSeen = now
+		l.mu.Unlock()
+		return
+	}
+	l.mu.Unlock()
+
+	s, err := packet.Session()
+	if err != nil {
+		return
+	}
+	res := &Entry{Session: s, Source: packet.Source, MessageIDHash: packet.MessageIDHash, LastSeen: now}
+	if ok {
+		res.interval = now.Sub(entry.LastSeen)
+	}
+
+	l.mu.Lock()
+	l.sessions[key] = res
+	l.mu.Unlock()
+}
+
+// Sessions returns the sessions in the directory, removing those that timed
+// out, in the order of their o= lines and sources.
+func (l *Listener) Sessions() []*Entry {
+	now := l.now()
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	l.mu.Lock()
+	res := make([]*Entry, 0, len(l.sessions))
+	for key, entry := range l.sessions {
+		expires := timeout
+		if 10*entry.interval > expires {
+			expires = 10 * entry.interval
+		}
+		if now.Sub(entry.LastSeen) > expires {
+			delete(l.sessions, key)
+			continue
+		}
+		entry := *entry
+		res = append(res, &entry)
+	}
+	l.mu.Unlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		if c := bytes.Compare(res[i].origin(), res[j].origin()); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(res[i].Source.To16(), res[j].Source.To16()) < 0
+	})
+	return res
+}
+
+func (e *Entry) origin() []byte {
+	text, _ := e.Session.Originator.MarshalText()
+	return text
+}
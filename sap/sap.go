@@ -0,0 +1,270 @@
+// Package sap implements the Session Announcement Protocol (SAP), rfc2974
+package sap
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const (
+	Version = 1
+
+	// SDPPayloadType is the payload type of session descriptions.
+	SDPPayloadType = "application/sdp"
+
+	// Port is the well-known SAP port, DefaultAddress the SAP address of
+	// global scope IPv4 sessions.
+	Port           = 9875
+	DefaultAddress = "224.2.127.254"
+)
+
+type MessageType int
+
+const (
+	Announcement MessageType = 0
+	Deletion     MessageType = 1
+)
+
+const (
+	addressTypeBit = 1 << 4
+	messageTypeBit = 1 << 2
+	encryptedBit   = 1 << 1
+	compressedBit  = 1 << 0
+
+	headerLength   = 4
+	maxAuthData    = 255 * 4
+	maxPayloadSize = 1 << 20
+)
+
+// Packet is a SAP packet. Payload is the uncompressed payload; the packet is
+// compressed with zlib when marshaled if Compressed is set. The payload of an
+// encrypted packet is left as it is, compressed or not.
+type Packet struct {
+	Type          MessageType
+	Encrypted     bool
+	Compressed    bool
+	MessageIDHash uint16
+	// Source is the IPv4 or IPv6 address of the originating source.
+	Source net.IP
+	// AuthData is the authentication data, a multiple of 4 bytes long.
+	AuthData []byte
+	// PayloadType is the MIME type of the payload, omitted if empty.
+	PayloadType string
+	Payload     []byte
+}
+
+// NewPacket returns an uncompressed packet of the given type carrying the
+// session description.
+func NewPacket(messageType MessageType, s *sdp.Session, source net.IP) (*Packet, error) {
+	if s.Originator == nil {
+		return nil, fmt.Errorf("session has no o= line")
+	}
+	payload, err := sdp.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Packet{
+		Type:          messageType,
+		MessageIDHash: MessageIDHash(payload),
+		Source:        source,
+		PayloadType:   SDPPayloadType,
+		Payload:       payload,
+	}, nil
+}
+
+// MessageIDHash returns the message id hash of a payload. It changes whenever
+// the payload does and is never zero, which receivers may ignore.
+func MessageIDHash(payload []byte) uint16 {
+	h := fnv.New32a()
+	h.Write(payload)
+	sum := h.Sum32()
+	res := uint16(sum>>16) ^ uint16(sum)
+	if res == 0 {
+		res = 1
+	}
+	return res
+}
+
+func (p *Packet) MarshalBinary() ([]byte, error) {
+	source := p.Source.To4()
+	flags := byte(Version << 5)
+	if source == nil {
+		source = p.Source.To16()
+		if source == nil {
+			return nil, fmt.Errorf("wrong sap packet format: no originating source")
+		}
+		flags |= addressTypeBit
+	}
+	if len(p.AuthData)%4 != 0 || len(p.AuthData) > maxAuthData {
+		return nil, fmt.Errorf("wrong sap packet format: authentication data length %v", len(p.AuthData))
+	}
+	if p.Type == Deletion {
+		flags |= messageTypeBit
+	}
+	if p.Encrypted {
+		flags |= encryptedBit
+	}
+	if p.Compressed {
+		flags |= compressedBit
+	}
+
+	res := make([]byte, headerLength, headerLength+len(source)+len(p.AuthData)+len(p.PayloadType)+1+len(p.Payload))
+	res[0] = flags
+	res[1] = byte(len(p.AuthData) / 4)
+	binary.BigEndian.PutUint16(res[2:], p.MessageIDHash)
+	res = append(res, source...)
+	res = append(res, p.AuthData...)
+
+	payload := p.payload()
+	if p.Compressed && !p.Encrypted {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+	}
+	return append(res, payload...), nil
+}
+
+// payload returns the payload type and the payload.
+func (p *Packet) payload() []byte {
+	if p.PayloadType == "" {
+		return p.Payload
+	}
+	res := make([]byte, 0, len(p.PayloadType)+1+len(p.Payload))
+	res = append(res, p.PayloadType...)
+	res = append(res, 0)
+	return append(res, p.Payload...)
+}
+
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	if len(data) < headerLength {
+		return fmt.Errorf("wrong sap packet format: packet is too short")
+	}
+	flags := data[0]
+	// Receivers should also accept SAPv0 packets (RFC 2974, appendix B),
+	// which have the same header. Their message id hash may be zero.
+	if version := flags >> 5; version != Version && version != 0 {
+		return fmt.Errorf("wrong sap packet format: version %v", version)
+	}
+
+	res := Packet{
+		Encrypted:     flags&encryptedBit != 0,
+		Compressed:    flags&compressedBit != 0,
+		MessageIDHash: binary.BigEndian.Uint16(data[2:]),
+	}
+	if flags&messageTypeBit != 0 {
+		res.Type = Deletion
+	}
+	sourceLength := net.IPv4len
+	if flags&addressTypeBit != 0 {
+		sourceLength = net.IPv6len
+	}
+	authLength := int(data[1]) * 4
+	data = data[headerLength:]
+	if len(data) < sourceLength+authLength {
+		return fmt.Errorf("wrong sap packet format: packet is too short")
+	}
+	res.Source = append(net.IP(nil), data[:sourceLength]...)
+	if authLength > 0 {
+		res.AuthData = append([]byte(nil), data[sourceLength:sourceLength+authLength]...)
+	}
+	payload := data[sourceLength+authLength:]
+
+	if res.Encrypted {
+		res.Payload = append([]byte(nil), payload...)
+		*p = res
+		return nil
+	}
+	if res.Compressed {
+		r, err := zlib.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("wrong sap packet format: %v", err)
+		}
+		payload, err = io.ReadAll(io.LimitReader(r, maxPayloadSize+1))
+		if err != nil {
+			return fmt.Errorf("wrong sap packet format: %v", err)
+		}
+		if len(payload) > maxPayloadSize {
+			return fmt.Errorf("wrong sap packet format: payload too large")
+		}
+	}
+
+	// Without a payload type the payload is a session description, which
+	// starts with v=0.
+	if !bytes.HasPrefix(payload, []byte("v=0")) {
+		index := bytes.IndexByte(payload, 0)
+		if index < 0 {
+			return fmt.Errorf("wrong sap packet format: no payload type")
+		}
+		res.PayloadType = string(payload[:index])
+		payload = payload[index+1:]
+	}
+	res.Payload = append([]byte(nil), payload...)
+	*p = res
+	return nil
+}
+
+// Session decodes the payload of an unencrypted packet with a session
+// description.
+func (p *Packet) Session() (*sdp.Session, error) {
+	if p.Encrypted {
+		return nil, fmt.Errorf("sap packet is encrypted")
+	}
+	if p.PayloadType != "" && p.PayloadType != SDPPayloadType {
+		return nil, fmt.Errorf("wrong sap payload type: %v", p.PayloadType)
+	}
+	return sdp.NewDecoder(bytes.NewReader(p.Payload)).Decode()
+}
+
+// sessionKey identifies a session by its o= line without the version, which
+// changes when the session is modified.
+type sessionKey struct {
+	username string
+	sessID   int64
+	nettype  string
+	addrtype string
+	address  string
+}
+
+func newSessionKey(origin *sdp.Origin) sessionKey {
+	return sessionKey{
+		username: origin.Username,
+		sessID:   origin.SessID,
+		nettype:  origin.Nettype,
+		addrtype: origin.Addrtype,
+		address:  origin.UnicastAddress,
+	}
+}
+
+// payloadOrigin returns the o= line of a payload. A deletion may carry only
+// the o= line rather than the whole session description.
+func payloadOrigin(payload []byte) (*sdp.Origin, error) {
+	for len(payload) > 0 {
+		line := payload
+		if index := bytes.IndexByte(payload, '\n'); index >= 0 {
+			line, payload = payload[:index], payload[index+1:]
+		} else {
+			payload = nil
+		}
+		if bytes.HasPrefix(line, []byte("o=")) {
+			var origin sdp.Origin
+			if err := origin.UnmarshalText(bytes.TrimSuffix(line[2:], []byte("\r"))); err != nil {
+				return nil, err
+			}
+			return &origin, nil
+		}
+	}
+	return nil, fmt.Errorf("wrong sap payload format: no o= line")
+}
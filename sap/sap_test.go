@@ -0,0 +1,391 @@
+package sap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const testSession = "v=0\n" +
+	"o=jdoe 2890844526 2890842807 IN IP4 10.47.16.5\n" +
+	"s=SDP Seminar\n" +
+	"c=IN IP4 224.2.17.12/127\n" +
+	"t=2873397496 2873404696\n" +
+	"m=audio 49170 RTP/AVP 0\n"
+
+func newTestSession(t *testing.T) *sdp.Session {
+	t.Helper()
+	s, err := sdp.NewDecoder(bytes.NewReader([]byte(testSession))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Packet *Packet
+	}{
+		{
+			Name: "announcement",
+			Packet: &Packet{MessageIDHash: 0x1234, Source: net.IPv4(10, 47, 16, 5).To4(),
+				PayloadType: SDPPayloadType, Payload: []byte(testSession)},
+		},
+		{
+			Name: "compressed IPv6 deletion",
+			Packet: &Packet{Type: Deletion, Compressed: true, MessageIDHash: 0x4321, Source: net.ParseIP("2001:db8::1"),
+				PayloadType: SDPPayloadType, Payload: []byte(testSession)},
+		},
+		{
+			Name: "authentication data",
+			Packet: &Packet{MessageIDHash: 1, Source: net.IPv4(10, 47, 16, 5).To4(), AuthData: []byte{0x20, 1, 2, 3, 4, 5, 6, 7},
+				PayloadType: SDPPayloadType, Payload: []byte(testSession)},
+		},
+		{
+			Name:   "no payload type",
+			Packet: &Packet{MessageIDHash: 1, Source: net.IPv4(10, 47, 16, 5).To4(), Payload: []byte(testSession)},
+		},
+		{
+			Name: "encrypted",
+			Packet: &Packet{Encrypted: true, Compressed: true, MessageIDHash: 1, Source: net.IPv4(10, 47, 16, 5).To4(),
+				Payload: []byte{0, 1, 2, 3}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			data, err := test.Packet.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var res Packet
+			if err := res.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(&res, test.Packet) {
+				t.Fatal(cmp.Diff(&res, test.Packet))
+			}
+		})
+	}
+}
+
+func TestPacketHeader(t *testing.T) {
+	packet := &Packet{Type: Deletion, Compressed: true, MessageIDHash: 0xabcd, Source: net.IPv4(192, 0, 2, 1),
+		AuthData: make([]byte, 8), Payload: []byte("v=0\n")}
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := []byte{0x25, 2, 0xab, 0xcd, 192, 0, 2, 1}
+	if !bytes.HasPrefix(data, header) {
+		t.Fatalf("wrong header: % x", data[:len(header)])
+	}
+}
+
+func TestPacketErrors(t *testing.T) {
+	marshalTests := []struct {
+		Name   string
+		Packet *Packet
+	}{
+		{Name: "no source", Packet: &Packet{}},
+		{Name: "authentication data length", Packet: &Packet{Source: net.IPv4(192, 0, 2, 1), AuthData: []byte{1, 2, 3}}},
+	}
+	for _, test := range marshalTests {
+		if _, err := test.Packet.MarshalBinary(); err == nil {
+			t.Errorf("%v: expected error", test.Name)
+		}
+	}
+
+	unmarshalTests := []struct {
+		Name string
+		Data []byte
+	}{
+		{Name: "too short", Data: []byte{0x20, 0}},
+		{Name: "version", Data: []byte{0x40, 0, 0, 0, 192, 0, 2, 1, 'v', '=', '0'}},
+		{Name: "no source", Data: []byte{0x30, 0, 0, 0, 192, 0, 2, 1}},
+		{Name: "no authentication data", Data: []byte{0x20, 1, 0, 0, 192, 0, 2, 1}},
+		{Name: "no payload type", Data: []byte{0x20, 0, 0, 0, 192, 0, 2, 1, 'o', '='}},
+		{Name: "compression", Data: []byte{0x21, 0, 0, 0, 192, 0, 2, 1, 'v', '=', '0'}},
+	}
+	for _, test := range unmarshalTests {
+		var packet Packet
+		if err := packet.UnmarshalBinary(test.Data); err == nil {
+			t.Errorf("%v: expected error", test.Name)
+		}
+	}
+
+	packet := &Packet{Source: net.IPv4(192, 0, 2, 1), Compressed: true, Payload: append([]byte("v=0\n"), make([]byte, maxPayloadSize)...)}
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := packet.UnmarshalBinary(data); err == nil || err.Error() != "wrong sap packet format: payload too large" {
+		t.Errorf("payload too large: unexpected error: %v", err)
+	}
+
+	packet = &Packet{PayloadType: "text/plain", Payload: []byte(testSession)}
+	if _, err := packet.Session(); err == nil {
+		t.Error("payload type: expected error")
+	}
+}
+
+func TestMessageIDHash(t *testing.T) {
+	s := newTestSession(t)
+	first, err := NewPacket(Announcement, s, net.IPv4(10, 47, 16, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Originator.SessVersion++
+	second, err := NewPacket(Announcement, s, net.IPv4(10, 47, 16, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.MessageIDHash == 0 || first.MessageIDHash == second.MessageIDHash {
+		t.Fatalf("wrong message id hashes: %v %v", first.MessageIDHash, second.MessageIDHash)
+	}
+}
+
+// memNetwork delivers the packets written by each of its connections to all
+// the others, as multicast does.
+type memNetwork struct {
+	mu    sync.Mutex
+	conns []*memConn
+}
+
+type memConn struct {
+	network *memNetwork
+	packets chan []byte
+	closed  chan struct{}
+	once    sync.Once
+}
+
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+func (n *memNetwork) conn() *memConn {
+	conn := &memConn{network: n, packets: make(chan []byte, 64), closed: make(chan struct{})}
+	n.mu.Lock()
+	n.conns = append(n.conns, conn)
+	n.mu.Unlock()
+	return conn
+}
+
+func (c *memConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case packet := <-c.packets:
+		return copy(p, packet), memAddr("sender"), nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *memConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.network.mu.Lock()
+	defer c.network.mu.Unlock()
+	for _, conn := range c.network.conns {
+		if conn != c {
+			conn.packets <- append([]byte(nil), p...)
+		}
+	}
+	return len(p), nil
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr                { return memAddr("local") }
+func (c *memConn) SetDeadline(t time.Time) error      { return nil }
+func (c *memConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *memConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// waitSessions waits until the listener has the given number of sessions.
+func waitSessions(t *testing.T, l *Listener, n int, version int64) []*Entry {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		entries := l.Sessions()
+		if len(entries) == n && (n == 0 || entries[0].Session.Originator.SessVersion == version) {
+			return entries
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("listener has %v sessions, expected %v", len(l.Sessions()), n)
+	return nil
+}
+
+func TestAnnouncerListener(t *testing.T) {
+	var network memNetwork
+	listenerConn := network.conn()
+	defer listenerConn.Close()
+	l := NewListener(listenerConn)
+	served := make(chan error, 1)
+	go func() { served <- l.Serve() }()
+
+	source := net.IPv4(10, 47, 16, 5).To4()
+	a := NewAnnouncer(network.conn(), memAddr(DefaultAddress), source)
+	a.Compress = true
+
+	s := newTestSession(t)
+	if err := a.Announce(s); err != nil {
+		t.Fatal(err)
+	}
+	entries := waitSessions(t, l, 1, s.Originator.SessVersion)
+	if !entries[0].Source.Equal(source) || entries[0].Session.SessionName != "SDP Seminar" {
+		t.Fatalf("wrong entry: %+v", entries[0])
+	}
+
+	s.Originator.SessVersion++
+	s.SessionName = "SDP Tutorial"
+	if err := a.Announce(s); err != nil {
+		t.Fatal(err)
+	}
+	entries = waitSessions(t, l, 1, s.Originator.SessVersion)
+	if entries[0].Session.SessionName != "SDP Tutorial" {
+		t.Fatalf("session was not updated: %v", entries[0].Session.SessionName)
+	}
+
+	if err := a.Delete(s); err != nil {
+		t.Fatal(err)
+	}
+	waitSessions(t, l, 0, 0)
+
+	listenerConn.Close()
+	if err := <-served; !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestListenerTimeout(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	l := NewListener(nil)
+	l.now = func() time.Time { return now }
+
+	packet, err := NewPacket(Announcement, newTestSession(t), net.IPv4(10, 47, 16, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := packet.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.handle(data)
+	now = now.Add(50 * time.Minute)
+	l.handle(data)
+	now = now.Add(2 * time.Hour)
+	if len(l.Sessions()) != 1 {
+		t.Fatal("session with a long interval timed out")
+	}
+	now = now.Add(7 * time.Hour)
+	if len(l.Sessions()) != 0 {
+		t.Fatal("session did not time out")
+	}
+}
+
+func TestListenerDeletion(t *testing.T) {
+	l := NewListener(nil)
+	s := newTestSession(t)
+	handle := func(messageType MessageType, source net.IP) {
+		t.Helper()
+		packet, err := NewPacket(messageType, s, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := packet.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.handle(data)
+	}
+
+	handle(Announcement, net.IPv4(10, 47, 16, 5))
+	handle(Deletion, net.IPv4(192, 0, 2, 1))
+	if len(l.Sessions()) != 1 {
+		t.Fatal("session was deleted by another source")
+	}
+
+	// Another source announcing the same o= line does not take the
+	// session over, so its deletion leaves the session alone.
+	s.SessionName = "Spoofed"
+	handle(Announcement, net.IPv4(192, 0, 2, 1))
+	handle(Deletion, net.IPv4(192, 0, 2, 1))
+	entries := l.Sessions()
+	if len(entries) != 1 || !entries[0].Source.Equal(net.IPv4(10, 47, 16, 5)) || entries[0].Session.SessionName != "SDP Seminar" {
+		t.Fatalf("session was taken over: %+v", entries)
+	}
+
+	handle(Deletion, net.IPv4(10, 47, 16, 5))
+	if len(l.Sessions()) != 0 {
+		t.Fatal("session was not deleted")
+	}
+}
+
+func TestListenerVersion0(t *testing.T) {
+	l := NewListener(nil)
+	s := newTestSession(t)
+	for _, name := range []string{"SDP Seminar", "SDP Tutorial"} {
+		s.SessionName = name
+		payload, err := sdp.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A SAPv0 announcement, with no message id hash and no payload
+		// type.
+		l.handle(append([]byte{0x00, 0, 0, 0, 10, 47, 16, 5}, payload...))
+		if entries := l.Sessions(); len(entries) != 1 || entries[0].Session.SessionName != name {
+			t.Fatalf("wrong sessions: %+v", entries)
+		}
+	}
+}
+
+func TestAnnouncerRun(t *testing.T) {
+	var network memNetwork
+	conn := network.conn()
+	a := NewAnnouncer(network.conn(), memAddr(DefaultAddress), net.IPv4(10, 47, 16, 5))
+	a.Interval = time.Millisecond
+
+	if err := a.Announce(newTestSession(t)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	buf := make([]byte, maxPacketSize)
+	for i := 0; i < 3; i++ {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var packet Packet
+		if err := packet.UnmarshalBinary(buf[:n]); err != nil || packet.Type != Announcement {
+			t.Fatalf("wrong packet: %+v, %v", packet, err)
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestAnnouncerInterval(t *testing.T) {
+	a := NewAnnouncer(nil, nil, nil)
+	for i := 0; i < 100; i++ {
+		if interval := a.interval(); interval < 200*time.Second || interval > 400*time.Second {
+			t.Fatalf("interval out of range: %v", interval)
+		}
+	}
+}
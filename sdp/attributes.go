@@ -0,0 +1,105 @@
+package sdp
+
+// propertyValue is the value the Decoder stores for property attributes
+// ("a=<name>" without a value), the Encoder writes them back without ':'.
+const propertyValue = " "
+
+// Attributes is an ordered list of session or media attributes.
+type Attributes []*Attribute
+
+// NewAttribute returns a value attribute, or a property attribute if value
+// is empty.
+func NewAttribute(name, value string) *Attribute {
+	if value == "" {
+		value = propertyValue
+	}
+	return &Attribute{Name: name, Value: value}
+}
+
+// IsProperty reports whether the attribute has no value, e.g. a=sendrecv.
+func (a *Attribute) IsProperty() bool {
+	return a.Value == propertyValue
+}
+
+func (a *Attribute) value() string {
+	if a.IsProperty() {
+		return ""
+	}
+	return a.Value
+}
+
+// Get returns the value of the first attribute with the given name. The
+// value of a property attribute is empty.
+func (a Attributes) Get(name string) (string, bool) {
+	for _, attribute := range a {
+		if attribute.Name == name {
+			return attribute.value(), true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns the values of all attributes with the given name in order.
+func (a Attributes) GetAll(name string) []string {
+	var res []string
+	for _, attribute := range a {
+		if attribute.Name == name {
+			res = append(res, attribute.value())
+		}
+	}
+	return res
+}
+
+func (a Attributes) Has(name string) bool {
+	_, ok := a.Get(name)
+	return ok
+}
+
+// Add appends an attribute, a property attribute if value is empty.
+func (a *Attributes) Add(name, value string) {
+	*a = append(*a, NewAttribute(name, value))
+}
+
+// Set replaces the value of the first attribute with the given name in
+// place and removes the others, or appends the attribute if there is none.
+func (a *Attributes) Set(name, value string) {
+	found := false
+	res := (*a)[:0]
+	for _, attribute := range *a {
+		if attribute.Name != name {
+			res = append(res, attribute)
+		} else if !found {
+			found = true
+			*attribute = *NewAttribute(name, value)
+			res = append(res, attribute)
+		}
+	}
+	// Do not keep the dropped attributes reachable from the backing array.
+	for i := len(res); i < len(*a); i++ {
+		(*a)[i] = nil
+	}
+	*a = res
+	if !found {
+		a.Add(name, value)
+	}
+}
+
+// Remove deletes all attributes with the given names and returns how many
+// were removed.
+func (a *Attributes) Remove(names ...string) int {
+	res := (*a)[:0]
+	for _, attribute := range *a {
+		if !inSet(attribute.Name, names) {
+			res = append(res, attribute)
+		}
+	}
+	removed := len(*a) - len(res)
+	for i := len(res); i < len(*a); i++ {
+		(*a)[i] = nil
+	}
+	*a = res
+	if len(*a) == 0 {
+		*a = nil
+	}
+	return removed
+}
@@ -0,0 +1,96 @@
+package sdp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAttributes(t *testing.T) {
+	var attributes Attributes
+
+	attributes.Add("sendrecv", "")
+	attributes.Add("rtpmap", "0 PCMU/8000")
+	attributes.Add("rtpmap", "8 PCMA/8000")
+	attributes.Add("mid", "0")
+
+	if value, ok := attributes.Get("sendrecv"); !ok || value != "" || !attributes[0].IsProperty() {
+		t.Fatalf("wrong property attribute: %v, %v", value, ok)
+	}
+	if value, ok := attributes.Get("rtpmap"); !ok || value != "0 PCMU/8000" {
+		t.Fatalf("wrong rtpmap: %v", value)
+	}
+	if !cmp.Equal(attributes.GetAll("rtpmap"), []string{"0 PCMU/8000", "8 PCMA/8000"}) {
+		t.Fatalf("wrong rtpmaps: %v", attributes.GetAll("rtpmap"))
+	}
+	if attributes.Has("fmtp") || !attributes.Has("mid") {
+		t.Fatal("wrong Has result")
+	}
+
+	full := attributes
+	attributes.Set("rtpmap", "9 G722/8000")
+	if len(attributes) != 3 || full[3] != nil {
+		t.Fatalf("dropped attribute is still referenced: %v", full[3])
+	}
+	attributes.Set("ptime", "20")
+	if n := attributes.Remove("sendrecv"); n != 1 {
+		t.Fatalf("wrong number of removed attributes: %v", n)
+	}
+
+	expected := Attributes{
+		{Name: "rtpmap", Value: "9 G722/8000"},
+		{Name: "mid", Value: "0"},
+		{Name: "ptime", Value: "20"},
+	}
+	if !cmp.Equal(attributes, expected) {
+		t.Fatal(cmp.Diff(attributes, expected))
+	}
+
+	if n := attributes.Remove("rtpmap", "mid", "ptime"); n != 3 || attributes != nil {
+		t.Fatalf("wrong attributes after removal: %v, %v", n, attributes)
+	}
+}
+
+func TestAttributesEncoding(t *testing.T) {
+	s, err := NewDecoder(strings.NewReader(`v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+a=ice-lite
+m=audio 9/1 RTP/AVP 0
+a=fingerprint:sha-256 AB:CD:EF
+`)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.Attributes.Has("ice-lite") {
+		t.Fatal("ice-lite was expected")
+	}
+	if value, _ := s.MediaDescs[0].Attributes.Get("fingerprint"); value != "sha-256 AB:CD:EF" {
+		t.Fatalf("wrong fingerprint: %v", value)
+	}
+
+	s.Attributes.Add("ice-options", "trickle")
+	s.MediaDescs[0].Attributes.Add("rtcp-mux", "")
+
+	var buf bytes.Buffer
+	NewEncoder(&buf).Encode(s)
+	expected := `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+a=ice-lite
+a=ice-options:trickle
+m=audio 9/1 RTP/AVP 0
+a=fingerprint:sha-256 AB:CD:EF
+a=rtcp-mux
+`
+	if buf.String() != expected {
+		t.Fatal(cmp.Diff(buf.String(), expected))
+	}
+}
@@ -0,0 +1,56 @@
+package sdp
+
+import "strings"
+
+const (
+	MidAttribute   = "mid"
+	GroupAttribute = "group"
+)
+
+const (
+	BundleSemantics = "BUNDLE"
+)
+
+// Mid returns the identification tag of the media description, if any.
+func (m *MediaDesc) Mid() string {
+	mid, _ := m.Attributes.Get(MidAttribute)
+	return strings.TrimSpace(mid)
+}
+
+// Groups returns the mids of every a=group attribute with the given
+// semantics, e.g. BundleSemantics.
+func (s *Session) Groups(semantics string) [][]string {
+	var groups [][]string
+	for _, value := range s.Attributes.GetAll(GroupAttribute) {
+		fields := strings.Fields(value)
+		if len(fields) == 0 || fields[0] != semantics {
+			continue
+		}
+		groups = append(groups, fields[1:])
+	}
+	return groups
+}
+
+// BundleGroup returns the media descriptions bundled together with m,
+// including m itself. An unbundled media description forms its own group.
+func (s *Session) BundleGroup(m *MediaDesc) []*MediaDesc {
+	mid := m.Mid()
+	if mid == "" {
+		return []*MediaDesc{m}
+	}
+
+	for _, group := range s.Groups(BundleSemantics) {
+		if !inSet(mid, group) {
+			continue
+		}
+		var res []*MediaDesc
+		for _, desc := range s.MediaDescs {
+			if desc.Mid() != "" && inSet(desc.Mid(), group) {
+				res = append(res, desc)
+			}
+		}
+		return res
+	}
+
+	return []*MediaDesc{m}
+}
@@ -0,0 +1,441 @@
+package sdp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Attributes of SDP capability negotiation (RFC 5939).
+const (
+	TcapAttribute = "tcap"
+	AcapAttribute = "acap"
+	PcfgAttribute = "pcfg"
+	AcfgAttribute = "acfg"
+)
+
+var capabilityAttributes = []string{TcapAttribute, AcapAttribute, PcfgAttribute, AcfgAttribute}
+
+// Deletion of the attributes a potential configuration replaces, the "-m",
+// "-s" and "-ms" prefixes of its attribute list.
+const (
+	DeleteMediaAttributes   = "m"
+	DeleteSessionAttributes = "s"
+	DeleteAllAttributes     = "ms"
+)
+
+// Capabilities are the transport and attribute capabilities of a media
+// description with the session-level ones, by capability number.
+type Capabilities struct {
+	Transports map[int][]string
+	Attributes map[int]*Attribute
+}
+
+// Capabilities returns the capabilities the media description can refer to,
+// the numbers of which are unique within the session.
+func (s *Session) Capabilities(m *MediaDesc) (*Capabilities, error) {
+	res := &Capabilities{Transports: make(map[int][]string), Attributes: make(map[int]*Attribute)}
+	for _, attributes := range []Attributes{s.Attributes, m.Attributes} {
+		for _, value := range attributes.GetAll(TcapAttribute) {
+			if err := res.addTransports(value); err != nil {
+				return nil, err
+			}
+		}
+		for _, value := range attributes.GetAll(AcapAttribute) {
+			if err := res.addAttribute(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// addTransports parses an a=tcap value, e.g. "1 RTP/SAVPF RTP/SAVP", which
+// numbers the protocols consecutively.
+func (c *Capabilities) addTransports(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return fmt.Errorf("wrong tcap format: %v", value)
+	}
+	number, err := parseCapabilityNumber(fields[0])
+	if err != nil {
+		return fmt.Errorf("wrong tcap format: %v", value)
+	}
+	for i, field := range fields[1:] {
+		proto, err := parseProto(nil, field)
+		if err != nil {
+			return fmt.Errorf("wrong tcap format: %v", value)
+		}
+		if _, ok := c.Transports[number+i]; ok {
+			return fmt.Errorf("duplicate tcap %v", number+i)
+		}
+		c.Transports[number+i] = proto
+	}
+	return nil
+}
+
+// addAttribute parses an a=acap value, e.g. "1 crypto:1 AES_CM_128_HMAC_SHA1_80
+// inline:...".
+func (c *Capabilities) addAttribute(value string) error {
+	field, attribute, ok := strings.Cut(strings.TrimSpace(value), " ")
+	number, err := parseCapabilityNumber(field)
+	attribute = strings.TrimSpace(attribute)
+	if !ok || err != nil || attribute == "" {
+		return fmt.Errorf("wrong acap format: %v", value)
+	}
+	if _, ok := c.Attributes[number]; ok {
+		return fmt.Errorf("duplicate acap %v", number)
+	}
+	name, attributeValue, _ := strings.Cut(attribute, ":")
+	c.Attributes[number] = NewAttribute(name, attributeValue)
+	return nil
+}
+
+func parseCapabilityNumber(value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 || len(value) > 10 || value[0] == '0' {
+		return 0, fmt.Errorf("wrong capability number: %v", value)
+	}
+	return number, nil
+}
+
+// AttributeCapabilityList is a list of attribute capabilities of a potential
+// configuration, e.g. "1,2,[3]", all of which are used but the optional ones,
+// which may be left out.
+type AttributeCapabilityList struct {
+	Mandatory []int
+	Optional  []int
+}
+
+// PotentialConfig is the value of an a=pcfg attribute. Transports and
+// AttributeLists are alternatives, each giving the configurations a choice.
+type PotentialConfig struct {
+	Number         int
+	Transports     []int
+	AttributeLists []*AttributeCapabilityList
+	// Delete is set if the attributes replace those of the media
+	// description, the session or both.
+	Delete string
+	// Extensions are the configuration lists of extensions as they are, which
+	// are ignored.
+	Extensions []string
+}
+
+// ParsePotentialConfig parses an a=pcfg value, e.g. "1 t=1|2 a=-m:1,[2]".
+func ParsePotentialConfig(value string) (*PotentialConfig, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("wrong pcfg format: %v", value)
+	}
+	number, err := parseCapabilityNumber(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("wrong pcfg format: %v", value)
+	}
+
+	res := &PotentialConfig{Number: number}
+	for _, field := range fields[1:] {
+		switch {
+		case strings.HasPrefix(field, "t="):
+			for _, alternative := range strings.Split(field[len("t="):], "|") {
+				number, err := parseCapabilityNumber(alternative)
+				if err != nil {
+					return nil, fmt.Errorf("wrong pcfg format: %v", value)
+				}
+				res.Transports = append(res.Transports, number)
+			}
+		case strings.HasPrefix(field, "a="):
+			if err := res.parseAttributeLists(field[len("a="):]); err != nil {
+				return nil, fmt.Errorf("wrong pcfg format: %v", value)
+			}
+		case strings.Contains(field, "="):
+			res.Extensions = append(res.Extensions, field)
+		default:
+			return nil, fmt.Errorf("wrong pcfg format: %v", value)
+		}
+	}
+	return res, nil
+}
+
+func (c *PotentialConfig) parseAttributeLists(value string) error {
+	if strings.HasPrefix(value, "-") {
+		var lists string
+		c.Delete, lists, _ = strings.Cut(value[1:], ":")
+		if !inSet(c.Delete, []string{DeleteMediaAttributes, DeleteSessionAttributes, DeleteAllAttributes}) {
+			return fmt.Errorf("wrong delete attributes: %v", c.Delete)
+		}
+		if lists == "" {
+			return nil
+		}
+		value = lists
+	}
+
+	for _, alternative := range strings.Split(value, "|") {
+		list := &AttributeCapabilityList{}
+		mandatory, optional, hasOptional := strings.Cut(alternative, "[")
+		if hasOptional {
+			if !strings.HasSuffix(optional, "]") {
+				return fmt.Errorf("wrong attribute capability list: %v", alternative)
+			}
+			if mandatory != "" {
+				if !strings.HasSuffix(mandatory, ",") {
+					return fmt.Errorf("wrong attribute capability list: %v", alternative)
+				}
+				mandatory = mandatory[:len(mandatory)-1]
+			}
+			numbers, err := parseCapabilityNumbers(optional[:len(optional)-1])
+			if err != nil {
+				return err
+			}
+			list.Optional = numbers
+		}
+		if mandatory != "" || !hasOptional {
+			numbers, err := parseCapabilityNumbers(mandatory)
+			if err != nil {
+				return err
+			}
+			list.Mandatory = numbers
+		}
+		c.AttributeLists = append(c.AttributeLists, list)
+	}
+	return nil
+}
+
+func parseCapabilityNumbers(value string) ([]int, error) {
+	var res []int
+	for _, field := range strings.Split(value, ",") {
+		number, err := parseCapabilityNumber(field)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, number)
+	}
+	return res, nil
+}
+
+func formatCapabilityNumbers(numbers []int, separator string) string {
+	fields := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		fields = append(fields, strconv.Itoa(number))
+	}
+	return strings.Join(fields, separator)
+}
+
+func (l *AttributeCapabilityList) String() string {
+	res := formatCapabilityNumbers(l.Mandatory, ",")
+	if len(l.Optional) > 0 {
+		if res != "" {
+			res += ","
+		}
+		res += "[" + formatCapabilityNumbers(l.Optional, ",") + "]"
+	}
+	return res
+}
+
+func (c *PotentialConfig) String() string {
+	fields := []string{strconv.Itoa(c.Number)}
+	if len(c.AttributeLists) > 0 || c.Delete != "" {
+		lists := make([]string, 0, len(c.AttributeLists))
+		for _, list := range c.AttributeLists {
+			lists = append(lists, list.String())
+		}
+		field := "a="
+		if c.Delete != "" {
+			field += "-" + c.Delete
+			if len(lists) > 0 {
+				field += ":"
+			}
+		}
+		fields = append(fields, field+strings.Join(lists, "|"))
+	}
+	if len(c.Transports) > 0 {
+		fields = append(fields, "t="+formatCapabilityNumbers(c.Transports, "|"))
+	}
+	fields = append(fields, c.Extensions...)
+	return strings.Join(fields, " ")
+}
+
+// PotentialConfigs returns the a=pcfg attributes of the media description
+// in order of preference, i.e. by number.
+func (m *MediaDesc) PotentialConfigs() ([]*PotentialConfig, error) {
+	var res []*PotentialConfig
+	for _, value := range m.Attributes.GetAll(PcfgAttribute) {
+		config, err := ParsePotentialConfig(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, config)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Number < res[j].Number })
+	return res, nil
+}
+
+// SelectedConfig is the value of an a=acfg attribute: the potential
+// configuration the answerer picked and its choices of alternatives.
+type SelectedConfig struct {
+	Number     int
+	Transport  int
+	Attributes []int
+}
+
+// ParseSelectedConfig parses an a=acfg value, e.g. "1 t=1 a=1,2".
+func ParseSelectedConfig(value string) (*SelectedConfig, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("wrong acfg format: %v", value)
+	}
+	number, err := parseCapabilityNumber(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("wrong acfg format: %v", value)
+	}
+
+	res := &SelectedConfig{Number: number}
+	for _, field := range fields[1:] {
+		switch {
+		case strings.HasPrefix(field, "t="):
+			res.Transport, err = parseCapabilityNumber(field[len("t="):])
+		case strings.HasPrefix(field, "a="):
+			attributes := field[len("a="):]
+			if strings.HasPrefix(attributes, "-") {
+				_, attributes, _ = strings.Cut(attributes, ":")
+			}
+			if attributes != "" {
+				res.Attributes, err = parseCapabilityNumbers(attributes)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("wrong acfg format: %v", value)
+		}
+	}
+	return res, nil
+}
+
+func (c *SelectedConfig) String() string {
+	res := strconv.Itoa(c.Number)
+	if c.Transport != 0 {
+		res += " t=" + strconv.Itoa(c.Transport)
+	}
+	if len(c.Attributes) > 0 {
+		res += " a=" + formatCapabilityNumbers(c.Attributes, ",")
+	}
+	return res
+}
+
+// SelectedConfig returns the a=acfg attribute of the media description of
+// an answer, nil if there is none.
+func (m *MediaDesc) SelectedConfig() (*SelectedConfig, error) {
+	value, ok := m.Attributes.Get(AcfgAttribute)
+	if !ok {
+		return nil, nil
+	}
+	return ParseSelectedConfig(value)
+}
+
+// Configuration is an alternative of a media description offered with
+// capability negotiation: a potential configuration with a choice of its
+// alternatives, or the actual configuration, which has a zero Number.
+type Configuration struct {
+	SelectedConfig
+	// DeleteSession is set if the attributes replace the session-level ones.
+	DeleteSession bool
+	// Media is the media description with the transport and attributes of
+	// the configuration, without the capability negotiation attributes.
+	Media *MediaDesc
+}
+
+// Configurations expands the media description into its configurations in
+// order of preference: the potential configurations by number, each with
+// its alternatives in order, and the actual configuration last. A list with
+// optional capabilities expands into an alternative with them followed by
+// one without them.
+func (s *Session) Configurations(m *MediaDesc) ([]*Configuration, error) {
+	capabilities, err := s.Capabilities(m)
+	if err != nil {
+		return nil, err
+	}
+	potential, err := m.PotentialConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	actual := m.Clone()
+	actual.Attributes.Remove(capabilityAttributes...)
+
+	var res []*Configuration
+	for _, config := range potential {
+		transports := config.Transports
+		if len(transports) == 0 {
+			transports = []int{0}
+		}
+		var attributeLists [][]int
+		for _, list := range config.AttributeLists {
+			attributeLists = append(attributeLists, append(append([]int(nil), list.Mandatory...), list.Optional...))
+			if len(list.Optional) > 0 {
+				attributeLists = append(attributeLists, list.Mandatory)
+			}
+		}
+		if len(attributeLists) == 0 {
+			attributeLists = [][]int{nil}
+		}
+
+		for _, transport := range transports {
+			for _, attributes := range attributeLists {
+				c, err := newConfiguration(config, transport, attributes, capabilities, actual)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, c)
+			}
+		}
+	}
+	return append(res, &Configuration{Media: actual}), nil
+}
+
+func newConfiguration(config *PotentialConfig, transport int, attributes []int, capabilities *Capabilities,
+	actual *MediaDesc) (*Configuration, error) {
+	res := &Configuration{
+		SelectedConfig: SelectedConfig{Number: config.Number, Transport: transport, Attributes: attributes},
+		DeleteSession:  config.Delete == DeleteSessionAttributes || config.Delete == DeleteAllAttributes,
+		Media:          actual.Clone(),
+	}
+	if transport != 0 {
+		proto, ok := capabilities.Transports[transport]
+		if !ok {
+			return nil, fmt.Errorf("unknown tcap %v in pcfg %v", transport, config.Number)
+		}
+		res.Media.Proto = cloneStrings(proto)
+	}
+	if config.Delete == DeleteMediaAttributes || config.Delete == DeleteAllAttributes {
+		res.Media.Attributes = nil
+	}
+	for _, number := range attributes {
+		attribute, ok := capabilities.Attributes[number]
+		if !ok {
+			return nil, fmt.Errorf("unknown acap %v in pcfg %v", number, config.Number)
+		}
+		res.Media.Attributes = append(res.Media.Attributes, &Attribute{Name: attribute.Name, Value: attribute.Value})
+	}
+	return res, nil
+}
+
+// SelectConfiguration returns the first configuration accept takes, nil if
+// there is none. An answer built from a potential configuration carries its
+// a=acfg attribute, see SetSelectedConfig.
+func SelectConfiguration(configs []*Configuration, accept func(*Configuration) bool) *Configuration {
+	for _, config := range configs {
+		if accept(config) {
+			return config
+		}
+	}
+	return nil
+}
+
+// SetSelectedConfig sets the a=acfg attribute of the media description of
+// an answer to the configuration, or removes it for the actual
+// configuration.
+func (m *MediaDesc) SetSelectedConfig(c *Configuration) {
+	m.Attributes.Remove(AcfgAttribute)
+	if c.Number != 0 {
+		m.Attributes.Add(AcfgAttribute, c.SelectedConfig.String())
+	}
+}
@@ -0,0 +1,127 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// capnegOffer offers SRTP with a fallback to RTP, as in RFC 5939 section 3.12.
+const capnegOffer = "v=0\r\n" +
+	"o=- 25678 753849 IN IP4 192.0.2.1\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 192.0.2.1\r\n" +
+	"t=0 0\r\n" +
+	"a=acap:1 key-mgmt:mikey AQAFgM0XflABAAAAAAAAAAAAAAsAyONQ6gAAAAAJAAAQbWlrZXlAZXhhbXBsZS5jb20AAAAAAA\r\n" +
+	"a=tcap:1 RTP/SAVP RTP/AVP\r\n" +
+	"m=audio 59000 RTP/AVP 98\r\n" +
+	"a=rtpmap:98 AMR/8000\r\n" +
+	"a=acap:2 crypto:1 AES_CM_128_HMAC_SHA1_32 inline:NzB4d1BINUAvLEw6UzF3WSJ+PSdFcGdUJShpX1Zj|2^20|1:32\r\n" +
+	"a=acap:3 rtcp-mux\r\n" +
+	"a=pcfg:2 t=1 a=2,[3]\r\n" +
+	"a=pcfg:1 t=1 a=1|2\r\n"
+
+func TestConfigurations(t *testing.T) {
+	s, err := NewDecoder(strings.NewReader(capnegOffer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := s.MediaDescs[0]
+
+	pcfg, err := m.PotentialConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPcfg := []*PotentialConfig{
+		{Number: 1, Transports: []int{1}, AttributeLists: []*AttributeCapabilityList{{Mandatory: []int{1}}, {Mandatory: []int{2}}}},
+		{Number: 2, Transports: []int{1}, AttributeLists: []*AttributeCapabilityList{{Mandatory: []int{2}, Optional: []int{3}}}},
+	}
+	if diff := cmp.Diff(expectedPcfg, pcfg); diff != "" {
+		t.Fatal(diff)
+	}
+	if value := pcfg[1].String(); value != "2 a=2,[3] t=1" {
+		t.Fatalf("wrong pcfg: %v", value)
+	}
+
+	configs, err := s.Configurations(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type summary struct {
+		Selected   string
+		Proto      string
+		Attributes []string
+	}
+	var summaries []summary
+	for _, config := range configs {
+		var attributes []string
+		for _, attribute := range config.Media.Attributes {
+			attributes = append(attributes, attribute.Name)
+		}
+		summaries = append(summaries, summary{config.SelectedConfig.String(), strings.Join(config.Media.Proto, "/"), attributes})
+	}
+	expected := []summary{
+		{"1 t=1 a=1", "RTP/SAVP", []string{"rtpmap", "key-mgmt"}},
+		{"1 t=1 a=2", "RTP/SAVP", []string{"rtpmap", "crypto"}},
+		{"2 t=1 a=2,3", "RTP/SAVP", []string{"rtpmap", "crypto", "rtcp-mux"}},
+		{"2 t=1 a=2", "RTP/SAVP", []string{"rtpmap", "crypto"}},
+		{"0", "RTP/AVP", []string{"rtpmap"}},
+	}
+	if diff := cmp.Diff(expected, summaries); diff != "" {
+		t.Fatal(diff)
+	}
+	if configs[2].Media.Attributes[2].Value != propertyValue {
+		t.Fatalf("wrong rtcp-mux attribute: %+v", configs[2].Media.Attributes[2])
+	}
+
+	// An endpoint without MIKEY takes SDES, a plain RTP one the actual
+	// configuration.
+	withoutMIKEY := SelectConfiguration(configs, func(c *Configuration) bool {
+		return !c.Media.Attributes.Has("key-mgmt")
+	})
+	answer := withoutMIKEY.Media.Clone()
+	answer.SetSelectedConfig(withoutMIKEY)
+	if value, _ := answer.Attributes.Get(AcfgAttribute); value != "1 t=1 a=2" {
+		t.Fatalf("wrong acfg: %v", value)
+	}
+	selected, err := answer.SelectedConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&withoutMIKEY.SelectedConfig, selected); diff != "" {
+		t.Fatal(diff)
+	}
+
+	plain := SelectConfiguration(configs, func(c *Configuration) bool { return c.Media.Proto[1] == AVPproto })
+	answer.SetSelectedConfig(plain)
+	if answer.Attributes.Has(AcfgAttribute) {
+		t.Fatal("acfg for the actual configuration")
+	}
+}
+
+func TestCapabilityNegotiationErrors(t *testing.T) {
+	for _, value := range []string{"", "0 t=1", "1 t=1|x", "1 a=-x:1", "1 a=1,[2", "1 a=1[2]", "1 a=", "1 x"} {
+		if _, err := ParsePotentialConfig(value); err == nil {
+			t.Fatalf("pcfg %q was accepted", value)
+		}
+	}
+	for _, value := range []string{"", "x", "1 t=0", "1 a=1,,2"} {
+		if _, err := ParseSelectedConfig(value); err == nil {
+			t.Fatalf("acfg %q was accepted", value)
+		}
+	}
+
+	for _, attributes := range []Attributes{
+		{NewAttribute(TcapAttribute, "1 RTP/XYZ")},
+		{NewAttribute(TcapAttribute, "1 RTP/AVP"), NewAttribute(TcapAttribute, "1 RTP/SAVP")},
+		{NewAttribute(AcapAttribute, "1")},
+		{NewAttribute(PcfgAttribute, "1 t=2"), NewAttribute(TcapAttribute, "1 RTP/SAVP")},
+		{NewAttribute(PcfgAttribute, "1 a=5")},
+	} {
+		s := &Session{MediaDescs: []*MediaDesc{{Proto: []string{RTPproto, AVPproto}, Attributes: attributes}}}
+		if _, err := s.Configurations(s.MediaDescs[0]); err == nil {
+			t.Fatalf("%v was accepted", attributes)
+		}
+	}
+}
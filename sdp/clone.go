@@ -0,0 +1,138 @@
+package sdp
+
+// Clone returns a deep copy of the session.
+func (s *Session) Clone() *Session {
+	if s == nil {
+		return nil
+	}
+
+	res := *s
+	if s.Originator != nil {
+		originator := *s.Originator
+		res.Originator = &originator
+	}
+	res.Emails = cloneStrings(s.Emails)
+	res.PhoneNumbers = cloneStrings(s.PhoneNumbers)
+	if s.ConnectionData != nil {
+		connection := *s.ConnectionData
+		res.ConnectionData = &connection
+	}
+	res.Bandwidths = cloneBandwidths(s.Bandwidths)
+	if s.Timings != nil {
+		res.Timings = make([]*Timing, 0, len(s.Timings))
+		for _, timing := range s.Timings {
+			res.Timings = append(res.Timings, timing.clone())
+		}
+	}
+	if s.TimeZones != nil {
+		res.TimeZones = make([]*TimeZone, 0, len(s.TimeZones))
+		for _, zone := range s.TimeZones {
+			zone := *zone
+			res.TimeZones = append(res.TimeZones, &zone)
+		}
+	}
+	res.EncryptionKeys = cloneEncryptionKeys(s.EncryptionKeys)
+	res.Attributes = cloneAttributes(s.Attributes)
+	if s.MediaDescs != nil {
+		res.MediaDescs = make([]*MediaDesc, 0, len(s.MediaDescs))
+		for _, desc := range s.MediaDescs {
+			res.MediaDescs = append(res.MediaDescs, desc.Clone())
+		}
+	}
+	res.ExtensionLines = cloneExtensionLines(s.ExtensionLines)
+	res.Raw = s.Raw.clone(s, &res)
+
+	return &res
+}
+
+// Clone returns a deep copy of the media description.
+func (m *MediaDesc) Clone() *MediaDesc {
+	if m == nil {
+		return nil
+	}
+
+	res := *m
+	res.Proto = cloneStrings(m.Proto)
+	res.Fmts = cloneStrings(m.Fmts)
+	res.Attributes = cloneAttributes(m.Attributes)
+	res.Bandwidths = cloneBandwidths(m.Bandwidths)
+	if m.Connections != nil {
+		res.Connections = make([]*Connection, 0, len(m.Connections))
+		for _, connection := range m.Connections {
+			connection := *connection
+			res.Connections = append(res.Connections, &connection)
+		}
+	}
+	res.EncryptionKeys = cloneEncryptionKeys(m.EncryptionKeys)
+	res.ExtensionLines = cloneExtensionLines(m.ExtensionLines)
+
+	return &res
+}
+
+func (t *Timing) clone() *Timing {
+	res := *t
+	if t.RepeatTimes != nil {
+		res.RepeatTimes = make([]*RepeatTime, 0, len(t.RepeatTimes))
+		for _, repeat := range t.RepeatTimes {
+			repeat := *repeat
+			repeat.Offsets = append([]int64(nil), repeat.Offsets...)
+			res.RepeatTimes = append(res.RepeatTimes, &repeat)
+		}
+	}
+	return &res
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(values)), values...)
+}
+
+func cloneAttributes(attributes Attributes) Attributes {
+	if attributes == nil {
+		return nil
+	}
+	res := make(Attributes, 0, len(attributes))
+	for _, attribute := range attributes {
+		attribute := *attribute
+		res = append(res, &attribute)
+	}
+	return res
+}
+
+func cloneBandwidths(bandwidths []*Bandwidth) []*Bandwidth {
+	if bandwidths == nil {
+		return nil
+	}
+	res := make([]*Bandwidth, 0, len(bandwidths))
+	for _, bandwidth := range bandwidths {
+		bandwidth := *bandwidth
+		res = append(res, &bandwidth)
+	}
+	return res
+}
+
+func cloneEncryptionKeys(keys []*EncryptionKey) []*EncryptionKey {
+	if keys == nil {
+		return nil
+	}
+	res := make([]*EncryptionKey, 0, len(keys))
+	for _, key := range keys {
+		key := *key
+		res = append(res, &key)
+	}
+	return res
+}
+
+func cloneExtensionLines(lines []*ExtensionLine) []*ExtensionLine {
+	if lines == nil {
+		return nil
+	}
+	res := make([]*ExtensionLine, 0, len(lines))
+	for _, line := range lines {
+		line := *line
+		res = append(res, &line)
+	}
+	return res
+}
@@ -0,0 +1,276 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	RTPMapAttribute = "rtpmap"
+	FmtpAttribute   = "fmtp"
+	RTCPFbAttribute = "rtcp-fb"
+)
+
+const (
+	H264Codec = "H264"
+)
+
+// Codec is an RTP payload format described by the fmt list, rtpmap, fmtp
+// and rtcp-fb attributes of a media description.
+type Codec struct {
+	PayloadType  uint8    `json:"payloadType"`
+	Name         string   `json:"name"`
+	ClockRate    uint32   `json:"clockRate"`
+	Channels     uint16   `json:"channels,omitempty"`
+	Fmtp         string   `json:"fmtp,omitempty"`
+	RTCPFeedback []string `json:"rtcpFeedback,omitempty"`
+}
+
+// staticCodecs are the static payload types of RFC 3551 section 6, which a
+// description may use without an rtpmap.
+var staticCodecs = map[uint8]Codec{
+	0:  {PayloadType: 0, Name: "PCMU", ClockRate: 8000, Channels: 1},
+	3:  {PayloadType: 3, Name: "GSM", ClockRate: 8000, Channels: 1},
+	4:  {PayloadType: 4, Name: "G723", ClockRate: 8000, Channels: 1},
+	5:  {PayloadType: 5, Name: "DVI4", ClockRate: 8000, Channels: 1},
+	6:  {PayloadType: 6, Name: "DVI4", ClockRate: 16000, Channels: 1},
+	7:  {PayloadType: 7, Name: "LPC", ClockRate: 8000, Channels: 1},
+	8:  {PayloadType: 8, Name: "PCMA", ClockRate: 8000, Channels: 1},
+	9:  {PayloadType: 9, Name: "G722", ClockRate: 8000, Channels: 1},
+	10: {PayloadType: 10, Name: "L16", ClockRate: 44100, Channels: 2},
+	11: {PayloadType: 11, Name: "L16", ClockRate: 44100, Channels: 1},
+	12: {PayloadType: 12, Name: "QCELP", ClockRate: 8000, Channels: 1},
+	13: {PayloadType: 13, Name: "CN", ClockRate: 8000, Channels: 1},
+	14: {PayloadType: 14, Name: "MPA", ClockRate: 90000},
+	15: {PayloadType: 15, Name: "G728", ClockRate: 8000, Channels: 1},
+	16: {PayloadType: 16, Name: "DVI4", ClockRate: 11025, Channels: 1},
+	17: {PayloadType: 17, Name: "DVI4", ClockRate: 22050, Channels: 1},
+	18: {PayloadType: 18, Name: "G729", ClockRate: 8000, Channels: 1},
+	25: {PayloadType: 25, Name: "CelB", ClockRate: 90000},
+	26: {PayloadType: 26, Name: "JPEG", ClockRate: 90000},
+	28: {PayloadType: 28, Name: "nv", ClockRate: 90000},
+	31: {PayloadType: 31, Name: "H261", ClockRate: 90000},
+	32: {PayloadType: 32, Name: "MPV", ClockRate: 90000},
+	33: {PayloadType: 33, Name: "MP2T", ClockRate: 90000},
+	34: {PayloadType: 34, Name: "H263", ClockRate: 90000},
+}
+
+// Codecs returns the codecs of an RTP media description in fmt list order.
+func (m *MediaDesc) Codecs() ([]*Codec, error) {
+	codecs := make([]*Codec, 0, len(m.Fmts))
+	byPT := make(map[uint8]*Codec, len(m.Fmts))
+
+	for _, format := range m.Fmts {
+		pt, err := parsePayloadType(format)
+		if err != nil {
+			return nil, err
+		}
+		codec := &Codec{PayloadType: pt}
+		if static, ok := staticCodecs[pt]; ok {
+			*codec = static
+		}
+		codecs = append(codecs, codec)
+		byPT[pt] = codec
+	}
+
+	for _, value := range m.Attributes.GetAll(RTPMapAttribute) {
+		pt, rest, err := splitPayloadType(value)
+		if err != nil {
+			return nil, err
+		}
+		if codec, ok := byPT[pt]; ok {
+			if err := parseRTPMap(codec, rest); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, value := range m.Attributes.GetAll(FmtpAttribute) {
+		pt, rest, err := splitPayloadType(value)
+		if err != nil {
+			return nil, err
+		}
+		if codec, ok := byPT[pt]; ok {
+			codec.Fmtp = rest
+		}
+	}
+
+	var wildcardFeedback []string
+	for _, value := range m.Attributes.GetAll(RTCPFbAttribute) {
+		if strings.HasPrefix(value, "* ") {
+			wildcardFeedback = append(wildcardFeedback, strings.TrimSpace(value[2:]))
+			continue
+		}
+		pt, rest, err := splitPayloadType(value)
+		if err != nil {
+			return nil, err
+		}
+		if codec, ok := byPT[pt]; ok {
+			codec.RTCPFeedback = append(codec.RTCPFeedback, rest)
+		}
+	}
+
+	for _, codec := range codecs {
+		if codec.Name == "" {
+			return nil, fmt.Errorf("no rtpmap for dynamic payload type %v", codec.PayloadType)
+		}
+		codec.RTCPFeedback = append(codec.RTCPFeedback, wildcardFeedback...)
+	}
+
+	return codecs, nil
+}
+
+// Codec returns the codec for the payload type pt.
+func (m *MediaDesc) Codec(pt uint8) (*Codec, error) {
+	codecs, err := m.Codecs()
+	if err != nil {
+		return nil, err
+	}
+	for _, codec := range codecs {
+		if codec.PayloadType == pt {
+			return codec, nil
+		}
+	}
+	return nil, fmt.Errorf("payload type %v not found", pt)
+}
+
+func parsePayloadType(value string) (uint8, error) {
+	pt, err := strconv.ParseUint(value, 10, 8)
+	if err != nil || pt > 127 {
+		return 0, fmt.Errorf("wrong payload type: %v", value)
+	}
+	return uint8(pt), nil
+}
+
+func splitPayloadType(value string) (uint8, string, error) {
+	index := strings.IndexByte(value, ' ')
+	if index < 0 {
+		return 0, "", fmt.Errorf("wrong payload attribute format: %v", value)
+	}
+	pt, err := parsePayloadType(value[:index])
+	if err != nil {
+		return 0, "", err
+	}
+	return pt, strings.TrimSpace(value[index+1:]), nil
+}
+
+func parseRTPMap(codec *Codec, value string) error {
+	fields := strings.Split(value, "/")
+	if len(fields) < 2 || len(fields) > 3 {
+		return fmt.Errorf("wrong rtpmap format: %v", value)
+	}
+
+	clockRate, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("wrong rtpmap clock rate: %v", value)
+	}
+
+	codec.Name = fields[0]
+	codec.ClockRate = uint32(clockRate)
+	codec.Channels = 0
+
+	if len(fields) == 3 {
+		channels, err := strconv.ParseUint(fields[2], 10, 16)
+		if err != nil {
+			return fmt.Errorf("wrong rtpmap channels: %v", value)
+		}
+		codec.Channels = uint16(channels)
+	}
+
+	return nil
+}
+
+// ParseFmtp splits format parameters of the form "key=value;key=value"
+// into a map. Parameter names are case-insensitive and are lower-cased.
+func ParseFmtp(value string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(value, ";") {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		key, val := param, ""
+		if index := strings.IndexByte(param, '='); index >= 0 {
+			key, val = param[:index], param[index+1:]
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(val)
+	}
+	return params
+}
+
+func (c *Codec) channelsOrDefault() uint16 {
+	if c.Channels == 0 {
+		return 1
+	}
+	return c.Channels
+}
+
+// SameFormat reports whether c and other have the same encoding name,
+// clock rate and number of channels.
+func (c *Codec) SameFormat(other *Codec) bool {
+	return strings.EqualFold(c.Name, other.Name) && c.ClockRate == other.ClockRate &&
+		c.channelsOrDefault() == other.channelsOrDefault()
+}
+
+// MatchCodecs selects the local codecs that can be used to answer the
+// offered ones. The result keeps the offered order and payload types, with
+// format parameters and feedback adjusted for the answer.
+func MatchCodecs(offered, local []*Codec) []*Codec {
+	var answer []*Codec
+	used := make(map[*Codec]bool)
+
+	for _, offer := range offered {
+		for _, candidate := range local {
+			if used[candidate] || !offer.SameFormat(candidate) {
+				continue
+			}
+			fmtp, ok := negotiateFmtp(offer, candidate)
+			if !ok {
+				continue
+			}
+			used[candidate] = true
+			answer = append(answer, &Codec{
+				PayloadType:  offer.PayloadType,
+				Name:         offer.Name,
+				ClockRate:    offer.ClockRate,
+				Channels:     offer.Channels,
+				Fmtp:         fmtp,
+				RTCPFeedback: intersectFeedback(offer.RTCPFeedback, candidate.RTCPFeedback),
+			})
+			break
+		}
+	}
+
+	return answer
+}
+
+func negotiateFmtp(offer, local *Codec) (string, bool) {
+	if !strings.EqualFold(offer.Name, H264Codec) {
+		return local.Fmtp, true
+	}
+
+	offerParams, err := ParseH264Params(offer.Fmtp)
+	if err != nil {
+		return "", false
+	}
+	localParams, err := ParseH264Params(local.Fmtp)
+	if err != nil {
+		return "", false
+	}
+	answer, err := NegotiateH264(offerParams, localParams)
+	if err != nil {
+		return "", false
+	}
+	return answer.String(), true
+}
+
+func intersectFeedback(offered, local []string) []string {
+	var res []string
+	for _, feedback := range offered {
+		if inSet(feedback, local) {
+			res = append(res, feedback)
+		}
+	}
+	return res
+}
@@ -0,0 +1,429 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	OpusCodec    = "opus"
+	VP8Codec     = "VP8"
+	VP9Codec     = "VP9"
+	AV1Codec     = "AV1"
+	RTXCodec     = "rtx"
+	REDCodec     = "red"
+	ULPFECCodec  = "ulpfec"
+	FlexFECCodec = "flexfec-03"
+)
+
+const (
+	opusMinPTimeParam          = "minptime"
+	opusUseInbandFECParam      = "useinbandfec"
+	opusStereoParam            = "stereo"
+	opusSpropStereoParam       = "sprop-stereo"
+	opusMaxAverageBitrateParam = "maxaveragebitrate"
+	opusMaxPlaybackRateParam   = "maxplaybackrate"
+	opusUseDTXParam            = "usedtx"
+	opusCBRParam               = "cbr"
+	vp8MaxFRParam              = "max-fr"
+	vp8MaxFSParam              = "max-fs"
+	vp9ProfileIDParam          = "profile-id"
+	av1ProfileParam            = "profile"
+	av1LevelIdxParam           = "level-idx"
+	av1TierParam               = "tier"
+	rtxAptParam                = "apt"
+	rtxTimeParam               = "rtx-time"
+	flexFECRepairWindowParam   = "repair-window"
+	opusDefaultMaxPlaybackRate = 48000
+	av1DefaultLevelIdx         = 5
+	opusMinMaxAverageBitrate   = 6000
+	opusMaxMaxAverageBitrate   = 510000
+	opusMinMaxPlaybackRate     = 8000
+	opusMaxPTime               = 120
+	opusMinPTime               = 3
+	vp9MaxProfileID            = 3
+	av1MaxProfile              = 2
+	av1MaxLevelIdx             = 23
+)
+
+// OpusParams are the Opus format parameters (RFC 7587). A zero
+// MaxPlaybackRate means the 48000 default.
+type OpusParams struct {
+	MinPTime          int
+	MaxPlaybackRate   int
+	MaxAverageBitrate int
+	Stereo            bool
+	SpropStereo       bool
+	UseInbandFEC      bool
+	UseDTX            bool
+	CBR               bool
+}
+
+// ParseOpusParams parses the Opus fmtp parameters, applying the RFC 7587
+// defaults for absent ones.
+func ParseOpusParams(fmtp string) (*OpusParams, error) {
+	var err error
+	params := OpusParams{MaxPlaybackRate: opusDefaultMaxPlaybackRate}
+
+	values := ParseFmtp(fmtp)
+	if err = parseFmtpInts(values, []fmtpInt{
+		{opusMinPTimeParam, &params.MinPTime},
+		{opusMaxPlaybackRateParam, &params.MaxPlaybackRate},
+		{opusMaxAverageBitrateParam, &params.MaxAverageBitrate},
+	}); err != nil {
+		return nil, err
+	}
+	for _, param := range []struct {
+		name  string
+		field *bool
+	}{
+		{opusStereoParam, &params.Stereo},
+		{opusSpropStereoParam, &params.SpropStereo},
+		{opusUseInbandFECParam, &params.UseInbandFEC},
+		{opusUseDTXParam, &params.UseDTX},
+		{opusCBRParam, &params.CBR},
+	} {
+		if value, ok := values[param.name]; ok {
+			if *param.field, err = parseFmtpBool(param.name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// Validate checks the parameters against the ranges of RFC 7587.
+func (p *OpusParams) Validate() error {
+	if p.MinPTime != 0 && (p.MinPTime < opusMinPTime || p.MinPTime > opusMaxPTime) {
+		return fmt.Errorf("opus minptime out of range: %v", p.MinPTime)
+	}
+	if p.MaxPlaybackRate != 0 &&
+		(p.MaxPlaybackRate < opusMinMaxPlaybackRate || p.MaxPlaybackRate > opusDefaultMaxPlaybackRate) {
+		return fmt.Errorf("opus maxplaybackrate out of range: %v", p.MaxPlaybackRate)
+	}
+	if p.MaxAverageBitrate != 0 &&
+		(p.MaxAverageBitrate < opusMinMaxAverageBitrate || p.MaxAverageBitrate > opusMaxMaxAverageBitrate) {
+		return fmt.Errorf("opus maxaveragebitrate out of range: %v", p.MaxAverageBitrate)
+	}
+	return nil
+}
+
+// String returns the parameters in fmtp form, omitting default values.
+func (p *OpusParams) String() string {
+	var params []string
+
+	if p.MaxAverageBitrate != 0 {
+		params = append(params, formatFmtpInt(opusMaxAverageBitrateParam, p.MaxAverageBitrate))
+	}
+	if p.MaxPlaybackRate != 0 && p.MaxPlaybackRate != opusDefaultMaxPlaybackRate {
+		params = append(params, formatFmtpInt(opusMaxPlaybackRateParam, p.MaxPlaybackRate))
+	}
+	if p.MinPTime != 0 {
+		params = append(params, formatFmtpInt(opusMinPTimeParam, p.MinPTime))
+	}
+	if p.SpropStereo {
+		params = append(params, opusSpropStereoParam+"=1")
+	}
+	if p.Stereo {
+		params = append(params, opusStereoParam+"=1")
+	}
+	if p.CBR {
+		params = append(params, opusCBRParam+"=1")
+	}
+	if p.UseDTX {
+		params = append(params, opusUseDTXParam+"=1")
+	}
+	if p.UseInbandFEC {
+		params = append(params, opusUseInbandFECParam+"=1")
+	}
+
+	return strings.Join(params, ";")
+}
+
+// VP8Params are the VP8 format parameters (RFC 7741).
+type VP8Params struct {
+	MaxFR int
+	MaxFS int
+}
+
+// ParseVP8Params parses the VP8 fmtp parameters.
+func ParseVP8Params(fmtp string) (*VP8Params, error) {
+	var params VP8Params
+	var err error
+
+	values := ParseFmtp(fmtp)
+	if value, ok := values[vp8MaxFRParam]; ok {
+		if params.MaxFR, err = parseFmtpInt(vp8MaxFRParam, value); err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := values[vp8MaxFSParam]; ok {
+		if params.MaxFS, err = parseFmtpInt(vp8MaxFSParam, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return &params, nil
+}
+
+func (p *VP8Params) String() string {
+	var params []string
+	if p.MaxFR != 0 {
+		params = append(params, formatFmtpInt(vp8MaxFRParam, p.MaxFR))
+	}
+	if p.MaxFS != 0 {
+		params = append(params, formatFmtpInt(vp8MaxFSParam, p.MaxFS))
+	}
+	return strings.Join(params, ";")
+}
+
+// VP9Params are the VP9 format parameters.
+type VP9Params struct {
+	ProfileID int
+}
+
+// ParseVP9Params parses the VP9 fmtp parameters. An absent profile-id is
+// profile 0.
+func ParseVP9Params(fmtp string) (*VP9Params, error) {
+	var params VP9Params
+	var err error
+
+	if value, ok := ParseFmtp(fmtp)[vp9ProfileIDParam]; ok {
+		if params.ProfileID, err = parseFmtpInt(vp9ProfileIDParam, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// Validate checks that the profile is one of the VP9 profiles 0 to 3.
+func (p *VP9Params) Validate() error {
+	if p.ProfileID < 0 || p.ProfileID > vp9MaxProfileID {
+		return fmt.Errorf("vp9 profile-id out of range: %v", p.ProfileID)
+	}
+	return nil
+}
+
+// String returns the parameters in fmtp form, omitting the default
+// profile 0.
+func (p *VP9Params) String() string {
+	if p.ProfileID == 0 {
+		return ""
+	}
+	return formatFmtpInt(vp9ProfileIDParam, p.ProfileID)
+}
+
+// AV1Params are the AV1 format parameters of the AV1 RTP payload format.
+type AV1Params struct {
+	Profile  int
+	LevelIdx int
+	Tier     int
+}
+
+// ParseAV1Params parses the AV1 fmtp parameters. Absent parameters default
+// to the Main profile, level 3.1 and the Main tier.
+func ParseAV1Params(fmtp string) (*AV1Params, error) {
+	var err error
+	params := AV1Params{LevelIdx: av1DefaultLevelIdx}
+
+	if err = parseFmtpInts(ParseFmtp(fmtp), []fmtpInt{
+		{av1ProfileParam, &params.Profile},
+		{av1LevelIdxParam, &params.LevelIdx},
+		{av1TierParam, &params.Tier},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+// Validate checks the profile, level and tier against the values the AV1
+// specification defines.
+func (p *AV1Params) Validate() error {
+	if p.Profile < 0 || p.Profile > av1MaxProfile {
+		return fmt.Errorf("av1 profile out of range: %v", p.Profile)
+	}
+	if p.LevelIdx < 0 || p.LevelIdx > av1MaxLevelIdx {
+		return fmt.Errorf("av1 level-idx out of range: %v", p.LevelIdx)
+	}
+	if p.Tier != 0 && p.Tier != 1 {
+		return fmt.Errorf("av1 tier out of range: %v", p.Tier)
+	}
+	return nil
+}
+
+// String returns the parameters in fmtp form, omitting default values.
+func (p *AV1Params) String() string {
+	var params []string
+	if p.LevelIdx != av1DefaultLevelIdx {
+		params = append(params, formatFmtpInt(av1LevelIdxParam, p.LevelIdx))
+	}
+	if p.Profile != 0 {
+		params = append(params, formatFmtpInt(av1ProfileParam, p.Profile))
+	}
+	if p.Tier != 0 {
+		params = append(params, formatFmtpInt(av1TierParam, p.Tier))
+	}
+	return strings.Join(params, ";")
+}
+
+// RTXParams are the retransmission format parameters (RFC 4588).
+type RTXParams struct {
+	APT     uint8
+	RTXTime int
+}
+
+// ParseRTXParams parses the rtx fmtp parameters, which must have the apt
+// parameter.
+func ParseRTXParams(fmtp string) (*RTXParams, error) {
+	var params RTXParams
+	var err error
+
+	values := ParseFmtp(fmtp)
+	apt, ok := values[rtxAptParam]
+	if !ok {
+		return nil, fmt.Errorf("rtx apt parameter is required")
+	}
+	if params.APT, err = parsePayloadType(apt); err != nil {
+		return nil, err
+	}
+	if value, ok := values[rtxTimeParam]; ok {
+		if params.RTXTime, err = parseFmtpInt(rtxTimeParam, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return &params, nil
+}
+
+func (p *RTXParams) String() string {
+	res := formatFmtpInt(rtxAptParam, int(p.APT))
+	if p.RTXTime != 0 {
+		res += ";" + formatFmtpInt(rtxTimeParam, p.RTXTime)
+	}
+	return res
+}
+
+// REDParams are the redundant audio format parameters (RFC 2198), i.e.
+// the payload types of the primary and redundant encodings.
+type REDParams struct {
+	PayloadTypes []uint8
+}
+
+// ParseREDParams parses the "/" separated payload types of a red fmtp.
+func ParseREDParams(fmtp string) (*REDParams, error) {
+	var params REDParams
+
+	fmtp = strings.TrimSpace(fmtp)
+	if fmtp == "" {
+		return nil, fmt.Errorf("red payload type list is required")
+	}
+	for _, value := range strings.Split(fmtp, "/") {
+		pt, err := parsePayloadType(value)
+		if err != nil {
+			return nil, err
+		}
+		params.PayloadTypes = append(params.PayloadTypes, pt)
+	}
+
+	return &params, nil
+}
+
+func (p *REDParams) String() string {
+	pts := make([]string, 0, len(p.PayloadTypes))
+	for _, pt := range p.PayloadTypes {
+		pts = append(pts, strconv.Itoa(int(pt)))
+	}
+	return strings.Join(pts, "/")
+}
+
+// FlexFECParams are the flexible FEC format parameters (RFC 8627).
+type FlexFECParams struct {
+	RepairWindow int
+}
+
+// ParseFlexFECParams parses the flexfec fmtp parameters, which must have
+// the repair-window parameter.
+func ParseFlexFECParams(fmtp string) (*FlexFECParams, error) {
+	var params FlexFECParams
+	var err error
+
+	value, ok := ParseFmtp(fmtp)[flexFECRepairWindowParam]
+	if !ok {
+		return nil, fmt.Errorf("flexfec repair-window parameter is required")
+	}
+	if params.RepairWindow, err = parseFmtpInt(flexFECRepairWindowParam, value); err != nil {
+		return nil, err
+	}
+	if err = params.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &params, nil
+}
+
+// Validate checks that the repair window is positive.
+func (p *FlexFECParams) Validate() error {
+	if p.RepairWindow <= 0 {
+		return fmt.Errorf("flexfec repair-window must be positive: %v", p.RepairWindow)
+	}
+	return nil
+}
+
+func (p *FlexFECParams) String() string {
+	return formatFmtpInt(flexFECRepairWindowParam, p.RepairWindow)
+}
+
+// fmtpInt is an integer parameter and the field it is parsed into.
+type fmtpInt struct {
+	name  string
+	field *int
+}
+
+// parseFmtpInts parses the present integer parameters in order, so that the
+// first wrong one is reported.
+func parseFmtpInts(values map[string]string, params []fmtpInt) error {
+	for _, param := range params {
+		if value, ok := values[param.name]; ok {
+			var err error
+			if *param.field, err = parseFmtpInt(param.name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseFmtpInt(name, value string) (int, error) {
+	num, err := strconv.Atoi(value)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("wrong %v: %v", name, value)
+	}
+	return num, nil
+}
+
+func parseFmtpBool(name, value string) (bool, error) {
+	switch value {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	}
+	return false, fmt.Errorf("wrong %v: %v", name, value)
+}
+
+func formatFmtpInt(name string, value int) string {
+	return name + "=" + strconv.Itoa(value)
+}
@@ -0,0 +1,107 @@
+package sdp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOpusParams(t *testing.T) {
+	params, err := ParseOpusParams("minptime=10;useinbandfec=1;stereo=1;maxaveragebitrate=64000;usedtx=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &OpusParams{
+		MinPTime:          10,
+		MaxPlaybackRate:   48000,
+		MaxAverageBitrate: 64000,
+		Stereo:            true,
+		UseInbandFEC:      true,
+	}
+	if !cmp.Equal(params, expected) {
+		t.Fatal(cmp.Diff(params, expected))
+	}
+	if params.String() != "maxaveragebitrate=64000;minptime=10;stereo=1;useinbandfec=1" {
+		t.Fatalf("wrong fmtp: %v", params.String())
+	}
+
+	if err := (&OpusParams{Stereo: true}).Validate(); err != nil {
+		t.Fatalf("default maxplaybackrate was rejected: %v", err)
+	}
+
+	for _, v := range []struct {
+		fmtp string
+		err  string
+	}{
+		{"stereo=2", "wrong stereo: 2"},
+		{"maxaveragebitrate=1000", "opus maxaveragebitrate out of range: 1000"},
+		{"minptime=500", "opus minptime out of range: 500"},
+		{"maxplaybackrate=96000", "opus maxplaybackrate out of range: 96000"},
+		{"cbr=x;maxaveragebitrate=x;minptime=x;stereo=x", "wrong minptime: x"},
+	} {
+		_, err := ParseOpusParams(v.fmtp)
+		if err == nil || err.Error() != v.err {
+			t.Fatalf("%v: wrong error: %v", v.fmtp, err)
+		}
+	}
+}
+
+func TestParseVideoParams(t *testing.T) {
+	vp8, err := ParseVP8Params("max-fr=30;max-fs=3600")
+	if err != nil || !cmp.Equal(vp8, &VP8Params{MaxFR: 30, MaxFS: 3600}) {
+		t.Fatalf("wrong vp8 params: %v, %v", vp8, err)
+	}
+
+	vp9, err := ParseVP9Params("profile-id=2")
+	if err != nil || vp9.ProfileID != 2 || vp9.String() != "profile-id=2" {
+		t.Fatalf("wrong vp9 params: %v, %v", vp9, err)
+	}
+	if value := (&VP9Params{}).String(); value != "" {
+		t.Fatalf("wrong vp9 default fmtp: %v", value)
+	}
+	if _, err := ParseVP9Params("profile-id=4"); err == nil {
+		t.Fatal("error was expected")
+	}
+
+	av1, err := ParseAV1Params("")
+	if err != nil || !cmp.Equal(av1, &AV1Params{Profile: 0, LevelIdx: 5, Tier: 0}) {
+		t.Fatalf("wrong av1 defaults: %v, %v", av1, err)
+	}
+	if value := av1.String(); value != "" {
+		t.Fatalf("wrong av1 default fmtp: %v", value)
+	}
+	av1, err = ParseAV1Params("profile=1;level-idx=8;tier=1")
+	if err != nil || av1.String() != "level-idx=8;profile=1;tier=1" {
+		t.Fatalf("wrong av1 params: %v, %v", av1, err)
+	}
+	if _, err := ParseAV1Params("tier=2"); err == nil {
+		t.Fatal("error was expected")
+	}
+}
+
+func TestParseRedundancyParams(t *testing.T) {
+	rtx, err := ParseRTXParams("apt=96;rtx-time=3000")
+	if err != nil || !cmp.Equal(rtx, &RTXParams{APT: 96, RTXTime: 3000}) {
+		t.Fatalf("wrong rtx params: %v, %v", rtx, err)
+	}
+	if _, err := ParseRTXParams("rtx-time=3000"); err == nil {
+		t.Fatal("error was expected")
+	}
+
+	red, err := ParseREDParams("111/111")
+	if err != nil || !cmp.Equal(red.PayloadTypes, []uint8{111, 111}) || red.String() != "111/111" {
+		t.Fatalf("wrong red params: %v, %v", red, err)
+	}
+	if _, err := ParseREDParams("111/abc"); err == nil {
+		t.Fatal("error was expected")
+	}
+
+	flexfec, err := ParseFlexFECParams("repair-window=10000000")
+	if err != nil || flexfec.RepairWindow != 10000000 {
+		t.Fatalf("wrong flexfec params: %v, %v", flexfec, err)
+	}
+	if _, err := ParseFlexFECParams(""); err == nil {
+		t.Fatal("error was expected")
+	}
+}
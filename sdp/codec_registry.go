@@ -0,0 +1,236 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinDynamicPayloadType = 96
+	MaxDynamicPayloadType = 127
+)
+
+// CodecCapability describes a codec supported by the local endpoint.
+type CodecCapability struct {
+	MimeType     string
+	ClockRate    uint32
+	Channels     uint16
+	Fmtp         string
+	RTCPFeedback []string
+	RTX          bool
+}
+
+func (c *CodecCapability) split() (media, name string) {
+	index := strings.IndexByte(c.MimeType, '/')
+	if index < 0 {
+		return "", c.MimeType
+	}
+	return c.MimeType[:index], c.MimeType[index+1:]
+}
+
+// CodecRegistry is the ordered list of codecs the local endpoint supports.
+type CodecRegistry struct {
+	capabilities []*CodecCapability
+}
+
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{}
+}
+
+// Register adds a codec with lower preference than the already registered
+// ones.
+func (r *CodecRegistry) Register(capability *CodecCapability) error {
+	media, name := capability.split()
+	if media == "" || name == "" {
+		return fmt.Errorf("wrong mime type: %v", capability.MimeType)
+	}
+	if capability.ClockRate == 0 {
+		return fmt.Errorf("clock rate is required for %v", capability.MimeType)
+	}
+	r.capabilities = append(r.capabilities, capability)
+	return nil
+}
+
+// Capabilities returns the registered codecs of the given media type.
+func (r *CodecRegistry) Capabilities(media string) []*CodecCapability {
+	var res []*CodecCapability
+	for _, capability := range r.capabilities {
+		if capMedia, _ := capability.split(); strings.EqualFold(capMedia, media) {
+			res = append(res, capability)
+		}
+	}
+	return res
+}
+
+// Codecs assigns payload types to the registered codecs of the given media
+// type, adding an RTX codec right after every primary that requests one.
+func (r *CodecRegistry) Codecs(media string, allocator *PayloadTypeAllocator) ([]*Codec, error) {
+	var codecs []*Codec
+
+	for _, capability := range r.Capabilities(media) {
+		_, name := capability.split()
+		codec := &Codec{
+			Name:         name,
+			ClockRate:    capability.ClockRate,
+			Channels:     capability.Channels,
+			Fmtp:         capability.Fmtp,
+			RTCPFeedback: capability.RTCPFeedback,
+		}
+		pt, err := allocator.Allocate(codec)
+		if err != nil {
+			return nil, err
+		}
+		codec.PayloadType = pt
+		codecs = append(codecs, codec)
+
+		if !capability.RTX {
+			continue
+		}
+		rtx := &Codec{
+			Name:      RTXCodec,
+			ClockRate: capability.ClockRate,
+			Fmtp:      (&RTXParams{APT: pt}).String(),
+		}
+		rtx.PayloadType, err = allocator.Allocate(rtx)
+		if err != nil {
+			return nil, err
+		}
+		codecs = append(codecs, rtx)
+	}
+
+	return codecs, nil
+}
+
+// Apply replaces the format list and the rtpmap, fmtp and rtcp-fb
+// attributes of m with the registered codecs of its media type.
+func (r *CodecRegistry) Apply(m *MediaDesc, allocator *PayloadTypeAllocator) error {
+	codecs, err := r.Codecs(m.Media, allocator)
+	if err != nil {
+		return err
+	}
+	if len(codecs) == 0 {
+		return fmt.Errorf("no registered codecs for media %v", m.Media)
+	}
+	m.SetCodecs(codecs)
+	return nil
+}
+
+// SetCodecs replaces the format list and the rtpmap, fmtp and rtcp-fb
+// attributes of m with the given codecs.
+func (m *MediaDesc) SetCodecs(codecs []*Codec) {
+	m.Attributes.Remove(RTPMapAttribute, FmtpAttribute, RTCPFbAttribute)
+
+	m.Fmts = m.Fmts[:0]
+	for _, codec := range codecs {
+		pt := strconv.Itoa(int(codec.PayloadType))
+		m.Fmts = append(m.Fmts, pt)
+
+		rtpmap := pt + " " + codec.Name + "/" + strconv.FormatUint(uint64(codec.ClockRate), 10)
+		if codec.Channels > 1 {
+			rtpmap += "/" + strconv.Itoa(int(codec.Channels))
+		}
+		m.Attributes.Add(RTPMapAttribute, rtpmap)
+		for _, feedback := range codec.RTCPFeedback {
+			m.Attributes.Add(RTCPFbAttribute, pt+" "+feedback)
+		}
+		if codec.Fmtp != "" {
+			m.Attributes.Add(FmtpAttribute, pt+" "+codec.Fmtp)
+		}
+	}
+}
+
+// RTXPairs maps the payload types of primary codecs to the payload types
+// of their RTX codecs.
+func RTXPairs(codecs []*Codec) (map[uint8]uint8, error) {
+	pairs := make(map[uint8]uint8)
+	for _, codec := range codecs {
+		if !strings.EqualFold(codec.Name, RTXCodec) {
+			continue
+		}
+		params, err := ParseRTXParams(codec.Fmtp)
+		if err != nil {
+			return nil, err
+		}
+		pairs[params.APT] = codec.PayloadType
+	}
+	return pairs, nil
+}
+
+// PayloadTypeAllocator assigns payload types to codecs. Codecs of the same
+// format get the same payload type, so one allocator can be shared by all
+// media descriptions of a BUNDLE group without collisions.
+type PayloadTypeAllocator struct {
+	assigned map[uint8]*Codec
+	next     int
+}
+
+func NewPayloadTypeAllocator() *PayloadTypeAllocator {
+	return &PayloadTypeAllocator{assigned: make(map[uint8]*Codec), next: MinDynamicPayloadType}
+}
+
+// NewBundlePayloadTypeAllocator returns an allocator that knows the codecs
+// already used by the media descriptions bundled with m.
+func NewBundlePayloadTypeAllocator(s *Session, m *MediaDesc) (*PayloadTypeAllocator, error) {
+	allocator := NewPayloadTypeAllocator()
+	for _, desc := range s.BundleGroup(m) {
+		if desc == m || !inSet(RTPproto, desc.Proto) {
+			// Data channels and other non-RTP sections have no payload types.
+			continue
+		}
+		codecs, err := desc.Codecs()
+		if err != nil {
+			return nil, err
+		}
+		if err := allocator.Reserve(codecs...); err != nil {
+			return nil, err
+		}
+	}
+	return allocator, nil
+}
+
+// Reserve marks the payload types of already negotiated codecs as taken.
+func (a *PayloadTypeAllocator) Reserve(codecs ...*Codec) error {
+	for _, codec := range codecs {
+		if assigned, ok := a.assigned[codec.PayloadType]; ok && !sameCodec(assigned, codec) {
+			return fmt.Errorf("payload type %v collision: %v and %v", codec.PayloadType, assigned.Name, codec.Name)
+		}
+		a.assigned[codec.PayloadType] = codec
+	}
+	return nil
+}
+
+// Allocate returns the static payload type of the codec, the payload type
+// already assigned to the same format, or the next free dynamic one.
+func (a *PayloadTypeAllocator) Allocate(codec *Codec) (uint8, error) {
+	// Payload types are tried in ascending order, so that the choice does not
+	// depend on the order of map iteration.
+	for pt := 0; pt <= MaxDynamicPayloadType; pt++ {
+		if assigned, ok := a.assigned[uint8(pt)]; ok && sameCodec(assigned, codec) {
+			return uint8(pt), nil
+		}
+	}
+
+	for pt := 0; pt < MinDynamicPayloadType; pt++ {
+		static, ok := staticCodecs[uint8(pt)]
+		if _, taken := a.assigned[uint8(pt)]; ok && !taken && static.SameFormat(codec) {
+			a.assigned[uint8(pt)] = codec
+			return uint8(pt), nil
+		}
+	}
+
+	for ; a.next <= MaxDynamicPayloadType; a.next++ {
+		pt := uint8(a.next)
+		if _, taken := a.assigned[pt]; !taken {
+			a.assigned[pt] = codec
+			a.next++
+			return pt, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no free dynamic payload types for %v", codec.Name)
+}
+
+func sameCodec(a, b *Codec) bool {
+	return a.SameFormat(b) && a.Fmtp == b.Fmtp
+}
@@ -0,0 +1,132 @@
+package sdp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestRegistry(t *testing.T) *CodecRegistry {
+	registry := NewCodecRegistry()
+	for _, capability := range []*CodecCapability{
+		{MimeType: "audio/opus", ClockRate: 48000, Channels: 2, Fmtp: "minptime=10;useinbandfec=1"},
+		{MimeType: "audio/PCMU", ClockRate: 8000},
+		{MimeType: "video/VP8", ClockRate: 90000, RTCPFeedback: []string{"nack", "nack pli"}, RTX: true},
+		{MimeType: "video/H264", ClockRate: 90000, Fmtp: "packetization-mode=1;profile-level-id=42e01f", RTX: true},
+	} {
+		if err := registry.Register(capability); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+func TestCodecRegistryApply(t *testing.T) {
+	registry := newTestRegistry(t)
+	allocator := NewPayloadTypeAllocator()
+
+	audio := &MediaDesc{Media: "audio", Attributes: []*Attribute{{Name: "mid", Value: "0"}}}
+	if err := registry.Apply(audio, allocator); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &MediaDesc{
+		Media: "audio",
+		Fmts:  []string{"96", "0"},
+		Attributes: []*Attribute{
+			{Name: "mid", Value: "0"},
+			{Name: "rtpmap", Value: "96 opus/48000/2"},
+			{Name: "fmtp", Value: "96 minptime=10;useinbandfec=1"},
+			{Name: "rtpmap", Value: "0 PCMU/8000"},
+		},
+	}
+	if !cmp.Equal(audio, expected) {
+		t.Fatal(cmp.Diff(audio, expected))
+	}
+
+	video := &MediaDesc{Media: "video"}
+	if err := registry.Apply(video, allocator); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(video.Fmts, []string{"97", "98", "99", "100"}) {
+		t.Fatalf("wrong fmts: %v", video.Fmts)
+	}
+
+	codecs, err := video.Codecs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs, err := RTXPairs(codecs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(pairs, map[uint8]uint8{97: 98, 99: 100}) {
+		t.Fatalf("wrong rtx pairs: %v", pairs)
+	}
+}
+
+func TestBundlePayloadTypeAllocator(t *testing.T) {
+	audio := &MediaDesc{
+		Media: "audio",
+		Proto: []string{UDPproto, TLSproto, RTPproto, SAVPFproto},
+		Fmts:  []string{"96", "97"},
+		Attributes: []*Attribute{
+			{Name: "mid", Value: "0"},
+			{Name: "rtpmap", Value: "96 opus/48000/2"},
+			{Name: "rtpmap", Value: "97 telephone-event/8000"},
+		},
+	}
+	video := &MediaDesc{
+		Media:      "video",
+		Proto:      []string{UDPproto, TLSproto, RTPproto, SAVPFproto},
+		Attributes: []*Attribute{{Name: "mid", Value: "1"}},
+	}
+	data := &MediaDesc{
+		Media:      "application",
+		Proto:      []string{UDPproto, DTLSproto, SCTPproto},
+		Fmts:       []string{"webrtc-datachannel"},
+		Attributes: []*Attribute{{Name: "mid", Value: "3"}, {Name: "sctp-port", Value: "5000"}},
+	}
+	other := &MediaDesc{
+		Media:      "video",
+		Proto:      []string{UDPproto, TLSproto, RTPproto, SAVPFproto},
+		Fmts:       []string{"98"},
+		Attributes: []*Attribute{{Name: "mid", Value: "2"}, {Name: "rtpmap", Value: "98 VP9/90000"}},
+	}
+	s := &Session{
+		Attributes: []*Attribute{{Name: "group", Value: "BUNDLE 0 1 3"}},
+		MediaDescs: []*MediaDesc{audio, video, other, data},
+	}
+
+	allocator, err := NewBundlePayloadTypeAllocator(s, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestRegistry(t).Apply(video, allocator); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(video.Fmts, []string{"98", "99", "100", "101"}) {
+		t.Fatalf("wrong fmts: %v", video.Fmts)
+	}
+
+	if err := allocator.Reserve(&Codec{PayloadType: 96, Name: "G722", ClockRate: 8000}); err == nil {
+		t.Fatal("collision error was expected")
+	}
+
+	// The same format reserved under two payload types is always answered
+	// with the lower one.
+	for i := 0; i < 20; i++ {
+		allocator := NewPayloadTypeAllocator()
+		opus := &Codec{Name: "opus", ClockRate: 48000, Channels: 2}
+		for _, pt := range []uint8{111, 100, 120} {
+			codec := *opus
+			codec.PayloadType = pt
+			if err := allocator.Reserve(&codec); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if pt, err := allocator.Allocate(opus); err != nil || pt != 100 {
+			t.Fatalf("wrong payload type: %v, %v", pt, err)
+		}
+	}
+}
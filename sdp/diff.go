@@ -0,0 +1,454 @@
+package sdp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind int
+
+const (
+	OriginVersionChanged ChangeKind = iota
+	OriginChanged
+	FieldChanged
+	MediaAdded
+	MediaRemoved
+	PortChanged
+	CodecAdded
+	CodecRemoved
+	CodecChanged
+	DirectionChanged
+	ICERestart
+	FingerprintChanged
+	AttributeAdded
+	AttributeRemoved
+	ProtoChanged
+	CodecOrderChanged
+)
+
+var changeKindNames = map[ChangeKind]string{
+	OriginVersionChanged: "origin version changed",
+	OriginChanged:        "origin changed",
+	FieldChanged:         "field changed",
+	MediaAdded:           "media added",
+	MediaRemoved:         "media removed",
+	PortChanged:          "port changed",
+	CodecAdded:           "codec added",
+	CodecRemoved:         "codec removed",
+	CodecChanged:         "codec changed",
+	DirectionChanged:     "direction changed",
+	ICERestart:           "ice restart",
+	FingerprintChanged:   "fingerprint changed",
+	AttributeAdded:       "attribute added",
+	AttributeRemoved:     "attribute removed",
+	ProtoChanged:         "proto changed",
+	CodecOrderChanged:    "codec order changed",
+}
+
+func (k ChangeKind) String() string {
+	if name, ok := changeKindNames[k]; ok {
+		return name
+	}
+	return "change " + strconv.Itoa(int(k))
+}
+
+// Change is a single difference between two sessions. Media identifies the
+// media description by its mid, or by "#<index>" if it has none, and is
+// empty for session-level changes.
+type Change struct {
+	Kind  ChangeKind
+	Media string
+	Old   string
+	New   string
+}
+
+func (c *Change) String() string {
+	var res strings.Builder
+	if c.Media == "" {
+		res.WriteString("session: ")
+	} else {
+		res.WriteString("media " + c.Media + ": ")
+	}
+	res.WriteString(c.Kind.String())
+	switch {
+	case c.Old != "" && c.New != "":
+		res.WriteString(": " + c.Old + " -> " + c.New)
+	case c.Old != "":
+		res.WriteString(": " + c.Old)
+	case c.New != "":
+		res.WriteString(": " + c.New)
+	}
+	return res.String()
+}
+
+// Attributes compared by dedicated rules rather than as plain attributes.
+var diffSpecialAttributes = []string{
+	RTPMapAttribute, FmtpAttribute, RTCPFbAttribute,
+	SendRecvAttribute, SendOnlyAttribute, RecvOnlyAttribute, InactiveAttribute,
+	IceUfragAttribute, IcePwdAttribute, FingerprintAttribute,
+}
+
+type differ struct {
+	a, b    *Session
+	changes []*Change
+}
+
+func (d *differ) add(kind ChangeKind, media, before, after string) {
+	d.changes = append(d.changes, &Change{Kind: kind, Media: media, Old: before, New: after})
+}
+
+// Diff returns the changes that turn session a into session b. Media
+// descriptions are matched by mid when present and by index otherwise, and
+// the order of attributes is ignored.
+func Diff(a, b *Session) []*Change {
+	d := &differ{a: a, b: b}
+
+	d.diffSession()
+	d.diffMedia()
+
+	return d.changes
+}
+
+func (d *differ) diffSession() {
+	a, b := d.a, d.b
+
+	switch {
+	case a.Originator == nil && b.Originator == nil:
+	case a.Originator == nil:
+		d.add(OriginChanged, "", "", b.Originator.String())
+	case b.Originator == nil:
+		d.add(OriginChanged, "", a.Originator.String(), "")
+	default:
+		originA, originB := *a.Originator, *b.Originator
+		originA.SessVersion, originB.SessVersion = 0, 0
+		if originA != originB {
+			d.add(OriginChanged, "", a.Originator.String(), b.Originator.String())
+		} else if a.Originator.SessVersion != b.Originator.SessVersion {
+			d.add(OriginVersionChanged, "", strconv.FormatInt(a.Originator.SessVersion, 10),
+				strconv.FormatInt(b.Originator.SessVersion, 10))
+		}
+	}
+
+	d.diffField("", SessionNameField, a.SessionName, b.SessionName)
+	d.diffField("", SessionInfoField, a.Information, b.Information)
+	d.diffField("", URIField, a.URI, b.URI)
+	d.diffField("", EmailField, strings.Join(a.Emails, ", "), strings.Join(b.Emails, ", "))
+	d.diffField("", PhoneNumberField, strings.Join(a.PhoneNumbers, ", "), strings.Join(b.PhoneNumbers, ", "))
+	d.diffField("", ConnectionDataField, formatConnection(a.ConnectionData), formatConnection(b.ConnectionData))
+	d.diffField("", BandwidthField, formatBandwidths(a.Bandwidths), formatBandwidths(b.Bandwidths))
+	d.diffField("", TimingField, formatTimings(a.Timings), formatTimings(b.Timings))
+	d.diffField("", RepeatTimeField, formatRepeatTimes(a.Timings), formatRepeatTimes(b.Timings))
+	d.diffField("", TimeZoneField, formatTimeZones(a.TimeZones), formatTimeZones(b.TimeZones))
+	d.diffField("", EncryptionKeyField, formatEncryptionKeys(a.EncryptionKeys), formatEncryptionKeys(b.EncryptionKeys))
+	d.diffTransport("", nil, nil)
+	d.diffAttributes("", a.Attributes, b.Attributes)
+}
+
+func (d *differ) diffField(media string, field byte, before, after string) {
+	if before != after {
+		d.add(FieldChanged, media, string(field)+"="+before, string(field)+"="+after)
+	}
+}
+
+func (d *differ) diffMedia() {
+	matched := make(map[*MediaDesc]bool)
+
+	for i, descB := range d.b.MediaDescs {
+		descA := d.match(i, descB, matched)
+		if descA == nil {
+			d.add(MediaAdded, mediaName(i, descB), "", formatMediaLine(descB))
+			continue
+		}
+		matched[descA] = true
+		d.diffMediaDesc(mediaName(i, descB), descA, descB)
+	}
+
+	for i, descA := range d.a.MediaDescs {
+		if !matched[descA] {
+			d.add(MediaRemoved, mediaName(i, descA), formatMediaLine(descA), "")
+		}
+	}
+}
+
+func (d *differ) match(index int, desc *MediaDesc, matched map[*MediaDesc]bool) *MediaDesc {
+	if mid := desc.Mid(); mid != "" {
+		for _, candidate := range d.a.MediaDescs {
+			if !matched[candidate] && candidate.Mid() == mid {
+				return candidate
+			}
+		}
+	}
+	if index < len(d.a.MediaDescs) {
+		candidate := d.a.MediaDescs[index]
+		if !matched[candidate] && candidate.Mid() == "" && candidate.Media == desc.Media {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func mediaName(index int, desc *MediaDesc) string {
+	if mid := desc.Mid(); mid != "" {
+		return mid
+	}
+	return "#" + strconv.Itoa(index)
+}
+
+func (d *differ) diffMediaDesc(name string, a, b *MediaDesc) {
+	if a.Port != b.Port {
+		d.add(PortChanged, name, strconv.FormatInt(a.Port, 10), strconv.FormatInt(b.Port, 10))
+	}
+	if protoA, protoB := strings.Join(a.Proto, "/"), strings.Join(b.Proto, "/"); protoA != protoB {
+		d.add(ProtoChanged, name, protoA, protoB)
+	}
+
+	d.diffField(name, SessionInfoField, a.Information, b.Information)
+	d.diffField(name, ConnectionDataField, formatConnections(a.Connections), formatConnections(b.Connections))
+	d.diffField(name, BandwidthField, formatBandwidths(a.Bandwidths), formatBandwidths(b.Bandwidths))
+	d.diffField(name, EncryptionKeyField, formatEncryptionKeys(a.EncryptionKeys), formatEncryptionKeys(b.EncryptionKeys))
+
+	d.diffTransport(name, a, b)
+	d.diffCodecs(name, a, b)
+	d.diffAttributes(name, a.Attributes, b.Attributes)
+}
+
+// diffTransport compares the direction, the ICE credentials and the
+// fingerprint of the session if a and b are nil, or of two media
+// descriptions. Values that both media descriptions inherit from the
+// session are compared only for the session.
+func (d *differ) diffTransport(name string, a, b *MediaDesc) {
+	own := func(names ...string) bool {
+		if a == nil {
+			return true
+		}
+		for _, name := range names {
+			if a.Attributes.Has(name) || b.Attributes.Has(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if own(SendRecvAttribute, SendOnlyAttribute, RecvOnlyAttribute, InactiveAttribute) {
+		if directionA, directionB := direction(d.a, a), direction(d.b, b); directionA != directionB {
+			d.add(DirectionChanged, name, directionA, directionB)
+		}
+	}
+
+	if own(IceUfragAttribute, IcePwdAttribute) {
+		ufragA, _ := mediaAttribute(d.a, a, IceUfragAttribute)
+		ufragB, _ := mediaAttribute(d.b, b, IceUfragAttribute)
+		pwdA, _ := mediaAttribute(d.a, a, IcePwdAttribute)
+		pwdB, _ := mediaAttribute(d.b, b, IcePwdAttribute)
+		if ufragA != ufragB {
+			d.add(ICERestart, name, ufragA, ufragB)
+		} else if pwdA != pwdB {
+			// The passwords are credentials and are left out of the
+			// change, which may end up in logs.
+			d.add(ICERestart, name, "", "ice-pwd changed")
+		}
+	}
+
+	if own(FingerprintAttribute) {
+		fingerprintA, _ := mediaAttribute(d.a, a, FingerprintAttribute)
+		fingerprintB, _ := mediaAttribute(d.b, b, FingerprintAttribute)
+		if fingerprintA != fingerprintB {
+			d.add(FingerprintChanged, name, fingerprintA, fingerprintB)
+		}
+	}
+}
+
+// direction returns the direction of a media description, or of the
+// session if m is nil.
+func direction(s *Session, m *MediaDesc) string {
+	if m != nil {
+		return s.Direction(m)
+	}
+	if direction := findDirection(s.Attributes); direction != "" {
+		return direction
+	}
+	return SendRecvAttribute
+}
+
+// mediaAttribute returns an attribute of a media description, or of the
+// session if m is nil or does not have it.
+func mediaAttribute(s *Session, m *MediaDesc, name string) (string, bool) {
+	if m != nil {
+		if value, ok := m.Attributes.Get(name); ok {
+			return value, ok
+		}
+	}
+	return s.Attributes.Get(name)
+}
+
+func (d *differ) diffCodecs(name string, a, b *MediaDesc) {
+	codecsA, errA := a.Codecs()
+	codecsB, errB := b.Codecs()
+	if errA != nil || errB != nil {
+		if strings.Join(a.Fmts, " ") != strings.Join(b.Fmts, " ") {
+			d.add(CodecChanged, name, strings.Join(a.Fmts, " "), strings.Join(b.Fmts, " "))
+		}
+		return
+	}
+
+	byPT := make(map[uint8]*Codec, len(codecsA))
+	for _, codec := range codecsA {
+		byPT[codec.PayloadType] = codec
+	}
+
+	for _, codec := range codecsB {
+		old, ok := byPT[codec.PayloadType]
+		if !ok {
+			d.add(CodecAdded, name, "", formatCodec(codec))
+			continue
+		}
+		delete(byPT, codec.PayloadType)
+		if formatCodec(old) != formatCodec(codec) {
+			d.add(CodecChanged, name, formatCodec(old), formatCodec(codec))
+		}
+	}
+
+	for _, codec := range codecsA {
+		if _, ok := byPT[codec.PayloadType]; ok {
+			d.add(CodecRemoved, name, formatCodec(codec), "")
+		}
+	}
+
+	// The order of the codecs is the order of preference.
+	orderA := commonPayloadTypes(codecsA, codecsB)
+	orderB := commonPayloadTypes(codecsB, codecsA)
+	if orderA != orderB {
+		d.add(CodecOrderChanged, name, orderA, orderB)
+	}
+}
+
+// commonPayloadTypes returns the payload types of the codecs that are also
+// in others, in their order.
+func commonPayloadTypes(codecs, others []*Codec) string {
+	in := make(map[uint8]bool, len(others))
+	for _, codec := range others {
+		in[codec.PayloadType] = true
+	}
+	res := make([]string, 0, len(codecs))
+	for _, codec := range codecs {
+		if in[codec.PayloadType] {
+			res = append(res, strconv.Itoa(int(codec.PayloadType)))
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+func (d *differ) diffAttributes(name string, a, b Attributes) {
+	count := make(map[string]int)
+	for _, attribute := range a {
+		if !inSet(attribute.Name, diffSpecialAttributes) {
+			count[attribute.String()]++
+		}
+	}
+	var added []string
+	for _, attribute := range b {
+		if inSet(attribute.Name, diffSpecialAttributes) {
+			continue
+		}
+		key := attribute.String()
+		if count[key] > 0 {
+			count[key]--
+		} else {
+			added = append(added, key)
+		}
+	}
+
+	var removed []string
+	for key, n := range count {
+		for ; n > 0; n-- {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, key := range removed {
+		d.add(AttributeRemoved, name, key, "")
+	}
+	for _, key := range added {
+		d.add(AttributeAdded, name, "", key)
+	}
+}
+
+func formatCodec(codec *Codec) string {
+	res := fmt.Sprintf("%v %v/%v", codec.PayloadType, codec.Name, codec.ClockRate)
+	if codec.Channels > 1 {
+		res += "/" + strconv.Itoa(int(codec.Channels))
+	}
+	if codec.Fmtp != "" {
+		res += " " + codec.Fmtp
+	}
+	return res
+}
+
+func formatConnection(connection *Connection) string {
+	if connection == nil {
+		return ""
+	}
+	return connection.String()
+}
+
+func formatConnections(connections []*Connection) string {
+	res := make([]string, 0, len(connections))
+	for _, connection := range connections {
+		res = append(res, connection.String())
+	}
+	return strings.Join(res, ", ")
+}
+
+func formatTimings(timings []*Timing) string {
+	res := make([]string, 0, len(timings))
+	for _, timing := range timings {
+		res = append(res, timing.String())
+	}
+	return strings.Join(res, ", ")
+}
+
+// formatRepeatTimes returns the r= values of all timings, those of different
+// timings separated by a semicolon.
+func formatRepeatTimes(timings []*Timing) string {
+	res := make([]string, 0, len(timings))
+	for _, timing := range timings {
+		repeats := make([]string, 0, len(timing.RepeatTimes))
+		for _, repeat := range timing.RepeatTimes {
+			repeats = append(repeats, repeat.String())
+		}
+		res = append(res, strings.Join(repeats, ", "))
+	}
+	return strings.TrimRight(strings.Join(res, "; "), "; ")
+}
+
+func formatTimeZones(timeZones []*TimeZone) string {
+	res := make([]string, 0, len(timeZones))
+	for _, timeZone := range timeZones {
+		res = append(res, timeZone.String())
+	}
+	return strings.Join(res, " ")
+}
+
+func formatEncryptionKeys(keys []*EncryptionKey) string {
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, key.String())
+	}
+	return strings.Join(res, ", ")
+}
+
+func formatBandwidths(bandwidths []*Bandwidth) string {
+	res := make([]string, 0, len(bandwidths))
+	for _, bandwidth := range bandwidths {
+		res = append(res, bandwidth.Type+":"+strconv.Itoa(bandwidth.Value))
+	}
+	return strings.Join(res, " ")
+}
+
+func formatMediaLine(desc *MediaDesc) string {
+	return desc.Media + " " + strconv.FormatInt(desc.Port, 10) + " " + strings.Join(desc.Proto, "/") + " " +
+		strings.Join(desc.Fmts, " ")
+}
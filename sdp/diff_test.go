@@ -0,0 +1,216 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := NewDecoder(strings.NewReader(jsepOffer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := a.Clone()
+	b.Originator.SessVersion = 3
+	b.MediaDescs[0], b.MediaDescs[1] = b.MediaDescs[1], b.MediaDescs[0]
+	audio := b.MediaDescs[1]
+	audio.Attributes.Remove(SendRecvAttribute)
+	audio.Attributes.Add(RecvOnlyAttribute, "")
+	audio.Attributes.Set(IceUfragAttribute, "Xk3v")
+	audio.Fmts = append(audio.Fmts, "0")
+	audio.Attributes.Add("ptime", "20")
+	b.MediaDescs = append(b.MediaDescs, &MediaDesc{
+		Media: "video", Port: 9, Proto: []string{"UDP", "TLS", "RTP", "SAVPF"}, Fmts: []string{"96"},
+		Attributes: Attributes{{Name: "mid", Value: "2"}, {Name: "rtpmap", Value: "96 VP8/90000"}},
+	})
+
+	changes := Diff(a, b)
+	expected := []*Change{
+		{Kind: OriginVersionChanged, Old: "2", New: "3"},
+		{Kind: DirectionChanged, Media: "0", Old: "sendrecv", New: "recvonly"},
+		{Kind: ICERestart, Media: "0", Old: "EsAw", New: "Xk3v"},
+		{Kind: CodecAdded, Media: "0", New: "0 PCMU/8000"},
+		{Kind: AttributeAdded, Media: "0", New: "ptime:20"},
+		{Kind: MediaAdded, Media: "2", New: "video 9 UDP/TLS/RTP/SAVPF 96"},
+	}
+	if !cmp.Equal(changes, expected) {
+		t.Fatal(cmp.Diff(changes, expected))
+	}
+	if changes[2].String() != "media 0: ice restart: EsAw -> Xk3v" {
+		t.Fatalf("wrong change description: %v", changes[2].String())
+	}
+
+	if changes := Diff(a, a.Clone()); len(changes) != 0 {
+		t.Fatalf("no changes were expected: %v", changes)
+	}
+
+	changes = Diff(b, a)
+	if changes[len(changes)-1].Kind != MediaRemoved || changes[len(changes)-1].Media != "2" {
+		t.Fatalf("media removal was expected: %v", changes)
+	}
+
+	c := a.Clone()
+	c.Originator = nil
+	c.Bandwidths = []*Bandwidth{{Type: "AS", Value: 256}}
+	data := c.MediaDescs[1]
+	data.Proto = []string{"TCP", "DTLS", "SCTP"}
+	data.Connections = []*Connection{{Nettype: NetworkInternet, Addrtype: TypeIPv4, ConnectionAddr: "192.0.2.1"}}
+	data.Bandwidths = []*Bandwidth{{Type: "TIAS", Value: 128000}}
+
+	changes = Diff(a, c)
+	expected = []*Change{
+		{Kind: OriginChanged, Old: a.Originator.String()},
+		{Kind: FieldChanged, Old: "b=", New: "b=AS:256"},
+		{Kind: ProtoChanged, Media: "1", Old: "UDP/DTLS/SCTP", New: "TCP/DTLS/SCTP"},
+		{Kind: FieldChanged, Media: "1", Old: "c=IN IP4 0.0.0.0", New: "c=IN IP4 192.0.2.1"},
+		{Kind: FieldChanged, Media: "1", Old: "b=", New: "b=TIAS:128000"},
+	}
+	if !cmp.Equal(changes, expected) {
+		t.Fatal(cmp.Diff(changes, expected))
+	}
+	if changes = Diff(c, a); changes[0].Kind != OriginChanged || changes[0].New != a.Originator.String() {
+		t.Fatalf("origin change was expected: %v", changes)
+	}
+}
+
+const diffFieldsSession = `v=0
+o=- 1 1 IN IP4 127.0.0.1
+s=-
+e=alice@example.com
+p=+1 617 555-6011
+c=IN IP4 224.2.1.1/127
+t=2873397496 2873404696
+r=7d 1h 0 25h
+z=2882844526 -1h 2898848070 0
+k=clear:secret
+m=audio 49170 RTP/AVP 0
+i=voice
+k=prompt
+`
+
+func TestDiffFields(t *testing.T) {
+	a, err := NewDecoder(strings.NewReader(diffFieldsSession)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		edit     func(s *Session)
+		expected *Change
+	}{
+		{
+			func(s *Session) { s.Emails = append(s.Emails, "bob@example.com") },
+			&Change{Kind: FieldChanged, Old: "e=alice@example.com", New: "e=alice@example.com, bob@example.com"},
+		},
+		{
+			func(s *Session) { s.PhoneNumbers = nil },
+			&Change{Kind: FieldChanged, Old: "p=+1 617 555-6011", New: "p="},
+		},
+		{
+			func(s *Session) { s.ConnectionData.TTL = 64 },
+			&Change{Kind: FieldChanged, Old: "c=IN IP4 224.2.1.1/127/1", New: "c=IN IP4 224.2.1.1/64/1"},
+		},
+		{
+			func(s *Session) { s.Timings[0].Start, s.Timings[0].Stop = 0, 0 },
+			&Change{Kind: FieldChanged, Old: "t=2873397496 2873404696", New: "t=0 0"},
+		},
+		{
+			func(s *Session) { s.Timings[0].RepeatTimes[0].Interval = 86400 },
+			&Change{Kind: FieldChanged, Old: "r=604800 3600 0 90000", New: "r=86400 3600 0 90000"},
+		},
+		{
+			func(s *Session) { s.TimeZones = s.TimeZones[:1] },
+			&Change{Kind: FieldChanged, Old: "z=2882844526 -3600 2898848070 0", New: "z=2882844526 -3600"},
+		},
+		{
+			func(s *Session) { s.EncryptionKeys[0].Value = "other" },
+			&Change{Kind: FieldChanged, Old: "k=clear:secret", New: "k=clear:other"},
+		},
+		{
+			func(s *Session) { s.MediaDescs[0].Information = "music" },
+			&Change{Kind: FieldChanged, Media: "#0", Old: "i=voice", New: "i=music"},
+		},
+		{
+			func(s *Session) { s.MediaDescs[0].EncryptionKeys = nil },
+			&Change{Kind: FieldChanged, Media: "#0", Old: "k=prompt", New: "k="},
+		},
+	}
+
+	for _, test := range tests {
+		b := a.Clone()
+		test.edit(b)
+		if changes, expected := Diff(a, b), []*Change{test.expected}; !cmp.Equal(changes, expected) {
+			t.Error(cmp.Diff(changes, expected))
+		}
+	}
+}
+
+func TestDiffOriginAndICE(t *testing.T) {
+	a, err := NewDecoder(strings.NewReader(jsepOffer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.MediaDescs[0].Fmts = []string{"111", "0"}
+	b := a.Clone()
+	b.Originator.Addrtype = TypeIPv6
+	b.MediaDescs[0].Attributes.Set(IcePwdAttribute, "asd88fgpdd777uzjYhagZg")
+	b.MediaDescs[0].Fmts = []string{"0", "111"}
+
+	changes := Diff(a, b)
+	expected := []*Change{
+		{Kind: OriginChanged, Old: "- 4611731400430051336 2 IN IP4 127.0.0.1", New: "- 4611731400430051336 2 IN IP6 127.0.0.1"},
+		{Kind: ICERestart, Media: "0", New: "ice-pwd changed"},
+		{Kind: CodecOrderChanged, Media: "0", Old: "111 0", New: "0 111"},
+	}
+	if !cmp.Equal(changes, expected) {
+		t.Fatal(cmp.Diff(changes, expected))
+	}
+	for _, change := range changes {
+		if text := change.String(); strings.Contains(text, "P2uYro0UCOQ4zxjKXaWCBui1") || strings.Contains(text, "asd88fgpdd777uzjYhagZg") {
+			t.Fatalf("ice password in change: %v", text)
+		}
+	}
+}
+
+const diffNoMediaSession = `v=0
+o=- 1 1 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=ice-ufrag:EsAw
+a=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1
+a=fingerprint:sha-256 D2:FA:0E:C3:22:59:5E:14:95:69:92:3D:13:B4:84:24:2C:C2:A2:C0:3E:FD:34:8E:5E:EA:6F:AF:52:CE:E6:0F
+a=sendrecv
+`
+
+func TestDiffSessionTransport(t *testing.T) {
+	a, err := NewDecoder(strings.NewReader(diffNoMediaSession)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := a.Clone()
+	b.Attributes.Remove(SendRecvAttribute)
+	b.Attributes.Add(InactiveAttribute, "")
+	b.Attributes.Set(IcePwdAttribute, "asd88fgpdd777uzjYhagZg")
+	b.Attributes.Set(FingerprintAttribute, "sha-256 00")
+	expected := []*Change{
+		{Kind: DirectionChanged, Old: "sendrecv", New: "inactive"},
+		{Kind: ICERestart, New: "ice-pwd changed"},
+		{Kind: FingerprintChanged, Old: a.Attributes[2].Value, New: "sha-256 00"},
+	}
+	if changes := Diff(a, b); !cmp.Equal(changes, expected) {
+		t.Fatal(cmp.Diff(changes, expected))
+	}
+
+	// A media description that inherits the changes does not repeat them.
+	m := &MediaDesc{Media: "audio", Port: 9, Proto: []string{"RTP", "AVP"}, Fmts: []string{"0"}}
+	a.MediaDescs = append(a.MediaDescs, m)
+	b.MediaDescs = append(b.MediaDescs, m.Clone())
+	if changes := Diff(a, b); !cmp.Equal(changes, expected) {
+		t.Fatal(cmp.Diff(changes, expected))
+	}
+}
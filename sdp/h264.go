@@ -0,0 +1,304 @@
+package sdp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type H264Profile int
+
+const (
+	H264ProfileConstrainedBaseline H264Profile = iota
+	H264ProfileBaseline
+	H264ProfileMain
+	H264ProfileConstrainedHigh
+	H264ProfileHigh
+	H264ProfilePredictiveHigh444
+)
+
+// H264Level is the level_idc value of a profile-level-id. Level 1b is 9, its
+// level_idc in the High profiles, whatever the profile; it is between levels
+// 1 and 1.1, see LessH264Level. The zero value is no level.
+type H264Level int
+
+const (
+	H264Level1b  H264Level = 9
+	H264Level1   H264Level = 10
+	H264Level1_1 H264Level = 11
+	H264Level1_2 H264Level = 12
+	H264Level1_3 H264Level = 13
+	H264Level2   H264Level = 20
+	H264Level2_1 H264Level = 21
+	H264Level2_2 H264Level = 22
+	H264Level3   H264Level = 30
+	H264Level3_1 H264Level = 31
+	H264Level3_2 H264Level = 32
+	H264Level4   H264Level = 40
+	H264Level4_1 H264Level = 41
+	H264Level4_2 H264Level = 42
+	H264Level5   H264Level = 50
+	H264Level5_1 H264Level = 51
+	H264Level5_2 H264Level = 52
+)
+
+const (
+	h264ProfileLevelIDParam     = "profile-level-id"
+	h264PacketizationModeParam  = "packetization-mode"
+	h264LevelAsymmetryParam     = "level-asymmetry-allowed"
+	h264SpropParameterSetsParam = "sprop-parameter-sets"
+	h264MaxMBPSParam            = "max-mbps"
+	h264MaxFSParam              = "max-fs"
+	h264MaxBRParam              = "max-br"
+)
+
+const h264DefaultProfileLevelID = "42000a"
+
+const h264ConstraintSet3Flag = 0x10
+
+type h264ProfilePattern struct {
+	profileIDC byte
+	mask       byte
+	value      byte
+	profile    H264Profile
+}
+
+// h264ProfilePatterns maps profile_idc and profile_iop bit patterns to
+// profiles, following RFC 6184 section 8.1 and the H.264 constraint flags.
+var h264ProfilePatterns = []h264ProfilePattern{
+	{0x42, 0x4f, 0x40, H264ProfileConstrainedBaseline},
+	{0x4d, 0x8f, 0x80, H264ProfileConstrainedBaseline},
+	{0x58, 0xcf, 0xc0, H264ProfileConstrainedBaseline},
+	{0x42, 0x4f, 0x00, H264ProfileBaseline},
+	{0x58, 0xcf, 0x80, H264ProfileBaseline},
+	{0x4d, 0xaf, 0x00, H264ProfileMain},
+	{0x64, 0xff, 0x00, H264ProfileHigh},
+	{0x64, 0xff, 0x0c, H264ProfileConstrainedHigh},
+	{0xf4, 0xff, 0x00, H264ProfilePredictiveHigh444},
+}
+
+type H264ProfileLevelID struct {
+	Profile H264Profile
+	Level   H264Level
+}
+
+// ParseH264ProfileLevelID parses the hexadecimal profile-level-id value.
+func ParseH264ProfileLevelID(value string) (*H264ProfileLevelID, error) {
+	if len(value) != 6 {
+		return nil, fmt.Errorf("wrong profile-level-id format: %v", value)
+	}
+	num, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("wrong profile-level-id format: %v", value)
+	}
+
+	profileIDC := byte(num >> 16)
+	profileIOP := byte(num >> 8)
+	levelIDC := byte(num)
+
+	// Level 1b is written as level 1.1 with constraint_set3 only in the
+	// Baseline, Main and Extended profiles (RFC 6184 section 8.1); in the
+	// High profiles constraint_set3 marks the intra profiles.
+	baselineMainExtended := profileIDC == 0x42 || profileIDC == 0x4d || profileIDC == 0x58
+
+	var level H264Level
+	switch {
+	case levelIDC == byte(H264Level1_1) && baselineMainExtended && profileIOP&h264ConstraintSet3Flag != 0:
+		level = H264Level1b
+	case levelIDC == byte(H264Level1b):
+		level = H264Level1b
+	case isH264Level(H264Level(levelIDC)):
+		level = H264Level(levelIDC)
+	default:
+		return nil, fmt.Errorf("unknown h264 level: %v", value)
+	}
+
+	for _, pattern := range h264ProfilePatterns {
+		if pattern.profileIDC == profileIDC && profileIOP&pattern.mask == pattern.value {
+			return &H264ProfileLevelID{Profile: pattern.profile, Level: level}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown h264 profile: %v", value)
+}
+
+func isH264Level(level H264Level) bool {
+	switch level {
+	case H264Level1, H264Level1_1, H264Level1_2, H264Level1_3, H264Level2, H264Level2_1, H264Level2_2,
+		H264Level3, H264Level3_1, H264Level3_2, H264Level4, H264Level4_1, H264Level4_2,
+		H264Level5, H264Level5_1, H264Level5_2:
+		return true
+	}
+	return false
+}
+
+// String returns the hexadecimal profile-level-id value.
+func (p *H264ProfileLevelID) String() string {
+	if p.Level == H264Level1b {
+		switch p.Profile {
+		case H264ProfileConstrainedBaseline:
+			return "42f00b"
+		case H264ProfileBaseline:
+			return "42100b"
+		case H264ProfileMain:
+			return "4d100b"
+		}
+	}
+	return fmt.Sprintf("%v%02x", p.prefix(), int(p.Level))
+}
+
+func (p *H264ProfileLevelID) prefix() string {
+	switch p.Profile {
+	case H264ProfileConstrainedBaseline:
+		return "42e0"
+	case H264ProfileBaseline:
+		return "4200"
+	case H264ProfileMain:
+		return "4d00"
+	case H264ProfileConstrainedHigh:
+		return "640c"
+	case H264ProfileHigh:
+		return "6400"
+	case H264ProfilePredictiveHigh444:
+		return "f400"
+	}
+	return "4200"
+}
+
+// LessH264Level reports whether level a is lower than level b.
+func LessH264Level(a, b H264Level) bool {
+	if a == H264Level1b {
+		return b != H264Level1 && b != H264Level1b
+	}
+	if b == H264Level1b {
+		return a == H264Level1
+	}
+	return a < b
+}
+
+func minH264Level(a, b H264Level) H264Level {
+	if LessH264Level(a, b) {
+		return a
+	}
+	return b
+}
+
+// H264Params is the typed view of the H.264 format parameters (RFC 6184).
+type H264Params struct {
+	ProfileLevelID        H264ProfileLevelID
+	PacketizationMode     int
+	LevelAsymmetryAllowed bool
+	SpropParameterSets    [][]byte
+	MaxMBPS               int
+	MaxFS                 int
+	MaxBR                 int
+}
+
+// ParseH264Params parses the parameters of an H.264 fmtp attribute.
+// Absent parameters take the defaults defined by RFC 6184.
+func ParseH264Params(fmtp string) (*H264Params, error) {
+	var params H264Params
+	var err error
+
+	values := ParseFmtp(fmtp)
+
+	profileLevelID, ok := values[h264ProfileLevelIDParam]
+	if !ok {
+		profileLevelID = h264DefaultProfileLevelID
+	}
+	id, err := ParseH264ProfileLevelID(profileLevelID)
+	if err != nil {
+		return nil, err
+	}
+	params.ProfileLevelID = *id
+
+	if value, ok := values[h264PacketizationModeParam]; ok {
+		params.PacketizationMode, err = strconv.Atoi(value)
+		if err != nil || params.PacketizationMode < 0 || params.PacketizationMode > 2 {
+			return nil, fmt.Errorf("wrong packetization-mode: %v", value)
+		}
+	}
+
+	if value, ok := values[h264LevelAsymmetryParam]; ok {
+		if params.LevelAsymmetryAllowed, err = parseFmtpBool(h264LevelAsymmetryParam, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if value, ok := values[h264SpropParameterSetsParam]; ok && value != "" {
+		for _, set := range strings.Split(value, ",") {
+			decoded, decErr := base64.StdEncoding.DecodeString(set)
+			if decErr != nil {
+				return nil, fmt.Errorf("wrong sprop-parameter-sets: %v", decErr)
+			}
+			params.SpropParameterSets = append(params.SpropParameterSets, decoded)
+		}
+	}
+
+	if err = parseFmtpInts(values, []fmtpInt{
+		{h264MaxMBPSParam, &params.MaxMBPS},
+		{h264MaxFSParam, &params.MaxFS},
+		{h264MaxBRParam, &params.MaxBR},
+	}); err != nil {
+		return nil, err
+	}
+
+	return &params, nil
+}
+
+// String returns the parameters in fmtp form.
+func (p *H264Params) String() string {
+	var params []string
+
+	if p.LevelAsymmetryAllowed {
+		params = append(params, h264LevelAsymmetryParam+"=1")
+	}
+	params = append(params, formatFmtpInt(h264PacketizationModeParam, p.PacketizationMode))
+	params = append(params, h264ProfileLevelIDParam+"="+p.ProfileLevelID.String())
+
+	if len(p.SpropParameterSets) > 0 {
+		sets := make([]string, 0, len(p.SpropParameterSets))
+		for _, set := range p.SpropParameterSets {
+			sets = append(sets, base64.StdEncoding.EncodeToString(set))
+		}
+		params = append(params, h264SpropParameterSetsParam+"="+strings.Join(sets, ","))
+	}
+	if p.MaxMBPS > 0 {
+		params = append(params, formatFmtpInt(h264MaxMBPSParam, p.MaxMBPS))
+	}
+	if p.MaxFS > 0 {
+		params = append(params, formatFmtpInt(h264MaxFSParam, p.MaxFS))
+	}
+	if p.MaxBR > 0 {
+		params = append(params, formatFmtpInt(h264MaxBRParam, p.MaxBR))
+	}
+
+	return strings.Join(params, ";")
+}
+
+// H264Compatible reports whether two parameter sets describe the same
+// payload format, i.e. they have the same profile and packetization mode.
+func H264Compatible(a, b *H264Params) bool {
+	return a.ProfileLevelID.Profile == b.ProfileLevelID.Profile && a.PacketizationMode == b.PacketizationMode
+}
+
+// NegotiateH264 builds the answer parameters for an offered H.264 payload
+// format given the local capabilities. Unless both sides allow level
+// asymmetry, the answered level is downgraded to the lower of both levels.
+func NegotiateH264(offer, local *H264Params) (*H264Params, error) {
+	if !H264Compatible(offer, local) {
+		return nil, fmt.Errorf("incompatible h264 parameters: %v and %v", offer, local)
+	}
+
+	answer := *local
+	answer.ProfileLevelID.Profile = offer.ProfileLevelID.Profile
+	answer.PacketizationMode = offer.PacketizationMode
+	answer.SpropParameterSets = nil
+
+	if !(offer.LevelAsymmetryAllowed && local.LevelAsymmetryAllowed) {
+		answer.ProfileLevelID.Level = minH264Level(offer.ProfileLevelID.Level, local.ProfileLevelID.Level)
+	}
+
+	return &answer, nil
+}
@@ -0,0 +1,201 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseH264ProfileLevelID(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Expected *H264ProfileLevelID
+	}{
+		{"42e01f", &H264ProfileLevelID{H264ProfileConstrainedBaseline, H264Level3_1}},
+		{"42c01f", &H264ProfileLevelID{H264ProfileConstrainedBaseline, H264Level3_1}},
+		{"42001f", &H264ProfileLevelID{H264ProfileBaseline, H264Level3_1}},
+		{"4d0032", &H264ProfileLevelID{H264ProfileMain, H264Level5}},
+		{"640c34", &H264ProfileLevelID{H264ProfileConstrainedHigh, H264Level5_2}},
+		{"64002a", &H264ProfileLevelID{H264ProfileHigh, H264Level4_2}},
+		{"42f00b", &H264ProfileLevelID{H264ProfileConstrainedBaseline, H264Level1b}},
+		{"4d100b", &H264ProfileLevelID{H264ProfileMain, H264Level1b}},
+		{"640c0b", &H264ProfileLevelID{H264ProfileConstrainedHigh, H264Level1_1}},
+		{"f4001f", &H264ProfileLevelID{H264ProfilePredictiveHigh444, H264Level3_1}},
+	}
+
+	for _, v := range tests {
+		id, err := ParseH264ProfileLevelID(v.Value)
+		if err != nil {
+			t.Fatalf("%v: %v", v.Value, err)
+		}
+		if !cmp.Equal(id, v.Expected) {
+			t.Fatalf("%v: %v", v.Value, cmp.Diff(id, v.Expected))
+		}
+		again, err := ParseH264ProfileLevelID(id.String())
+		if err != nil || !cmp.Equal(again, id) {
+			t.Fatalf("%v: round trip through %v failed", v.Value, id.String())
+		}
+	}
+
+	for _, value := range []string{"", "42e0", "zzzzzz", "6e001f", "4200ff"} {
+		if _, err := ParseH264ProfileLevelID(value); err == nil {
+			t.Fatalf("%v: error was expected", value)
+		}
+	}
+}
+
+func TestParseH264Params(t *testing.T) {
+	params, err := ParseH264Params("profile-level-id=42e01f;level-asymmetry-allowed=1;packetization-mode=1;" +
+		"sprop-parameter-sets=Z0IACpZTBYmI,aMljiA==;max-mbps=108000;max-fs=3600")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := &H264Params{
+		ProfileLevelID:        H264ProfileLevelID{H264ProfileConstrainedBaseline, H264Level3_1},
+		PacketizationMode:     1,
+		LevelAsymmetryAllowed: true,
+		SpropParameterSets: [][]byte{
+			{0x67, 0x42, 0x00, 0x0a, 0x96, 0x53, 0x05, 0x89, 0x88},
+			{0x68, 0xc9, 0x63, 0x88},
+		},
+		MaxMBPS: 108000,
+		MaxFS:   3600,
+	}
+	if !cmp.Equal(params, expected) {
+		t.Fatal(cmp.Diff(params, expected))
+	}
+
+	defaults, err := ParseH264Params("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defaults.ProfileLevelID != (H264ProfileLevelID{H264ProfileBaseline, H264Level1}) || defaults.PacketizationMode != 0 {
+		t.Fatalf("wrong defaults: %v", defaults)
+	}
+
+	for _, v := range []struct {
+		fmtp string
+		err  string
+	}{
+		{"packetization-mode=3", "wrong packetization-mode: 3"},
+		{"level-asymmetry-allowed=2", "wrong level-asymmetry-allowed: 2"},
+		{"max-fs=-1", "wrong max-fs: -1"},
+		{"max-br=x;max-fs=x;max-mbps=x", "wrong max-mbps: x"},
+	} {
+		if _, err := ParseH264Params(v.fmtp); err == nil || err.Error() != v.err {
+			t.Fatalf("%v: wrong error: %v", v.fmtp, err)
+		}
+	}
+}
+
+func TestH264Level(t *testing.T) {
+	var zero H264Level
+	if zero == H264Level1b || isH264Level(zero) {
+		t.Fatal("zero level is a level")
+	}
+	levels := []H264Level{H264Level1, H264Level1b, H264Level1_1}
+	if !LessH264Level(levels[0], levels[1]) || !LessH264Level(levels[1], levels[2]) || LessH264Level(levels[1], levels[1]) {
+		t.Fatal("wrong order of level 1b")
+	}
+}
+
+func TestNegotiateH264(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Offer    string
+		Local    string
+		Expected string
+	}{
+		{
+			Name:     "Level downgrade",
+			Offer:    "packetization-mode=1;profile-level-id=42e01f",
+			Local:    "packetization-mode=1;profile-level-id=42e028",
+			Expected: "packetization-mode=1;profile-level-id=42e01f",
+		},
+		{
+			Name:     "Local level is lower",
+			Offer:    "packetization-mode=1;profile-level-id=42e028",
+			Local:    "packetization-mode=1;profile-level-id=42e00d",
+			Expected: "packetization-mode=1;profile-level-id=42e00d",
+		},
+		{
+			Name:     "Level asymmetry",
+			Offer:    "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f",
+			Local:    "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e034",
+			Expected: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e034",
+		},
+		{
+			Name:     "Level 1b",
+			Offer:    "profile-level-id=42f00b",
+			Local:    "profile-level-id=42e01f",
+			Expected: "packetization-mode=0;profile-level-id=42f00b",
+		},
+	}
+
+	for _, v := range tests {
+		offer, err := ParseH264Params(v.Offer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		local, err := ParseH264Params(v.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		answer, err := NegotiateH264(offer, local)
+		if err != nil {
+			t.Fatalf("%v: %v", v.Name, err)
+		}
+		if answer.String() != v.Expected {
+			t.Fatalf("%v: got %v, expected %v", v.Name, answer.String(), v.Expected)
+		}
+	}
+}
+
+func TestMatchCodecs(t *testing.T) {
+	offer, err := NewDecoder(strings.NewReader(`v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96 97 98 99 32
+a=rtpmap:96 H264/90000
+a=rtcp-fb:96 nack
+a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42e01f
+a=rtpmap:97 H264/90000
+a=rtcp-fb:97 nack
+a=fmtp:97 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
+a=rtpmap:98 H264/90000
+a=rtcp-fb:98 nack
+a=fmtp:98 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f
+a=rtpmap:99 VP8/90000
+a=rtcp-fb:* goog-remb
+`)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offered, err := offer.MediaDescs[0].Codecs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offered[0].Name != H264Codec || offered[3].Name != "VP8" || !cmp.Equal(offered[3].RTCPFeedback, []string{"goog-remb"}) ||
+		offered[4].Name != "MPV" || offered[4].ClockRate != 90000 {
+		t.Fatalf("wrong codecs: %v", dump(offered))
+	}
+
+	local := []*Codec{
+		{PayloadType: 102, Name: H264Codec, ClockRate: 90000, Fmtp: "packetization-mode=1;profile-level-id=42e034",
+			RTCPFeedback: []string{"nack", "goog-remb"}},
+	}
+
+	answer := MatchCodecs(offered, local)
+	expected := []*Codec{
+		{PayloadType: 98, Name: H264Codec, ClockRate: 90000, Fmtp: "packetization-mode=1;profile-level-id=42e01f",
+			RTCPFeedback: []string{"nack", "goog-remb"}},
+	}
+	if !cmp.Equal(answer, expected) {
+		t.Fatal(cmp.Diff(answer, expected))
+	}
+}
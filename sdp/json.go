@@ -0,0 +1,195 @@
+package sdp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The JSON form of a session mirrors its fields one to one, so it can be
+// encoded back to the same SDP text. Media descriptions additionally carry
+// read-only fields derived from their attributes (mid, msid, direction and
+// codecs), which are ignored when decoding: attributes, including unknown
+// ones, stay the source of truth.
+
+type attributeJSON struct {
+	Name  string  `json:"name"`
+	Value *string `json:"value,omitempty"`
+}
+
+// MarshalJSON omits the value of property attributes.
+func (a *Attribute) MarshalJSON() ([]byte, error) {
+	res := attributeJSON{Name: a.Name}
+	if !a.IsProperty() {
+		res.Value = &a.Value
+	}
+	return json.Marshal(res)
+}
+
+func (a *Attribute) UnmarshalJSON(data []byte) error {
+	var res attributeJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	if res.Name == "" {
+		return fmt.Errorf("attribute name is required")
+	}
+	*a = *NewAttribute(res.Name, "")
+	if res.Value != nil {
+		a.Value = *res.Value
+	}
+	return nil
+}
+
+type encryptionKeyJSON struct {
+	Method string  `json:"method"`
+	Value  *string `json:"value,omitempty"`
+}
+
+// MarshalJSON omits the value of keys without one, e.g. k=prompt.
+func (k *EncryptionKey) MarshalJSON() ([]byte, error) {
+	res := encryptionKeyJSON{Method: k.Method}
+	if k.Value != propertyValue {
+		res.Value = &k.Value
+	}
+	return json.Marshal(res)
+}
+
+func (k *EncryptionKey) UnmarshalJSON(data []byte) error {
+	var res encryptionKeyJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	k.Method, k.Value = res.Method, propertyValue
+	if res.Value != nil {
+		k.Value = *res.Value
+	}
+	return nil
+}
+
+type extensionLineJSON struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	After string `json:"after,omitempty"`
+}
+
+// MarshalJSON writes the line types as one-letter strings.
+func (l *ExtensionLine) MarshalJSON() ([]byte, error) {
+	res := extensionLineJSON{Type: string(l.Type), Value: l.Value}
+	if l.After != 0 {
+		res.After = string(l.After)
+	}
+	return json.Marshal(res)
+}
+
+func (l *ExtensionLine) UnmarshalJSON(data []byte) error {
+	var res extensionLineJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	if len(res.Type) != 1 || len(res.After) > 1 {
+		return fmt.Errorf("wrong extension line type")
+	}
+	*l = ExtensionLine{Type: res.Type[0], Value: res.Value}
+	if res.After != "" {
+		l.After = res.After[0]
+	}
+	return nil
+}
+
+type mediaDescFields MediaDesc
+
+type mediaDescJSON struct {
+	*mediaDescFields
+	Mid       string   `json:"mid,omitempty" jsonschema:"readonly"`
+	Msid      *Msid    `json:"msid,omitempty" jsonschema:"readonly"`
+	Direction string   `json:"direction,omitempty" jsonschema:"readonly"`
+	Codecs    []*Codec `json:"codecs,omitempty" jsonschema:"readonly"`
+}
+
+func (m *MediaDesc) MarshalJSON() ([]byte, error) {
+	res := mediaDescJSON{
+		mediaDescFields: (*mediaDescFields)(m),
+		Mid:             m.Mid(),
+		Msid:            m.Msid(),
+		Direction:       findDirection(m.Attributes),
+	}
+	if inSet(RTPproto, m.Proto) {
+		if codecs, err := m.Codecs(); err == nil {
+			res.Codecs = codecs
+		}
+	}
+	return json.Marshal(res)
+}
+
+func (m *MediaDesc) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*mediaDescFields)(m))
+}
+
+// Types with a text form keep their structured JSON form rather than being
+// encoded as strings.
+
+type (
+	sessionFields    Session
+	originFields     Origin
+	connectionFields Connection
+	bandwidthFields  Bandwidth
+	timingFields     Timing
+	repeatTimeFields RepeatTime
+	timeZoneFields   TimeZone
+)
+
+func (s *Session) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*sessionFields)(s))
+}
+
+func (s *Session) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*sessionFields)(s))
+}
+
+func (o *Origin) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*originFields)(o))
+}
+
+func (o *Origin) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*originFields)(o))
+}
+
+func (c *Connection) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*connectionFields)(c))
+}
+
+func (c *Connection) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*connectionFields)(c))
+}
+
+func (b *Bandwidth) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*bandwidthFields)(b))
+}
+
+func (b *Bandwidth) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*bandwidthFields)(b))
+}
+
+func (t *Timing) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*timingFields)(t))
+}
+
+func (t *Timing) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*timingFields)(t))
+}
+
+func (r *RepeatTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*repeatTimeFields)(r))
+}
+
+func (r *RepeatTime) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*repeatTimeFields)(r))
+}
+
+func (z *TimeZone) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*timeZoneFields)(z))
+}
+
+func (z *TimeZone) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*timeZoneFields)(z))
+}
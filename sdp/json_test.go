@@ -0,0 +1,83 @@
+package sdp
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, v := range append(unmarshalTests, &testVector{Name: "JSEP offer", Data: jsepOffer}) {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			sess, err := NewDecoder(strings.NewReader(v.Data)).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := json.Marshal(sess)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var res Session
+			if err := json.Unmarshal(data, &res); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(&res, sess) {
+				t.Fatal(cmp.Diff(&res, sess))
+			}
+		})
+	}
+}
+
+func TestJSONForm(t *testing.T) {
+	sess, err := NewDecoder(strings.NewReader(`v=0
+o=- 4611731400430051336 2 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+k=prompt
+m=audio 9 RTP/AVP 0
+a=mid:a
+a=sendonly
+a=x-unknown:value
+`)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"version":0,"origin":{"username":"-","sessionId":"4611731400430051336","sessionVersion":"2",` +
+		`"netType":"IN","addrType":"IP4","address":"127.0.0.1"},"sessionName":"-",` +
+		`"connection":{"netType":"IN","addrType":"IP4","address":"127.0.0.1","addressCount":1},` +
+		`"timings":[{"start":0,"stop":0}],"encryptionKeys":[{"method":"prompt"}],` +
+		`"media":[{"media":"audio","port":9,"portCount":1,"proto":["RTP","AVP"],"formats":["0"],` +
+		`"attributes":[{"name":"mid","value":"a"},{"name":"sendonly"},{"name":"x-unknown","value":"value"}],` +
+		`"mid":"a","direction":"sendonly","codecs":[{"payloadType":0,"name":"PCMU","clockRate":8000,"channels":1}]}]}`
+	if string(data) != expected {
+		t.Fatal(cmp.Diff(string(data), expected))
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile("session.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(schema) != string(expected) {
+		t.Fatal("session.schema.json is outdated, run go generate")
+	}
+}
@@ -1,24 +1,66 @@
 package sdp
 
 import (
+	"errors"
 	"io"
 	"strconv"
 )
 
+// Encoder writes session descriptions to an io.Writer. The text is built in
+// a buffer that is reused between calls and written out in a single Write.
 type Encoder struct {
-	w io.Writer
+	w   io.Writer
+	buf []byte
+
+	compactTimes bool
+
+	// A recording Encoder marks where the line of each element starts.
+	recording bool
+	marks     []lineMark
 }
 
 func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{w: w}
 }
 
-func (e *Encoder) Encode(s *Session) {
+// SetCompactTimes makes the Encoder write the times of r= lines and the
+// offsets of z= lines in the shortest shorthand form, e.g. "r=7d 1h 0 25h"
+// rather than "r=604800 3600 0 90000".
+func (e *Encoder) SetCompactTimes(compact bool) {
+	e.compactTimes = compact
+}
+
+func (e *Encoder) Encode(s *Session) error {
+	if s.Originator == nil {
+		return errNoOriginator
+	}
+	e.buf = e.buf[:0]
+	e.encodeSession(s)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+var errNoOriginator = errors.New("sdp: session has no originator")
+
+// Marshal returns the text of the session description, which must have an
+// originator.
+func Marshal(s *Session) ([]byte, error) {
+	if s.Originator == nil {
+		return nil, errNoOriginator
+	}
+	return AppendSDP(nil, s), nil
+}
+
+// AppendSDP appends the text of the session description to dst and returns
+// the extended buffer. The o= line is left out if there is no originator.
+func AppendSDP(dst []byte, s *Session) []byte {
+	e := Encoder{buf: dst}
 	e.encodeSession(s)
+	return e.buf
 }
 
 func (e *Encoder) writeInt64(v int64) *Encoder {
-	e.w.Write([]byte(strconv.FormatInt(v, 10)))
+	e.buf = strconv.AppendInt(e.buf, v, 10)
 	return e
 }
 
@@ -27,23 +69,21 @@ func (e *Encoder) writeInt(v int) *Encoder {
 }
 
 func (e *Encoder) writeString(v string) *Encoder {
-	e.w.Write([]byte(v))
+	e.buf = append(e.buf, v...)
 	return e
 }
 
 func (e *Encoder) writeChar(char byte) *Encoder {
-	e.w.Write([]byte{char})
+	e.buf = append(e.buf, char)
 	return e
 }
 
 func (e *Encoder) writeNewline() *Encoder {
-	e.w.Write([]byte{'\n'})
-	return e
+	return e.writeChar('\n')
 }
 
 func (e *Encoder) writeSpace() *Encoder {
-	e.w.Write([]byte{' '})
-	return e
+	return e.writeChar(' ')
 }
 
 func (e *Encoder) writeField(field byte) *Encoder {
@@ -51,40 +91,64 @@ func (e *Encoder) writeField(field byte) *Encoder {
 }
 
 func (e *Encoder) encodeSession(s *Session) {
+	if s.Raw != nil && !e.recording {
+		e.encodeRawLines(s)
+		return
+	}
+
+	e.markField(s, VersionField, 0)
 	e.encodeVersion(s.Version)
+	e.encodeExtensionLines(s.ExtensionLines, VersionField)
 	e.encodeOriginator(s.Originator)
+	e.encodeExtensionLines(s.ExtensionLines, OriginField)
+	e.markField(s, SessionNameField, 0)
 	e.encodeSessionName(s.SessionName)
+	e.encodeExtensionLines(s.ExtensionLines, SessionNameField)
 
 	if s.Information != "" {
+		e.markField(s, SessionInfoField, 0)
 		e.encodeInformation(s.Information)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, SessionInfoField)
 	if s.URI != "" {
+		e.markField(s, URIField, 0)
 		e.encodeURI(s.URI)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, URIField)
 	if s.Emails != nil {
-		e.encodeEmails(s.Emails)
+		e.encodeEmails(s)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, EmailField)
 	if s.PhoneNumbers != nil {
-		e.encodePhoneNumbers(s.PhoneNumbers)
+		e.encodePhoneNumbers(s)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, PhoneNumberField)
 	if s.ConnectionData != nil {
 		e.encodeConnection(s.ConnectionData)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, ConnectionDataField)
 	if s.Bandwidths != nil {
 		e.encodeBandwidths(s.Bandwidths)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, BandwidthField)
 	if s.Timings != nil {
 		e.encodeTimings(s.Timings)
 	}
-	if s.TimeZones != nil {
+	e.encodeExtensionLines(s.ExtensionLines, TimingField)
+	if len(s.TimeZones) > 0 {
+		e.markField(s, TimeZoneField, 0)
 		e.encodeTimeZones(s.TimeZones)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, TimeZoneField)
 	if s.EncryptionKeys != nil {
 		e.encodeEncryptionKeys(s.EncryptionKeys)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, EncryptionKeyField)
 	if s.Attributes != nil {
 		e.encodeAttributes(s.Attributes)
 	}
+	e.encodeExtensionLines(s.ExtensionLines, AttributeField)
+	e.encodeExtensionLines(s.ExtensionLines, 0)
 	if s.MediaDescs != nil {
 		e.encodeMediaDescs(s.MediaDescs)
 	}
@@ -95,10 +159,18 @@ func (e *Encoder) encodeVersion(version int) {
 }
 
 func (e *Encoder) encodeOriginator(originator *Origin) {
-	e.writeField(OriginField).writeString(originator.Username).writeSpace()
+	if originator == nil {
+		return
+	}
+	e.markElement(originator)
+	e.writeField(OriginField).writeOriginator(originator).writeNewline()
+}
+
+func (e *Encoder) writeOriginator(originator *Origin) *Encoder {
+	e.writeString(originator.Username).writeSpace()
 	e.writeInt64(originator.SessID).writeSpace().writeInt64(originator.SessVersion).writeSpace()
 	e.writeString(originator.Nettype).writeSpace().writeString(originator.Addrtype).writeSpace()
-	e.writeString(originator.UnicastAddress).writeNewline()
+	return e.writeString(originator.UnicastAddress)
 }
 
 func (e *Encoder) encodeSessionName(name string) {
@@ -117,8 +189,9 @@ func (e *Encoder) encodeEmail(email string) {
 	e.writeField(EmailField).writeString(email).writeNewline()
 }
 
-func (e *Encoder) encodeEmails(emails []string) {
-	for _, email := range emails {
+func (e *Encoder) encodeEmails(s *Session) {
+	for i, email := range s.Emails {
+		e.markField(s, EmailField, i)
 		e.encodeEmail(email)
 	}
 }
@@ -127,19 +200,37 @@ func (e *Encoder) encodePhoneNumber(phone string) {
 	e.writeField(PhoneNumberField).writeString(phone).writeNewline()
 }
 
-func (e *Encoder) encodePhoneNumbers(phones []string) {
-	for _, phone := range phones {
+func (e *Encoder) encodePhoneNumbers(s *Session) {
+	for i, phone := range s.PhoneNumbers {
+		e.markField(s, PhoneNumberField, i)
 		e.encodePhoneNumber(phone)
 	}
 }
 
 func (e *Encoder) encodeConnection(connection *Connection) {
-	e.writeField(ConnectionDataField).writeString(connection.Nettype).writeSpace().writeString(connection.Addrtype).writeSpace()
+	e.markElement(connection)
+	e.writeField(ConnectionDataField).writeConnection(connection).writeNewline()
+}
+
+func (e *Encoder) writeConnection(connection *Connection) *Encoder {
+	e.writeString(connection.Nettype).writeSpace().writeString(connection.Addrtype).writeSpace()
 	e.writeString(connection.ConnectionAddr)
-	if connection.TTL > 0 {
-		e.writeChar('/').writeInt64(connection.TTL)
+	// A lone IPv4 suffix is the TTL, so without a TTL the number of addresses
+	// is only written, after a zero TTL, if there are several.
+	switch connection.Addrtype {
+	case TypeIPv4:
+		if connection.TTL > 0 || connection.AddressesNum > 1 {
+			e.writeChar('/').writeInt64(connection.TTL)
+			if connection.AddressesNum > 0 {
+				e.writeChar('/').writeInt64(connection.AddressesNum)
+			}
+		}
+	case TypeIPv6:
+		if connection.AddressesNum > 0 {
+			e.writeChar('/').writeInt64(connection.AddressesNum)
+		}
 	}
-	e.writeChar('/').writeInt64(connection.AddressesNum).writeNewline()
+	return e
 }
 
 func (e *Encoder) encodeConnections(connections []*Connection) {
@@ -149,7 +240,12 @@ func (e *Encoder) encodeConnections(connections []*Connection) {
 }
 
 func (e *Encoder) encodeBandwidth(bandwidth *Bandwidth) {
-	e.writeField(BandwidthField).writeString(bandwidth.Type).writeChar(':').writeInt(bandwidth.Value).writeNewline()
+	e.markElement(bandwidth)
+	e.writeField(BandwidthField).writeBandwidth(bandwidth).writeNewline()
+}
+
+func (e *Encoder) writeBandwidth(bandwidth *Bandwidth) *Encoder {
+	return e.writeString(bandwidth.Type).writeChar(':').writeInt(bandwidth.Value)
 }
 
 func (e *Encoder) encodeBandwidths(bandwidths []*Bandwidth) {
@@ -159,18 +255,24 @@ func (e *Encoder) encodeBandwidths(bandwidths []*Bandwidth) {
 }
 
 func (e *Encoder) encodeRepeatTime(time *RepeatTime) {
-	e.writeField(RepeatTimeField).writeInt64(time.Interval).writeSpace().writeInt64(time.Duration)
+	e.markElement(time)
+	e.writeField(RepeatTimeField).writeRepeatTime(time).writeNewline()
+}
+
+func (e *Encoder) writeRepeatTime(time *RepeatTime) *Encoder {
+	e.writeTypedTime(time.Interval).writeSpace().writeTypedTime(time.Duration)
 
 	if len(time.Offsets) > 0 {
 		e.writeSpace()
 	}
 	for i, offset := range time.Offsets {
-		e.writeInt64(offset)
+		e.writeTypedTime(offset)
 
 		if i+1 != len(time.Offsets) {
 			e.writeSpace()
 		}
 	}
+	return e
 }
 
 func (e *Encoder) encodeRepeatTimes(times []*RepeatTime) {
@@ -180,13 +282,13 @@ func (e *Encoder) encodeRepeatTimes(times []*RepeatTime) {
 }
 
 func (e *Encoder) encodeTiming(timing *Timing) {
-	e.writeField(TimingField).writeInt64(timing.Start).writeSpace().writeInt64(timing.Stop)
+	e.markElement(timing)
+	e.writeField(TimingField).writeTiming(timing).writeNewline()
+	e.encodeRepeatTimes(timing.RepeatTimes)
+}
 
-	if timing.RepeatTimes != nil {
-		e.writeNewline()
-		e.encodeRepeatTimes(timing.RepeatTimes)
-	}
-	e.writeNewline()
+func (e *Encoder) writeTiming(timing *Timing) *Encoder {
+	return e.writeInt64(timing.Start).writeSpace().writeInt64(timing.Stop)
 }
 
 func (e *Encoder) encodeTimings(timings []*Timing) {
@@ -199,7 +301,7 @@ func (e *Encoder) encodeTimeZones(zones []*TimeZone) {
 	e.writeField(TimeZoneField)
 
 	for i, zone := range zones {
-		e.writeInt64(zone.Time).writeSpace().writeInt64(zone.Offset)
+		e.writeTimeZone(zone)
 		if i+1 != len(zones) {
 			e.writeSpace()
 		}
@@ -208,13 +310,36 @@ func (e *Encoder) encodeTimeZones(zones []*TimeZone) {
 	e.writeNewline()
 }
 
+func (e *Encoder) writeTimeZone(zone *TimeZone) *Encoder {
+	return e.writeInt64(zone.Time).writeSpace().writeTypedTime(zone.Offset)
+}
+
+var compactShorthands = []byte{DayShorthand, HourShorthand, MinuteShorthand}
+
+// writeTypedTime writes a time that may be given in shorthand form.
+func (e *Encoder) writeTypedTime(seconds int64) *Encoder {
+	if e.compactTimes && seconds != 0 {
+		for _, shorthand := range compactShorthands {
+			unit := timeShorthandToSeconds(shorthand)
+			if seconds%unit == 0 {
+				return e.writeInt64(seconds / unit).writeChar(shorthand)
+			}
+		}
+	}
+	return e.writeInt64(seconds)
+}
+
 func (e *Encoder) encodeEncryptionKey(key *EncryptionKey) {
-	e.writeField(EncryptionKeyField).writeString(key.Method)
+	e.markElement(key)
+	e.writeField(EncryptionKeyField).writeEncryptionKey(key).writeNewline()
+}
 
-	if key.Value != " " {
+func (e *Encoder) writeEncryptionKey(key *EncryptionKey) *Encoder {
+	e.writeString(key.Method)
+	if key.Value != propertyValue {
 		e.writeChar(':').writeString(key.Value)
 	}
-	e.writeNewline()
+	return e
 }
 
 func (e *Encoder) encodeEncryptionKeys(encryptionKeys []*EncryptionKey) {
@@ -224,52 +349,77 @@ func (e *Encoder) encodeEncryptionKeys(encryptionKeys []*EncryptionKey) {
 }
 
 func (e *Encoder) encodeAttribute(attribute *Attribute) {
-	e.writeField(AttributeField).writeString(attribute.Name)
-	if attribute.Value != " " {
+	e.markElement(attribute)
+	e.writeField(AttributeField).writeAttribute(attribute).writeNewline()
+}
+
+func (e *Encoder) writeAttribute(attribute *Attribute) *Encoder {
+	e.writeString(attribute.Name)
+	if !attribute.IsProperty() {
 		e.writeChar(':').writeString(attribute.Value)
 	}
-	e.writeNewline()
+	return e
 }
 
-func (e *Encoder) encodeAttributes(attributes []*Attribute) {
+func (e *Encoder) encodeAttributes(attributes Attributes) {
 	for _, attribute := range attributes {
 		e.encodeAttribute(attribute)
 	}
 }
 
 func (e *Encoder) encodeMediaDesc(desc *MediaDesc) {
-	e.writeField(MediaDescField).writeString(desc.Media).writeSpace().writeInt64(desc.Port).writeChar('/').writeInt64(desc.PortsNum).writeSpace()
+	e.markElement(desc)
+	e.writeField(MediaDescField).writeString(desc.Media).writeSpace().writeInt64(desc.Port)
+	if desc.PortsNum > 0 {
+		e.writeChar('/').writeInt64(desc.PortsNum)
+	}
+	e.writeSpace()
 	for i, proto := range desc.Proto {
 		e.writeString(proto)
 		if i+1 != len(desc.Proto) {
 			e.writeChar('/')
 		}
 	}
-	e.writeSpace()
-	for i, fmt := range desc.Fmts {
-		e.writeString(fmt)
-		if i+1 != len(desc.Fmts) {
-			e.writeSpace()
-		}
+	for _, fmt := range desc.Fmts {
+		e.writeSpace().writeString(fmt)
 	}
 
 	e.writeNewline()
+	e.encodeExtensionLines(desc.ExtensionLines, MediaDescField)
 
 	if desc.Information != "" {
+		e.markField(desc, SessionInfoField, 0)
 		e.writeField(SessionInfoField).writeString(desc.Information).writeNewline()
 	}
+	e.encodeExtensionLines(desc.ExtensionLines, SessionInfoField)
 	if desc.Connections != nil {
 		e.encodeConnections(desc.Connections)
 	}
+	e.encodeExtensionLines(desc.ExtensionLines, ConnectionDataField)
 	if desc.Bandwidths != nil {
 		e.encodeBandwidths(desc.Bandwidths)
 	}
+	e.encodeExtensionLines(desc.ExtensionLines, BandwidthField)
 	if desc.EncryptionKeys != nil {
 		e.encodeEncryptionKeys(desc.EncryptionKeys)
 	}
+	e.encodeExtensionLines(desc.ExtensionLines, EncryptionKeyField)
 	if desc.Attributes != nil {
 		e.encodeAttributes(desc.Attributes)
 	}
+	e.encodeExtensionLines(desc.ExtensionLines, AttributeField)
+	e.encodeExtensionLines(desc.ExtensionLines, 0)
+}
+
+// encodeExtensionLines writes the extension lines that followed a line of
+// the given type.
+func (e *Encoder) encodeExtensionLines(lines []*ExtensionLine, after byte) {
+	for _, line := range lines {
+		if line.After == after {
+			e.markElement(line)
+			e.writeField(line.Type).writeString(line.Value).writeNewline()
+		}
+	}
 }
 
 func (e *Encoder) encodeMediaDescs(descs []*MediaDesc) {
@@ -2,6 +2,9 @@ package sdp
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"net"
 	"strings"
 	"testing"
 
@@ -134,7 +137,7 @@ a=fmtp:100 profile-level-id=42c01f;level-asymmetry-allowed=1
 		Data: `v=0
 o=alice 2890844526 2890844526 IN IP4 alice.example.org
 s=Example
-c=IN IP4 127.0.0.1/1
+c=IN IP4 127.0.0.1
 t=0 0
 a=sendrecv
 m=audio 10000/1 RTP/AVP 0 8
@@ -189,7 +192,7 @@ a=rtpmap:8 PCMA/8000
 		Data: `v=0
 o=- 0 2 IN IP4 127.0.0.1
 s=-
-c=IN IP4 127.0.0.1/1
+c=IN IP4 127.0.0.1
 t=0 0
 m=application 10000/1 DTLS/SCTP 5000
 a=sctpmap:5000 webrtc-datachannel 256
@@ -250,15 +253,77 @@ func TestMarshal(t *testing.T) {
 			buf := bytes.NewBufferString((res))
 
 			e := NewEncoder(buf)
-			e.Encode(v.Session)
+			if err := e.Encode(v.Session); err != nil {
+				t.Fatal(err)
+			}
 
 			if !cmp.Equal(buf.String(), v.Data) {
 				t.Fatalf("bad Session, got: %s, expected: %s, diff: %v", buf.String(), v.Data, cmp.Diff(buf.String(), v.Data))
 			}
+
+			data, err := Marshal(v.Session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != v.Data {
+				t.Fatal(cmp.Diff(string(data), v.Data))
+			}
+
+			if res := string(AppendSDP([]byte("prefix\n"), v.Session)); res != "prefix\n"+v.Data {
+				t.Fatal(cmp.Diff(res, "prefix\n"+v.Data))
+			}
 		})
 	}
 }
 
+func TestEncodeCompactTimes(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetCompactTimes(true)
+	if err := e.Encode(marshalTests[0].Session); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Replace(marshalTests[0].Data, "r=604800 3600 0 90000\nz=3034423619 -3600 3042462419 0",
+		"r=7d 1h 0 25h\nz=3034423619 -1h 3042462419 0", 1)
+	if buf.String() != expected {
+		t.Fatal(cmp.Diff(buf.String(), expected))
+	}
+
+	tests := []struct {
+		Seconds int64
+		Text    string
+	}{
+		{Seconds: 0, Text: "0"},
+		{Seconds: 90, Text: "90"},
+		{Seconds: 120, Text: "2m"},
+		{Seconds: 5400, Text: "90m"},
+		{Seconds: -7200, Text: "-2h"},
+		{Seconds: 172800, Text: "2d"},
+	}
+	for _, test := range tests {
+		e := Encoder{compactTimes: true}
+		if text := string(e.writeTypedTime(test.Seconds).buf); text != test.Text {
+			t.Errorf("%v seconds written as %v, expected %v", test.Seconds, text, test.Text)
+		}
+		if seconds, err := parseTime(test.Text); err != nil || seconds != test.Seconds {
+			t.Errorf("%v parsed as %v, %v", test.Text, seconds, err)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestEncodeWriteError(t *testing.T) {
+	if err := NewEncoder(failingWriter{}).Encode(marshalTests[0].Session); err == nil {
+		t.Fatal("error was expected")
+	}
+}
+
 func FuzzEncode(f *testing.F) {
 	f.Fuzz(func(t *testing.T, data string) {
 		sess, err := NewDecoder(strings.NewReader(data)).Decode()
@@ -275,3 +340,129 @@ func FuzzEncode(f *testing.F) {
 		}
 	})
 }
+
+func BenchmarkEncode(b *testing.B) {
+	for name, data := range readCorpus(b) {
+		var s Session
+		if err := Unmarshal(data, &s); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			e := NewEncoder(io.Discard)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := e.Encode(&s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for name, data := range readCorpus(b) {
+		var s Session
+		if err := Unmarshal(data, &s); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := Marshal(&s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAppendSDP(b *testing.B) {
+	for name, data := range readCorpus(b) {
+		var s Session
+		if err := Unmarshal(data, &s); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			var buf []byte
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				buf = AppendSDP(buf[:0], &s)
+			}
+		})
+	}
+}
+
+// countingWriter is an unbuffered writer, like a network connection, that
+// counts its Write calls.
+type countingWriter struct {
+	w      io.Writer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.w.Write(p)
+}
+
+// writeTokens writes the text of a session with one Write call per token
+// and separator, as the Encoder did before it buffered its output.
+func writeTokens(w io.Writer, text []byte) error {
+	for len(text) > 0 {
+		n := bytes.IndexAny(text, " =:/\n")
+		if n < 0 {
+			n = len(text)
+		} else if n == 0 {
+			n = 1
+		}
+		if _, err := w.Write(text[:n]); err != nil {
+			return err
+		}
+		text = text[n:]
+	}
+	return nil
+}
+
+// BenchmarkEncodeUnbuffered compares the Encoder with one Write call per
+// token on a writer with a cost per call: a synchronous in-memory network
+// connection.
+func BenchmarkEncodeUnbuffered(b *testing.B) {
+	for name, data := range readCorpus(b) {
+		var s Session
+		if err := Unmarshal(data, &s); err != nil {
+			b.Fatal(err)
+		}
+		for _, buffered := range []bool{true, false} {
+			mode := "per-token"
+			if buffered {
+				mode = "buffered"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				client, server := net.Pipe()
+				defer client.Close()
+				go io.Copy(io.Discard, server)
+				w := &countingWriter{w: client}
+				e := NewEncoder(w)
+				var buf []byte
+
+				b.ReportAllocs()
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					var err error
+					if buffered {
+						err = e.Encode(&s)
+					} else {
+						buf = AppendSDP(buf[:0], &s)
+						err = writeTokens(w, buf)
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+			})
+		}
+	}
+}
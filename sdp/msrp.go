@@ -0,0 +1,262 @@
+package sdp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Attributes of MSRP media descriptions (RFC 4975).
+const (
+	PathAttribute               = "path"
+	AcceptTypesAttribute        = "accept-types"
+	AcceptWrappedTypesAttribute = "accept-wrapped-types"
+	MaxSizeAttribute            = "max-size"
+)
+
+// MessageMedia is the media type of MSRP media descriptions, which have the
+// single format "*".
+const MessageMedia = "message"
+
+// Schemes of MSRP URIs.
+const (
+	MSRPScheme  = "msrp"
+	MSRPSScheme = "msrps"
+)
+
+// MSRPURI is an MSRP URI of an a=path attribute, e.g.
+// "msrps://bob@biloxi.example.com:12763/kjhd37s2s20w2a;tcp". Port is zero if
+// the URI has none.
+type MSRPURI struct {
+	Secure    bool
+	User      string
+	Host      string
+	Port      int
+	SessionID string
+	Transport string
+	// Params are the URI parameters after the transport as they are.
+	Params []string
+}
+
+// ParseMSRPURI parses an msrp:// or msrps:// URI.
+func ParseMSRPURI(value string) (*MSRPURI, error) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	res := &MSRPURI{Secure: strings.EqualFold(scheme, MSRPSScheme)}
+	if !ok || !res.Secure && !strings.EqualFold(scheme, MSRPScheme) {
+		return nil, fmt.Errorf("wrong msrp uri format: %v", value)
+	}
+
+	rest, params, ok := strings.Cut(rest, ";")
+	if !ok {
+		return nil, fmt.Errorf("wrong msrp uri format: no transport in %v", value)
+	}
+	fields := strings.Split(params, ";")
+	res.Transport = fields[0]
+	if res.Transport == "" {
+		return nil, fmt.Errorf("wrong msrp uri format: no transport in %v", value)
+	}
+	if len(fields) > 1 {
+		res.Params = fields[1:]
+	}
+
+	authority, sessionID, _ := strings.Cut(rest, "/")
+	res.SessionID = sessionID
+	if user, hostport, ok := strings.Cut(authority, "@"); ok {
+		res.User, authority = user, hostport
+	}
+	host, port, err := net.SplitHostPort(authority)
+	if err != nil {
+		// There is no port.
+		host = strings.TrimSuffix(strings.TrimPrefix(authority, "["), "]")
+	} else {
+		res.Port, err = strconv.Atoi(port)
+		if err != nil || res.Port < 0 || res.Port > maxPort {
+			return nil, fmt.Errorf("wrong msrp uri format: port %v", port)
+		}
+	}
+	if host == "" || strings.ContainsAny(host, "[]/") {
+		return nil, fmt.Errorf("wrong msrp uri format: host in %v", value)
+	}
+	res.Host = host
+	return res, nil
+}
+
+func (u *MSRPURI) String() string {
+	var b strings.Builder
+	if u.Secure {
+		b.WriteString(MSRPSScheme)
+	} else {
+		b.WriteString(MSRPScheme)
+	}
+	b.WriteString("://")
+	if u.User != "" {
+		b.WriteString(u.User + "@")
+	}
+	host := u.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	b.WriteString(host)
+	if u.Port != 0 {
+		b.WriteString(":" + strconv.Itoa(u.Port))
+	}
+	if u.SessionID != "" {
+		b.WriteString("/" + u.SessionID)
+	}
+	b.WriteString(";" + u.Transport)
+	for _, param := range u.Params {
+		b.WriteString(";" + param)
+	}
+	return b.String()
+}
+
+// MSRPParams is the typed view of the MSRP attributes of a media
+// description. MaxSize is zero if there is no a=max-size.
+type MSRPParams struct {
+	Path               []*MSRPURI
+	AcceptTypes        []string
+	AcceptWrappedTypes []string
+	MaxSize            int64
+	// Setup is the a=setup role of the connection (RFC 6135).
+	Setup string
+}
+
+// Secure reports whether the first URI of the path, the hop the peer
+// connects to, is an msrps:// one, which needs TCP/TLS/MSRP. With relays
+// (RFC 4976) the first URI is the relay and the last the endpoint.
+func (p *MSRPParams) Secure() bool {
+	return len(p.Path) > 0 && p.Path[0].Secure
+}
+
+// IsMSRP reports whether the media description is an MSRP session.
+func (m *MediaDesc) IsMSRP() bool {
+	return inSet(MSRPproto, m.Proto)
+}
+
+// MSRP returns the MSRP attributes of the media description. The a=path and
+// a=accept-types attributes RFC 4975 requires must be present, and the path
+// scheme must match the transport protocol.
+func (m *MediaDesc) MSRP() (*MSRPParams, error) {
+	var res MSRPParams
+
+	path, ok := m.Attributes.Get(PathAttribute)
+	if !ok {
+		return nil, fmt.Errorf("msrp media description without path")
+	}
+	for _, field := range strings.Fields(path) {
+		uri, err := ParseMSRPURI(field)
+		if err != nil {
+			return nil, err
+		}
+		res.Path = append(res.Path, uri)
+	}
+	if len(res.Path) == 0 {
+		return nil, fmt.Errorf("wrong path format: %v", path)
+	}
+	if res.Secure() != inSet(TLSproto, m.Proto) {
+		return nil, fmt.Errorf("msrp path %v does not match protocol %v",
+			res.Path[0], strings.Join(m.Proto, "/"))
+	}
+
+	acceptTypes, ok := m.Attributes.Get(AcceptTypesAttribute)
+	if !ok {
+		return nil, fmt.Errorf("msrp media description without accept-types")
+	}
+	res.AcceptTypes = strings.Fields(acceptTypes)
+	if value, ok := m.Attributes.Get(AcceptWrappedTypesAttribute); ok {
+		res.AcceptWrappedTypes = strings.Fields(value)
+	}
+
+	if value, ok := m.Attributes.Get(MaxSizeAttribute); ok {
+		maxSize, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || maxSize < 0 {
+			return nil, fmt.Errorf("wrong max-size format: %v", value)
+		}
+		res.MaxSize = maxSize
+	}
+	res.Setup, _ = m.Attributes.Get(SetupAttribute)
+
+	return &res, nil
+}
+
+// SetMSRP replaces the MSRP attributes of the media description.
+func (m *MediaDesc) SetMSRP(p *MSRPParams) {
+	m.Attributes.Remove(AcceptTypesAttribute, AcceptWrappedTypesAttribute, MaxSizeAttribute, PathAttribute,
+		SetupAttribute)
+	m.Attributes.Add(AcceptTypesAttribute, strings.Join(p.AcceptTypes, " "))
+	if len(p.AcceptWrappedTypes) > 0 {
+		m.Attributes.Add(AcceptWrappedTypesAttribute, strings.Join(p.AcceptWrappedTypes, " "))
+	}
+	if p.MaxSize > 0 {
+		m.Attributes.Add(MaxSizeAttribute, strconv.FormatInt(p.MaxSize, 10))
+	}
+	path := make([]string, 0, len(p.Path))
+	for _, uri := range p.Path {
+		path = append(path, uri.String())
+	}
+	m.Attributes.Add(PathAttribute, strings.Join(path, " "))
+	if p.Setup != "" {
+		m.Attributes.Add(SetupAttribute, p.Setup)
+	}
+}
+
+// NewMSRPMediaDesc returns an m=message <port> TCP/MSRP * media description,
+// with TCP/TLS/MSRP if the path is secure.
+func NewMSRPMediaDesc(port int64, p *MSRPParams) *MediaDesc {
+	proto := []string{TCPproto, MSRPproto}
+	if p.Secure() {
+		proto = []string{TCPproto, TLSproto, MSRPproto}
+	}
+	m := &MediaDesc{
+		Media:    MessageMedia,
+		Port:     port,
+		PortsNum: 1,
+		Proto:    proto,
+		Fmts:     []string{"*"},
+	}
+	m.SetMSRP(p)
+	return m
+}
+
+// MatchAcceptTypes returns the offered media types the local side accepts,
+// in the offered order. Either side may use wildcards, "*" or e.g.
+// "image/*"; a matched wildcard is answered with the more specific type.
+func MatchAcceptTypes(offered, local []string) []string {
+	var res []string
+	for _, offer := range offered {
+		for _, accept := range local {
+			if matchMediaType(accept, offer) {
+				res = appendType(res, offer)
+				break
+			}
+			if matchMediaType(offer, accept) {
+				res = appendType(res, accept)
+			}
+		}
+	}
+	return res
+}
+
+func appendType(types []string, value string) []string {
+	for _, existing := range types {
+		if strings.EqualFold(existing, value) {
+			return types
+		}
+	}
+	return append(types, value)
+}
+
+// matchMediaType reports whether the media type matches a pattern that may be
+// a wildcard.
+func matchMediaType(pattern, mediaType string) bool {
+	if pattern == "*" {
+		return true
+	}
+	patternType, patternSubtype, _ := strings.Cut(pattern, "/")
+	mainType, subtype, _ := strings.Cut(mediaType, "/")
+	if !strings.EqualFold(patternType, mainType) {
+		return false
+	}
+	return patternSubtype == "*" || strings.EqualFold(patternSubtype, subtype)
+}
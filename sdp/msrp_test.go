@@ -0,0 +1,154 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseMSRPURI(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected *MSRPURI
+	}{
+		{"msrp://atlanta.example.com:7654/jshA7weztas;tcp",
+			&MSRPURI{Host: "atlanta.example.com", Port: 7654, SessionID: "jshA7weztas", Transport: "tcp"}},
+		{"msrps://bob@[2001:db8::1]:12763/kjhd37s2s20w2a;tcp;ext=1",
+			&MSRPURI{Secure: true, User: "bob", Host: "2001:db8::1", Port: 12763, SessionID: "kjhd37s2s20w2a",
+				Transport: "tcp", Params: []string{"ext=1"}}},
+		{"msrp://relay.example.net;tcp", &MSRPURI{Host: "relay.example.net", Transport: "tcp"}},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			uri, err := ParseMSRPURI(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, uri); diff != "" {
+				t.Fatal(diff)
+			}
+			if uri.String() != test.value {
+				t.Fatalf("wrong uri: %v", uri)
+			}
+		})
+	}
+
+	for _, value := range []string{
+		"sip:alice@example.com",
+		"msrp://example.com:7654/abc",
+		"msrp://example.com:port/abc;tcp",
+		"msrp://:7654/abc;tcp",
+		"msrp://example.com/abc;",
+	} {
+		if _, err := ParseMSRPURI(value); err == nil {
+			t.Fatalf("%v was accepted", value)
+		}
+	}
+}
+
+const msrpOffer = "v=0\r\n" +
+	"o=alice 2890844526 2890844527 IN IP4 atlanta.example.com\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 atlanta.example.com\r\n" +
+	"t=0 0\r\n" +
+	"m=message 7654 TCP/TLS/MSRP *\r\n" +
+	"a=accept-types:message/cpim text/plain text/html\r\n" +
+	"a=accept-wrapped-types:text/plain image/*\r\n" +
+	"a=max-size:131072\r\n" +
+	"a=path:msrps://atlanta.example.com:7654/jshA7weztas;tcp\r\n" +
+	"a=setup:actpass\r\n"
+
+func TestMSRP(t *testing.T) {
+	s, err := NewDecoder(strings.NewReader(msrpOffer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := s.MediaDescs[0]
+	if !m.IsMSRP() {
+		t.Fatal("not msrp")
+	}
+	params, err := m.MSRP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &MSRPParams{
+		Path: []*MSRPURI{{Secure: true, Host: "atlanta.example.com", Port: 7654, SessionID: "jshA7weztas",
+			Transport: "tcp"}},
+		AcceptTypes:        []string{"message/cpim", "text/plain", "text/html"},
+		AcceptWrappedTypes: []string{"text/plain", "image/*"},
+		MaxSize:            131072,
+		Setup:              "actpass",
+	}
+	if diff := cmp.Diff(expected, params); diff != "" {
+		t.Fatal(diff)
+	}
+
+	s.MediaDescs[0] = NewMSRPMediaDesc(7654, params)
+	data, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := strings.NewReplacer("\r\n", "\n", "7654 ", "7654/1 ").Replace(msrpOffer); string(data) != expected {
+		t.Fatalf("wrong description:\n%s", data)
+	}
+
+	for _, change := range []func(m *MediaDesc){
+		func(m *MediaDesc) { m.Attributes.Remove(PathAttribute) },
+		func(m *MediaDesc) { m.Attributes.Remove(AcceptTypesAttribute) },
+		func(m *MediaDesc) { m.Proto = []string{TCPproto, MSRPproto} },
+		func(m *MediaDesc) { m.Attributes.Set(MaxSizeAttribute, "big") },
+		func(m *MediaDesc) { m.Attributes.Set(PathAttribute, "msrps://example.com/abc") },
+	} {
+		m := NewMSRPMediaDesc(7654, params)
+		change(m)
+		if _, err := m.MSRP(); err == nil {
+			t.Fatalf("wrong media description was accepted: %v", m.Attributes)
+		}
+	}
+}
+
+func TestMSRPRelay(t *testing.T) {
+	relay := &MSRPURI{Secure: true, Host: "relay.example.net", Port: 2855, SessionID: "dcb3de3", Transport: "tcp"}
+	alice := &MSRPURI{Host: "alice.example.com", Port: 7654, SessionID: "jshA7weztas", Transport: "tcp"}
+	m := NewMSRPMediaDesc(7654, &MSRPParams{Path: []*MSRPURI{relay, alice}, AcceptTypes: []string{"text/plain"}})
+	if expected := []string{TCPproto, TLSproto, MSRPproto}; !cmp.Equal(m.Proto, expected) {
+		t.Fatalf("wrong protocol: %v", m.Proto)
+	}
+	if value, _ := m.Attributes.Get(PathAttribute); value !=
+		"msrps://relay.example.net:2855/dcb3de3;tcp msrp://alice.example.com:7654/jshA7weztas;tcp" {
+		t.Fatalf("wrong path: %v", value)
+	}
+	params, err := m.MSRP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.Secure() || len(params.Path) != 2 {
+		t.Fatalf("wrong params: %+v", params)
+	}
+
+	m.Proto = []string{TCPproto, MSRPproto}
+	if _, err := m.MSRP(); err == nil {
+		t.Fatal("a path through a secure relay was accepted over TCP/MSRP")
+	}
+}
+
+func TestMatchAcceptTypes(t *testing.T) {
+	tests := []struct {
+		offered  []string
+		local    []string
+		expected []string
+	}{
+		{[]string{"message/cpim", "text/plain", "text/html"}, []string{"text/plain", "message/cpim"},
+			[]string{"message/cpim", "text/plain"}},
+		{[]string{"text/plain", "image/png"}, []string{"TEXT/*"}, []string{"text/plain"}},
+		{[]string{"*"}, []string{"text/plain", "message/cpim"}, []string{"text/plain", "message/cpim"}},
+		{[]string{"image/*"}, []string{"image/jpeg", "text/plain", "image/png"}, []string{"image/jpeg", "image/png"}},
+		{[]string{"text/plain"}, []string{"message/cpim"}, nil},
+	}
+	for _, test := range tests {
+		if diff := cmp.Diff(test.expected, MatchAcceptTypes(test.offered, test.local)); diff != "" {
+			t.Fatalf("%v and %v: %v", test.offered, test.local, diff)
+		}
+	}
+}
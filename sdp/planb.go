@@ -0,0 +1,291 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type planBTrack struct {
+	msid   *Msid
+	ssrcs  []uint32
+	groups []*SourceGroup
+}
+
+// ToUnifiedPlan converts a Plan B session, which carries every track of a
+// media type in a single media description, into a Unified Plan session
+// with one media description per track. The first track of a media
+// description keeps its mid, the following ones get "<mid>-<n>", unique in
+// the session, and are added to the BUNDLE group next to it. The tracks of a
+// media description without a mid get "<media>-<n>".
+func ToUnifiedPlan(s *Session) (*Session, error) {
+	res := s.Clone()
+	res.MediaDescs = nil
+	mids := make(map[string][]string)
+	taken := make(map[string]bool)
+	for _, desc := range s.MediaDescs {
+		taken[desc.Mid()] = true
+	}
+	newMid := func(base string) string {
+		for n := 1; ; n++ {
+			if mid := base + "-" + strconv.Itoa(n); !taken[mid] {
+				taken[mid] = true
+				return mid
+			}
+		}
+	}
+
+	for _, desc := range s.MediaDescs {
+		tracks, err := planBTracks(desc)
+		if err != nil {
+			return nil, err
+		}
+		if len(tracks) == 0 || desc.Msid() != nil {
+			res.MediaDescs = append(res.MediaDescs, desc.Clone())
+			continue
+		}
+
+		mid := desc.Mid()
+		sources, err := desc.SourceAttributes()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, track := range tracks {
+			trackMid := mid
+			if mid == "" {
+				trackMid = newMid(desc.Media)
+			} else if i > 0 {
+				trackMid = newMid(mid)
+			}
+			res.MediaDescs = append(res.MediaDescs, unifiedMediaDesc(desc, trackMid, track, sources))
+			if mid != "" {
+				mids[mid] = append(mids[mid], trackMid)
+			}
+		}
+	}
+
+	rewriteGroups(res, mids)
+	return res, nil
+}
+
+func planBTracks(desc *MediaDesc) ([]*planBTrack, error) {
+	sources, err := desc.SourceAttributes()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := desc.SourceGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	var order []uint32
+	parent := make(map[uint32]uint32)
+	var find func(ssrc uint32) uint32
+	find = func(ssrc uint32) uint32 {
+		if parent[ssrc] != ssrc {
+			parent[ssrc] = find(parent[ssrc])
+		}
+		return parent[ssrc]
+	}
+	add := func(ssrc uint32) {
+		if _, ok := parent[ssrc]; !ok {
+			parent[ssrc] = ssrc
+			order = append(order, ssrc)
+		}
+	}
+
+	msids := make(map[uint32]*Msid)
+	for _, source := range sources {
+		add(source.SSRC)
+		if source.Name == MsidAttribute {
+			msids[source.SSRC] = parseMsid(source.Value)
+		}
+	}
+	for _, group := range groups {
+		for _, ssrc := range group.SSRCs {
+			add(ssrc)
+			parent[find(ssrc)] = find(group.SSRCs[0])
+		}
+	}
+
+	for ssrc, msid := range msids {
+		if msid == nil || msid.Track == "" {
+			continue
+		}
+		for other, otherMsid := range msids {
+			if otherMsid != nil && otherMsid.Track == msid.Track {
+				parent[find(other)] = find(ssrc)
+			}
+		}
+	}
+
+	var tracks []*planBTrack
+	byRoot := make(map[uint32]*planBTrack)
+	for _, ssrc := range order {
+		root := find(ssrc)
+		track, ok := byRoot[root]
+		if !ok {
+			track = &planBTrack{}
+			byRoot[root] = track
+			tracks = append(tracks, track)
+		}
+		track.ssrcs = append(track.ssrcs, ssrc)
+		if track.msid == nil && msids[ssrc] != nil {
+			track.msid = msids[ssrc]
+		}
+	}
+	for _, group := range groups {
+		if len(group.SSRCs) > 0 {
+			track := byRoot[find(group.SSRCs[0])]
+			track.groups = append(track.groups, group)
+		}
+	}
+
+	return tracks, nil
+}
+
+func unifiedMediaDesc(desc *MediaDesc, mid string, track *planBTrack, sources []*SourceAttribute) *MediaDesc {
+	res := desc.Clone()
+	res.Attributes.Remove(SSRCAttribute, SSRCGroupAttribute, MsidAttribute)
+	res.Attributes.Set(MidAttribute, mid)
+
+	if track.msid != nil {
+		res.Attributes.Add(MsidAttribute, track.msid.String())
+	}
+	for _, group := range track.groups {
+		res.Attributes.Add(SSRCGroupAttribute, group.String())
+	}
+	for _, source := range sources {
+		for _, ssrc := range track.ssrcs {
+			if source.SSRC == ssrc {
+				res.Attributes.Add(SSRCAttribute, source.String())
+			}
+		}
+	}
+
+	return res
+}
+
+// ToPlanB converts a Unified Plan session into a Plan B session by merging
+// the audio and video media descriptions of each media type into the first
+// one of that type. Track identification moves from a=msid to a=ssrc msid
+// attributes, and the merged mids are removed from the BUNDLE group.
+func ToPlanB(s *Session) (*Session, error) {
+	res := s.Clone()
+	res.MediaDescs = nil
+	mids := make(map[string][]string)
+	merged := make(map[string]*MediaDesc)
+	directions := make(map[*MediaDesc][]string)
+
+	for _, desc := range s.MediaDescs {
+		if desc.Media != "audio" && desc.Media != "video" {
+			res.MediaDescs = append(res.MediaDescs, desc.Clone())
+			continue
+		}
+
+		sources, err := desc.SourceAttributes()
+		if err != nil {
+			return nil, err
+		}
+		if _, err = desc.SourceGroups(); err != nil {
+			return nil, err
+		}
+
+		target, ok := merged[desc.Media]
+		if !ok {
+			target = desc.Clone()
+			target.Attributes.Remove(SSRCAttribute, SSRCGroupAttribute, MsidAttribute)
+			merged[desc.Media] = target
+			res.MediaDescs = append(res.MediaDescs, target)
+		} else if target.Mid() == "" && desc.Mid() != "" {
+			return nil, fmt.Errorf("can not merge media %v into media without mid", desc.Mid())
+		}
+
+		if mid := desc.Mid(); mid != "" {
+			mids[mid] = []string{target.Mid()}
+		}
+		directions[target] = append(directions[target], s.Direction(desc))
+
+		for _, value := range desc.Attributes.GetAll(SSRCGroupAttribute) {
+			target.Attributes.Add(SSRCGroupAttribute, value)
+		}
+		msid := desc.Msid()
+		withMsid := make(map[uint32]bool)
+		for _, source := range sources {
+			if source.Name == MsidAttribute {
+				withMsid[source.SSRC] = true
+			}
+		}
+		for i, source := range sources {
+			target.Attributes.Add(SSRCAttribute, source.String())
+			lastOfSource := i+1 == len(sources) || sources[i+1].SSRC != source.SSRC
+			if msid != nil && lastOfSource && !withMsid[source.SSRC] {
+				withMsid[source.SSRC] = true
+				target.Attributes.Add(SSRCAttribute,
+					(&SourceAttribute{SSRC: source.SSRC, Name: MsidAttribute, Value: msid.String()}).String())
+			}
+		}
+	}
+
+	for target, values := range directions {
+		if direction := mergeDirections(values); res.Direction(target) != direction {
+			setDirection(target, direction)
+		}
+	}
+
+	rewriteGroups(res, mids)
+	return res, nil
+}
+
+func mergeDirections(directions []string) string {
+	send, recv := false, false
+	for _, direction := range directions {
+		send = send || direction == SendRecvAttribute || direction == SendOnlyAttribute
+		recv = recv || direction == SendRecvAttribute || direction == RecvOnlyAttribute
+	}
+	switch {
+	case send && recv:
+		return SendRecvAttribute
+	case send:
+		return SendOnlyAttribute
+	case recv:
+		return RecvOnlyAttribute
+	}
+	return InactiveAttribute
+}
+
+func setDirection(m *MediaDesc, direction string) {
+	for _, attribute := range m.Attributes {
+		if isDirection(attribute.Name) {
+			attribute.Name = direction
+			return
+		}
+	}
+	m.Attributes.Add(direction, "")
+}
+
+func rewriteGroups(s *Session, mids map[string][]string) {
+	for _, attribute := range s.Attributes {
+		if attribute.Name != GroupAttribute {
+			continue
+		}
+		fields := strings.Fields(attribute.Value)
+		if len(fields) == 0 {
+			continue
+		}
+		res := []string{fields[0]}
+		for _, mid := range fields[1:] {
+			replacement, ok := mids[mid]
+			if !ok {
+				replacement = []string{mid}
+			}
+			for _, value := range replacement {
+				if !inSet(value, res[1:]) {
+					res = append(res, value)
+				}
+			}
+		}
+		attribute.Value = strings.Join(res, " ")
+	}
+}
@@ -0,0 +1,162 @@
+package sdp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const planBSession = `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+a=group:BUNDLE audio video
+m=audio 9/1 UDP/TLS/RTP/SAVPF 111
+a=mid:audio
+a=sendrecv
+a=rtpmap:111 opus/48000/2
+a=ssrc:1001 cname:alice
+a=ssrc:1001 msid:stream-a audio-a
+m=video 9/1 UDP/TLS/RTP/SAVPF 96 97
+a=mid:video
+a=sendrecv
+a=rtpmap:96 VP8/90000
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96
+a=ssrc-group:FID 2001 2002
+a=ssrc:2001 cname:alice
+a=ssrc:2001 msid:stream-a video-a
+a=ssrc:2002 cname:alice
+a=ssrc:2002 msid:stream-a video-a
+a=ssrc:3001 cname:bob
+a=ssrc:3001 msid:stream-b video-b
+`
+
+const unifiedPlanSession = `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+a=group:BUNDLE audio video video-1
+m=audio 9/1 UDP/TLS/RTP/SAVPF 111
+a=mid:audio
+a=sendrecv
+a=rtpmap:111 opus/48000/2
+a=msid:stream-a audio-a
+a=ssrc:1001 cname:alice
+a=ssrc:1001 msid:stream-a audio-a
+m=video 9/1 UDP/TLS/RTP/SAVPF 96 97
+a=mid:video
+a=sendrecv
+a=rtpmap:96 VP8/90000
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96
+a=msid:stream-a video-a
+a=ssrc-group:FID 2001 2002
+a=ssrc:2001 cname:alice
+a=ssrc:2001 msid:stream-a video-a
+a=ssrc:2002 cname:alice
+a=ssrc:2002 msid:stream-a video-a
+m=video 9/1 UDP/TLS/RTP/SAVPF 96 97
+a=mid:video-1
+a=sendrecv
+a=rtpmap:96 VP8/90000
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96
+a=msid:stream-b video-b
+a=ssrc:3001 cname:bob
+a=ssrc:3001 msid:stream-b video-b
+`
+
+func TestToUnifiedPlan(t *testing.T) {
+	planB, err := NewDecoder(strings.NewReader(planBSession)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unified, err := ToUnifiedPlan(planB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	NewEncoder(&buf).Encode(unified)
+	if buf.String() != unifiedPlanSession {
+		t.Fatal(cmp.Diff(buf.String(), unifiedPlanSession))
+	}
+
+	if len(planB.MediaDescs) != 2 {
+		t.Fatal("source session was modified")
+	}
+}
+
+func TestToUnifiedPlanMids(t *testing.T) {
+	planB, err := NewDecoder(strings.NewReader(planBSession + `m=application 9/1 UDP/DTLS/SCTP webrtc-datachannel
+a=mid:video-1
+m=audio 9/1 RTP/AVP 0
+a=ssrc:4001 cname:carol
+a=ssrc:5001 cname:dave
+`)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	planB.Attributes.Set(GroupAttribute, "BUNDLE audio video video-1")
+
+	unified, err := ToUnifiedPlan(planB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mids []string
+	for _, desc := range unified.MediaDescs {
+		mids = append(mids, desc.Mid())
+	}
+	if expected := []string{"audio", "video", "video-2", "video-1", "audio-1", "audio-2"}; !cmp.Equal(mids, expected) {
+		t.Fatal(cmp.Diff(mids, expected))
+	}
+	if group, _ := unified.Attributes.Get(GroupAttribute); group != "BUNDLE audio video video-2 video-1" {
+		t.Fatalf("wrong group: %v", group)
+	}
+}
+
+func TestToPlanB(t *testing.T) {
+	planB, err := NewDecoder(strings.NewReader(planBSession)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unified, err := ToUnifiedPlan(planB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ToPlanB(unified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(res, planB) {
+		t.Fatal(cmp.Diff(res, planB))
+	}
+}
+
+func TestToPlanBDirections(t *testing.T) {
+	s := &Session{
+		MediaDescs: []*MediaDesc{
+			{Media: "audio", Attributes: []*Attribute{{Name: "mid", Value: "0"}, {Name: "sendonly", Value: " "}}},
+			{Media: "audio", Attributes: []*Attribute{{Name: "mid", Value: "1"}, {Name: "recvonly", Value: " "}}},
+		},
+		Attributes: []*Attribute{{Name: "group", Value: "BUNDLE 0 1"}},
+	}
+
+	res, err := ToPlanB(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.MediaDescs) != 1 || res.Direction(res.MediaDescs[0]) != SendRecvAttribute {
+		t.Fatalf("wrong plan b session: %v", dump(res))
+	}
+	if res.Attributes[0].Value != "BUNDLE 0" {
+		t.Fatalf("wrong bundle group: %v", res.Attributes[0].Value)
+	}
+}
@@ -0,0 +1,415 @@
+package sdp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attributes of preconditions (RFC 3312, RFC 4032).
+const (
+	CurrAttribute = "curr"
+	DesAttribute  = "des"
+	ConfAttribute = "conf"
+)
+
+// QoSPrecondition is the precondition type of RFC 3312.
+const QoSPrecondition = "qos"
+
+// Status types of preconditions.
+const (
+	PreconditionE2E    = "e2e"
+	PreconditionLocal  = "local"
+	PreconditionRemote = "remote"
+)
+
+// Direction tags of preconditions.
+const (
+	PreconditionDirectionNone = "none"
+	PreconditionSend          = "send"
+	PreconditionRecv          = "recv"
+	PreconditionSendRecv      = "sendrecv"
+)
+
+// Strength tags of desired preconditions, in increasing order of strength
+// up to PreconditionMandatory.
+const (
+	PreconditionStrengthNone = "none"
+	PreconditionOptional     = "optional"
+	PreconditionMandatory    = "mandatory"
+	PreconditionFailure      = "failure"
+	PreconditionUnknown      = "unknown"
+)
+
+var (
+	preconditionStatuses   = []string{PreconditionE2E, PreconditionLocal, PreconditionRemote}
+	preconditionDirections = []string{PreconditionDirectionNone, PreconditionSend, PreconditionRecv, PreconditionSendRecv}
+	preconditionStrengths  = []string{PreconditionStrengthNone, PreconditionOptional, PreconditionMandatory,
+		PreconditionFailure, PreconditionUnknown}
+)
+
+// Precondition is the value of an a=curr or a=conf attribute, e.g.
+// "qos local sendrecv".
+type Precondition struct {
+	Type      string
+	Status    string
+	Direction string
+}
+
+// DesiredPrecondition is the value of an a=des attribute, e.g.
+// "qos mandatory local sendrecv".
+type DesiredPrecondition struct {
+	Type      string
+	Strength  string
+	Status    string
+	Direction string
+}
+
+// ParsePrecondition parses an a=curr or a=conf value.
+func ParsePrecondition(value string) (*Precondition, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 3 || !inSet(fields[1], preconditionStatuses) || !inSet(fields[2], preconditionDirections) {
+		return nil, fmt.Errorf("wrong precondition format: %v", value)
+	}
+	return &Precondition{Type: fields[0], Status: fields[1], Direction: fields[2]}, nil
+}
+
+func (p *Precondition) String() string {
+	return p.Type + " " + p.Status + " " + p.Direction
+}
+
+// ParseDesiredPrecondition parses an a=des value.
+func ParseDesiredPrecondition(value string) (*DesiredPrecondition, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 4 || !inSet(fields[1], preconditionStrengths) || !inSet(fields[2], preconditionStatuses) ||
+		!inSet(fields[3], preconditionDirections) {
+		return nil, fmt.Errorf("wrong desired precondition format: %v", value)
+	}
+	return &DesiredPrecondition{Type: fields[0], Strength: fields[1], Status: fields[2], Direction: fields[3]}, nil
+}
+
+func (p *DesiredPrecondition) String() string {
+	return p.Type + " " + p.Strength + " " + p.Status + " " + p.Direction
+}
+
+// CurrentPreconditions returns the a=curr attributes of the media description.
+func (m *MediaDesc) CurrentPreconditions() ([]*Precondition, error) {
+	return parsePreconditions(m.Attributes.GetAll(CurrAttribute))
+}
+
+// ConfirmPreconditions returns the a=conf attributes of the media description.
+func (m *MediaDesc) ConfirmPreconditions() ([]*Precondition, error) {
+	return parsePreconditions(m.Attributes.GetAll(ConfAttribute))
+}
+
+// DesiredPreconditions returns the a=des attributes of the media description.
+func (m *MediaDesc) DesiredPreconditions() ([]*DesiredPrecondition, error) {
+	var res []*DesiredPrecondition
+	for _, value := range m.Attributes.GetAll(DesAttribute) {
+		precondition, err := ParseDesiredPrecondition(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, precondition)
+	}
+	return res, nil
+}
+
+func parsePreconditions(values []string) ([]*Precondition, error) {
+	var res []*Precondition
+	for _, value := range values {
+		precondition, err := ParsePrecondition(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, precondition)
+	}
+	return res, nil
+}
+
+// preconditionKey is a row of the status tables of RFC 3312 section 5: a
+// status type and a single direction, send or recv.
+type preconditionKey struct {
+	status    string
+	direction string
+}
+
+type preconditionEntry struct {
+	strength string
+	current  bool
+	// confirm is set if the local side asks the peer to confirm the status.
+	confirm bool
+	// confirmRequested is set if the peer asked to be told when the status
+	// is met, reported is set once it was.
+	confirmRequested bool
+	reported         bool
+}
+
+// PreconditionState is the local view of the preconditions of one type of a
+// media stream, with status types and directions as seen by the local side.
+// It merges the a=curr, a=des and a=conf attributes of the peer with the
+// local reservation status and writes those of the next offer or answer.
+type PreconditionState struct {
+	Type    string
+	entries map[preconditionKey]*preconditionEntry
+}
+
+// NewPreconditionState returns the state of preconditions of the given type,
+// usually QoSPrecondition.
+func NewPreconditionState(preconditionType string) *PreconditionState {
+	return &PreconditionState{Type: preconditionType, entries: make(map[preconditionKey]*preconditionEntry)}
+}
+
+// splitDirection returns the single directions of a direction tag.
+func splitDirection(direction string) []string {
+	switch direction {
+	case PreconditionSend, PreconditionRecv:
+		return []string{direction}
+	case PreconditionSendRecv:
+		return []string{PreconditionSend, PreconditionRecv}
+	}
+	return nil
+}
+
+func joinDirection(send, recv bool) string {
+	switch {
+	case send && recv:
+		return PreconditionSendRecv
+	case send:
+		return PreconditionSend
+	case recv:
+		return PreconditionRecv
+	}
+	return PreconditionDirectionNone
+}
+
+func (p *PreconditionState) entry(status, direction string) *preconditionEntry {
+	key := preconditionKey{status: status, direction: direction}
+	entry, ok := p.entries[key]
+	if !ok {
+		entry = &preconditionEntry{strength: PreconditionStrengthNone}
+		p.entries[key] = entry
+	}
+	return entry
+}
+
+// SetDesired sets the locally desired strength of the precondition.
+func (p *PreconditionState) SetDesired(strength, status, direction string) {
+	for _, direction := range splitDirection(direction) {
+		p.entry(status, direction).strength = strength
+	}
+}
+
+// SetCurrent records whether resources are reserved, e.g. for the local
+// segment once the local reservation completes.
+func (p *PreconditionState) SetCurrent(status, direction string, met bool) {
+	for _, direction := range splitDirection(direction) {
+		entry := p.entry(status, direction)
+		if entry.current != met {
+			entry.current = met
+			entry.reported = false
+		}
+	}
+}
+
+// RequestConfirm asks the peer to tell when the precondition is met.
+func (p *PreconditionState) RequestConfirm(status, direction string) {
+	for _, direction := range splitDirection(direction) {
+		p.entry(status, direction).confirm = true
+	}
+}
+
+// invertStatus and invertDirection turn the view of the peer into the local
+// one: its local segment is the remote one and its send direction is recv.
+func invertStatus(status string) string {
+	switch status {
+	case PreconditionLocal:
+		return PreconditionRemote
+	case PreconditionRemote:
+		return PreconditionLocal
+	}
+	return status
+}
+
+func invertDirection(direction string) string {
+	switch direction {
+	case PreconditionSend:
+		return PreconditionRecv
+	case PreconditionRecv:
+		return PreconditionSend
+	}
+	return direction
+}
+
+// Update merges the preconditions of an offer or answer received from the
+// peer. Desired strengths are upgraded to the stronger of both sides, the
+// current status of the segments of the peer is taken from it and its
+// a=conf attributes are recorded.
+func (p *PreconditionState) Update(m *MediaDesc) error {
+	desired, err := m.DesiredPreconditions()
+	if err != nil {
+		return err
+	}
+	current, err := m.CurrentPreconditions()
+	if err != nil {
+		return err
+	}
+	confirm, err := m.ConfirmPreconditions()
+	if err != nil {
+		return err
+	}
+
+	for _, des := range desired {
+		if des.Type != p.Type {
+			continue
+		}
+		for _, direction := range splitDirection(des.Direction) {
+			entry := p.entry(invertStatus(des.Status), invertDirection(direction))
+			entry.strength = strongerPrecondition(entry.strength, des.Strength)
+		}
+	}
+
+	for _, curr := range current {
+		if curr.Type != p.Type || curr.Status == PreconditionRemote {
+			// The peer's view of the local segment is not better than ours.
+			continue
+		}
+		status := invertStatus(curr.Status)
+		met := make(map[string]bool)
+		for _, direction := range splitDirection(curr.Direction) {
+			met[invertDirection(direction)] = true
+		}
+		for _, direction := range []string{PreconditionSend, PreconditionRecv} {
+			if _, ok := p.entries[preconditionKey{status: status, direction: direction}]; !ok && !met[direction] {
+				continue
+			}
+			entry := p.entry(status, direction)
+			if status == PreconditionE2E {
+				// Either side may learn of the end-to-end reservation.
+				entry.current = entry.current || met[direction]
+			} else {
+				entry.current = met[direction]
+			}
+			if entry.current {
+				// There is nothing left to confirm.
+				entry.confirm = false
+			}
+		}
+	}
+
+	for _, conf := range confirm {
+		if conf.Type != p.Type {
+			continue
+		}
+		for _, direction := range splitDirection(conf.Direction) {
+			entry := p.entry(invertStatus(conf.Status), invertDirection(direction))
+			entry.confirmRequested = true
+			entry.reported = false
+		}
+	}
+	return nil
+}
+
+// strongerPrecondition returns the stronger of two strength tags. Failure is
+// the strongest, unknown changes nothing.
+func strongerPrecondition(a, b string) string {
+	if a == PreconditionFailure || b == PreconditionFailure {
+		return PreconditionFailure
+	}
+	rank := func(strength string) int {
+		for i, value := range []string{PreconditionStrengthNone, PreconditionOptional, PreconditionMandatory} {
+			if value == strength {
+				return i
+			}
+		}
+		return -1
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// Met reports whether all mandatory preconditions are met, so that the
+// session may proceed, e.g. the called party may be alerted.
+func (p *PreconditionState) Met() bool {
+	if p.Failed() {
+		return false
+	}
+	for _, entry := range p.entries {
+		if entry.strength == PreconditionMandatory && !entry.current {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed reports whether either side gave up on a precondition.
+func (p *PreconditionState) Failed() bool {
+	for _, entry := range p.entries {
+		if entry.strength == PreconditionFailure {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsUpdate reports whether the peer asked to confirm a status that
+// changed since the last Apply, which then has to be sent in a new offer,
+// e.g. in an UPDATE.
+func (p *PreconditionState) NeedsUpdate() bool {
+	for _, entry := range p.entries {
+		if entry.confirmRequested && entry.current && !entry.reported {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply replaces the a=curr, a=des and a=conf attributes of the media
+// description of the next offer or answer with the state.
+func (p *PreconditionState) Apply(m *MediaDesc) {
+	m.Attributes.Remove(CurrAttribute, DesAttribute, ConfAttribute)
+
+	var current, desired, confirm Attributes
+	for _, status := range preconditionStatuses {
+		send, hasSend := p.entries[preconditionKey{status: status, direction: PreconditionSend}]
+		recv, hasRecv := p.entries[preconditionKey{status: status, direction: PreconditionRecv}]
+		if !hasSend && !hasRecv {
+			continue
+		}
+		if !hasSend {
+			send = &preconditionEntry{strength: PreconditionStrengthNone}
+		}
+		if !hasRecv {
+			recv = &preconditionEntry{strength: PreconditionStrengthNone}
+		}
+
+		curr := &Precondition{Type: p.Type, Status: status, Direction: joinDirection(send.current, recv.current)}
+		current.Add(CurrAttribute, curr.String())
+		if send.strength == recv.strength {
+			des := &DesiredPrecondition{Type: p.Type, Strength: send.strength, Status: status,
+				Direction: PreconditionSendRecv}
+			desired.Add(DesAttribute, des.String())
+		} else {
+			for _, des := range []*DesiredPrecondition{
+				{Type: p.Type, Strength: send.strength, Status: status, Direction: PreconditionSend},
+				{Type: p.Type, Strength: recv.strength, Status: status, Direction: PreconditionRecv},
+			} {
+				desired.Add(DesAttribute, des.String())
+			}
+		}
+		if send.confirm || recv.confirm {
+			conf := &Precondition{Type: p.Type, Status: status, Direction: joinDirection(send.confirm, recv.confirm)}
+			confirm.Add(ConfAttribute, conf.String())
+		}
+
+		for _, entry := range []*preconditionEntry{send, recv} {
+			if entry.current {
+				entry.reported = true
+			}
+		}
+	}
+	m.Attributes = append(m.Attributes, current...)
+	m.Attributes = append(m.Attributes, desired...)
+	m.Attributes = append(m.Attributes, confirm...)
+}
@@ -0,0 +1,164 @@
+package sdp
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePreconditions(t *testing.T) {
+	m := &MediaDesc{}
+	m.Attributes.Add(CurrAttribute, "qos local none")
+	m.Attributes.Add(CurrAttribute, "qos remote sendrecv")
+	m.Attributes.Add(DesAttribute, "qos mandatory e2e send")
+	m.Attributes.Add(ConfAttribute, "qos remote recv")
+
+	current, err := m.CurrentPreconditions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Precondition{
+		{Type: QoSPrecondition, Status: PreconditionLocal, Direction: PreconditionDirectionNone},
+		{Type: QoSPrecondition, Status: PreconditionRemote, Direction: PreconditionSendRecv},
+	}
+	if diff := cmp.Diff(expected, current); diff != "" {
+		t.Fatal(diff)
+	}
+	desired, err := m.DesiredPreconditions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDesired := []*DesiredPrecondition{
+		{Type: QoSPrecondition, Strength: PreconditionMandatory, Status: PreconditionE2E, Direction: PreconditionSend},
+	}
+	if diff := cmp.Diff(expectedDesired, desired); diff != "" {
+		t.Fatal(diff)
+	}
+	confirm, err := m.ConfirmPreconditions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirm) != 1 || confirm[0].String() != "qos remote recv" {
+		t.Fatalf("wrong confirm preconditions: %v", confirm)
+	}
+
+	for _, value := range []string{"qos local", "qos segment send", "qos local both"} {
+		if _, err := ParsePrecondition(value); err == nil {
+			t.Fatalf("%v was accepted", value)
+		}
+	}
+	for _, value := range []string{"qos local send", "qos required local send", "qos mandatory local send extra"} {
+		if _, err := ParseDesiredPrecondition(value); err == nil {
+			t.Fatalf("%v was accepted", value)
+		}
+	}
+}
+
+func preconditionAttributes(m *MediaDesc) []string {
+	var res []string
+	for _, attribute := range m.Attributes {
+		res = append(res, attribute.Name+":"+attribute.Value)
+	}
+	return res
+}
+
+// TestPreconditionState follows the segmented flow of RFC 3312 section 6,
+// with the answerer asking for confirmation.
+func TestPreconditionState(t *testing.T) {
+	offerer := NewPreconditionState(QoSPrecondition)
+	offerer.SetDesired(PreconditionMandatory, PreconditionLocal, PreconditionSendRecv)
+	offerer.SetDesired(PreconditionStrengthNone, PreconditionRemote, PreconditionSendRecv)
+	offer := &MediaDesc{}
+	offerer.Apply(offer)
+	expected := []string{
+		"curr:qos local none",
+		"curr:qos remote none",
+		"des:qos mandatory local sendrecv",
+		"des:qos none remote sendrecv",
+	}
+	if diff := cmp.Diff(expected, preconditionAttributes(offer)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	answerer := NewPreconditionState(QoSPrecondition)
+	if err := answerer.Update(offer); err != nil {
+		t.Fatal(err)
+	}
+	answerer.SetDesired(PreconditionMandatory, PreconditionLocal, PreconditionSendRecv)
+	answerer.RequestConfirm(PreconditionRemote, PreconditionSendRecv)
+	answer := &MediaDesc{}
+	answerer.Apply(answer)
+	expected = []string{
+		"curr:qos local none",
+		"curr:qos remote none",
+		"des:qos mandatory local sendrecv",
+		"des:qos mandatory remote sendrecv",
+		"conf:qos remote sendrecv",
+	}
+	if diff := cmp.Diff(expected, preconditionAttributes(answer)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := offerer.Update(answer); err != nil {
+		t.Fatal(err)
+	}
+	if offerer.Met() || offerer.NeedsUpdate() {
+		t.Fatal("preconditions met before the reservation")
+	}
+	offerer.SetCurrent(PreconditionLocal, PreconditionSendRecv, true)
+	if !offerer.NeedsUpdate() {
+		t.Fatal("confirmation is not sent")
+	}
+	update := &MediaDesc{}
+	offerer.Apply(update)
+	if offerer.NeedsUpdate() {
+		t.Fatal("confirmation is sent twice")
+	}
+	expected = []string{
+		"curr:qos local sendrecv",
+		"curr:qos remote none",
+		"des:qos mandatory local sendrecv",
+		"des:qos mandatory remote sendrecv",
+	}
+	if diff := cmp.Diff(expected, preconditionAttributes(update)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := answerer.Update(update); err != nil {
+		t.Fatal(err)
+	}
+	if answerer.Met() {
+		t.Fatal("preconditions met before the local reservation")
+	}
+	answerer.SetCurrent(PreconditionLocal, PreconditionSendRecv, true)
+	if !answerer.Met() {
+		t.Fatal("preconditions are not met")
+	}
+	updateAnswer := &MediaDesc{}
+	answerer.Apply(updateAnswer)
+	expected = []string{
+		"curr:qos local sendrecv",
+		"curr:qos remote sendrecv",
+		"des:qos mandatory local sendrecv",
+		"des:qos mandatory remote sendrecv",
+	}
+	if diff := cmp.Diff(expected, preconditionAttributes(updateAnswer)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := offerer.Update(updateAnswer); err != nil {
+		t.Fatal(err)
+	}
+	if !offerer.Met() {
+		t.Fatal("preconditions are not met")
+	}
+
+	failure := &MediaDesc{}
+	failure.Attributes.Add(DesAttribute, "qos failure e2e send")
+	if err := offerer.Update(failure); err != nil {
+		t.Fatal(err)
+	}
+	if !offerer.Failed() || offerer.Met() {
+		t.Fatal("failure is ignored")
+	}
+}
@@ -0,0 +1,306 @@
+package sdp
+
+import "strings"
+
+// RawLines is the original text of a session description decoded by a
+// Decoder in raw line mode, see Decoder.KeepRawLines. When a session has raw
+// lines, the Encoder writes the lines of the elements that did not change
+// exactly as they were read, in their original order. A changed element is
+// written in place of its original lines, an added one after the element it
+// follows in the session, and the lines of a removed one are left out. If
+// elements were moved, e.g. media descriptions reordered or an attribute
+// moved to another section, all lines are written in the current order of
+// the elements instead, still keeping the text of the unchanged ones.
+type RawLines struct {
+	lines []rawLine
+	// ending is the line ending of the added lines, that of the first line.
+	ending string
+}
+
+type rawLine struct {
+	key interface{}
+	// text is the line with its line ending, if any.
+	text string
+	// canonical is the line as the Encoder wrote it when it was decoded,
+	// without the line ending.
+	canonical string
+	// position is the index of the line among the lines the Encoder wrote
+	// when it was decoded.
+	position int
+}
+
+// fieldKey identifies the line of a plain value of a session or a media
+// description, which, unlike the other elements, has no pointer of its own.
+type fieldKey struct {
+	owner interface{}
+	field byte
+	index int
+}
+
+// encodedLine is a line written by a recording Encoder, without the line
+// ending.
+type encodedLine struct {
+	key  interface{}
+	text string
+}
+
+type lineMark struct {
+	key    interface{}
+	offset int
+}
+
+// markElement starts the line of an element, identified by its pointer.
+func (e *Encoder) markElement(element interface{}) {
+	if e.recording {
+		e.marks = append(e.marks, lineMark{key: element, offset: len(e.buf)})
+	}
+}
+
+// markField starts the line of a plain value of owner.
+func (e *Encoder) markField(owner interface{}, field byte, index int) {
+	if e.recording {
+		e.markElement(fieldKey{owner: owner, field: field, index: index})
+	}
+}
+
+// recordLines returns the lines the Encoder writes for the session, ignoring
+// its raw lines.
+func recordLines(s *Session, compactTimes bool) []encodedLine {
+	e := Encoder{recording: true, compactTimes: compactTimes}
+	e.encodeSession(s)
+
+	lines := make([]encodedLine, 0, len(e.marks))
+	for i, mark := range e.marks {
+		end := len(e.buf)
+		if i+1 < len(e.marks) {
+			end = e.marks[i+1].offset
+		}
+		text := string(e.buf[mark.offset:end])
+		lines = append(lines, encodedLine{key: mark.key, text: text[:len(text)-1]})
+	}
+	return lines
+}
+
+// encodeRawLines writes the raw lines of the session merged with its
+// current elements.
+func (e *Encoder) encodeRawLines(s *Session) {
+	raw := s.Raw
+	lines := recordLines(s, false)
+	// The lines are compared with the canonical text of the raw lines, in
+	// full form, and changed ones are written in the form of the Encoder.
+	text := lines
+	if e.compactTimes {
+		text = recordLines(s, true)
+	}
+	start := len(e.buf)
+
+	index := make(map[interface{}]int, len(lines))
+	for i, line := range lines {
+		index[line.key] = i
+	}
+	decoded := make(map[interface{}]bool, len(raw.lines))
+	for _, line := range raw.lines {
+		decoded[line.key] = true
+	}
+	written := make([]bool, len(lines))
+
+	writeLine := func(text, ending string) {
+		// The last line of the original text may have no line ending.
+		if len(e.buf) > start && e.buf[len(e.buf)-1] != '\n' {
+			e.writeString(raw.ending)
+		}
+		e.writeString(text).writeString(ending)
+	}
+	// writeAdded writes the elements added after the one before lines[i].
+	writeAdded := func(i int) {
+		for ; i < len(lines) && !decoded[lines[i].key]; i++ {
+			if !written[i] {
+				writeLine(text[i].text, raw.ending)
+				written[i] = true
+			}
+		}
+	}
+
+	if moved(raw, lines) {
+		e.encodeMovedLines(raw, lines, text, writeLine)
+		return
+	}
+
+	writeAdded(0)
+	for _, line := range raw.lines {
+		i, ok := index[line.key]
+		if !ok || written[i] {
+			continue
+		}
+		if lines[i].text == line.canonical {
+			writeLine(line.text, "")
+		} else {
+			writeLine(text[i].text, lineEnding(line.text))
+			written[i] = true
+		}
+		writeAdded(i + 1)
+	}
+}
+
+// moved reports whether the decoded elements are no longer in the order
+// they were decoded in.
+func moved(raw *RawLines, lines []encodedLine) bool {
+	positions := make(map[interface{}]int, len(raw.lines))
+	for _, line := range raw.lines {
+		positions[line.key] = line.position
+	}
+	last := -1
+	for _, line := range lines {
+		if position, ok := positions[line.key]; ok {
+			if position < last {
+				return true
+			}
+			last = position
+		}
+	}
+	return false
+}
+
+// encodeMovedLines writes the lines in the current order of the elements,
+// with the raw text of the unchanged ones.
+func (e *Encoder) encodeMovedLines(raw *RawLines, lines, text []encodedLine, writeLine func(text, ending string)) {
+	// An element may have been read from several lines, e.g. time zones
+	// from several z= lines.
+	rawLines := make(map[interface{}][]string, len(raw.lines))
+	canonical := make(map[interface{}]string, len(raw.lines))
+	for _, line := range raw.lines {
+		rawLines[line.key] = append(rawLines[line.key], line.text)
+		canonical[line.key] = line.canonical
+	}
+	for i, line := range lines {
+		texts, ok := rawLines[line.key]
+		switch {
+		case !ok:
+			writeLine(text[i].text, raw.ending)
+		case line.text == canonical[line.key]:
+			for _, text := range texts {
+				writeLine(text, "")
+			}
+		default:
+			writeLine(text[i].text, lineEnding(texts[0]))
+		}
+	}
+}
+
+func lineEnding(line string) string {
+	if strings.HasSuffix(line, "\r\n") {
+		return "\r\n"
+	}
+	if strings.HasSuffix(line, "\n") {
+		return "\n"
+	}
+	return ""
+}
+
+// clone returns the raw lines of to, a clone of from.
+func (r *RawLines) clone(from, to *Session) *RawLines {
+	if r == nil {
+		return nil
+	}
+
+	keys := make(map[interface{}]interface{})
+	toLines := recordLines(to, false)
+	for i, line := range recordLines(from, false) {
+		keys[line.key] = toLines[i].key
+	}
+
+	res := &RawLines{lines: make([]rawLine, 0, len(r.lines)), ending: r.ending}
+	for _, line := range r.lines {
+		// The lines of removed elements have no key and stay removed.
+		line.key = keys[line.key]
+		res.lines = append(res.lines, line)
+	}
+	return res
+}
+
+// KeepRawLines makes the Decoder keep the original lines of the session
+// description in Session.Raw.
+func (d *Decoder) KeepRawLines() {
+	d.raw = &RawLines{}
+}
+
+// keepRawLine records the line just parsed, text, with its line ending.
+func (d *Decoder) keepRawLine(text string, field byte, isExtension bool) {
+	if d.raw.ending == "" {
+		d.raw.ending = lineEnding(text)
+	}
+	d.raw.lines = append(d.raw.lines, rawLine{key: d.lineKey(field, isExtension), text: text})
+}
+
+// lineKey returns the key of the element the line just parsed went into.
+func (d *Decoder) lineKey(field byte, isExtension bool) interface{} {
+	var desc *MediaDesc
+	if len(d.s.MediaDescs) > 0 {
+		desc = d.s.MediaDescs[len(d.s.MediaDescs)-1]
+	}
+
+	if isExtension {
+		if desc != nil {
+			return desc.ExtensionLines[len(desc.ExtensionLines)-1]
+		}
+		return d.s.ExtensionLines[len(d.s.ExtensionLines)-1]
+	}
+	switch field {
+	case MediaDescField:
+		return desc
+	case OriginField:
+		return d.s.Originator
+	case EmailField:
+		return fieldKey{owner: d.s, field: field, index: len(d.s.Emails) - 1}
+	case PhoneNumberField:
+		return fieldKey{owner: d.s, field: field, index: len(d.s.PhoneNumbers) - 1}
+	case TimingField:
+		return d.s.Timings[len(d.s.Timings)-1]
+	case RepeatTimeField:
+		timing := d.s.Timings[len(d.s.Timings)-1]
+		return timing.RepeatTimes[len(timing.RepeatTimes)-1]
+	}
+	if desc == nil {
+		switch field {
+		case ConnectionDataField:
+			return d.s.ConnectionData
+		case BandwidthField:
+			return d.s.Bandwidths[len(d.s.Bandwidths)-1]
+		case EncryptionKeyField:
+			return d.s.EncryptionKeys[len(d.s.EncryptionKeys)-1]
+		case AttributeField:
+			return d.s.Attributes[len(d.s.Attributes)-1]
+		}
+		return fieldKey{owner: d.s, field: field}
+	}
+	switch field {
+	case ConnectionDataField:
+		return desc.Connections[len(desc.Connections)-1]
+	case BandwidthField:
+		return desc.Bandwidths[len(desc.Bandwidths)-1]
+	case EncryptionKeyField:
+		return desc.EncryptionKeys[len(desc.EncryptionKeys)-1]
+	case AttributeField:
+		return desc.Attributes[len(desc.Attributes)-1]
+	}
+	return fieldKey{owner: desc, field: field}
+}
+
+// finishRawLines sets the canonical text of the kept lines once the session
+// is decoded.
+func (d *Decoder) finishRawLines() {
+	positions := make(map[interface{}]int)
+	lines := recordLines(d.s, false)
+	for i, line := range lines {
+		positions[line.key] = i
+	}
+	for i := range d.raw.lines {
+		position := positions[d.raw.lines[i].key]
+		d.raw.lines[i].canonical = lines[position].text
+		d.raw.lines[i].position = position
+	}
+	if d.raw.ending == "" {
+		d.raw.ending = "\n"
+	}
+	d.s.Raw = d.raw
+}
@@ -0,0 +1,159 @@
+package sdp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// rawTestData is in the order and layout the Encoder does not produce.
+const rawTestData = "v=0\r\n" +
+	"o=jdoe 2890844526 2890842807 IN IP4 10.47.16.5\r\n" +
+	"s=SDP Seminar\r\n" +
+	"c=IN IP4 224.2.17.12/0\r\n" +
+	"i=A Seminar on the session description protocol\r\n" +
+	"t=2873397496 2873404696\r\n" +
+	"r=7d 1h 0 25h\r\n" +
+	"z=2882844526 -1h\r\n" +
+	"z=2898848070 0\r\n" +
+	"y=0123456789\r\n" +
+	"a=recvonly\r\n" +
+	"m=audio 49170/1 RTP/AVP 0\n" +
+	"a=rtpmap:0 PCMU/8000\n" +
+	"m=video 51372 RTP/AVP 99\r\n" +
+	"a=rtpmap:99 h263-1998/90000"
+
+func decodeRaw(t *testing.T, data string) *Session {
+	t.Helper()
+	d := NewDecoder(bytes.NewReader([]byte(data)))
+	d.KeepRawLines()
+	s, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRawLinesRoundTrip(t *testing.T) {
+	corpus := readCorpus(t)
+	corpus["raw"] = []byte(rawTestData)
+
+	for name, data := range corpus {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			s := decodeRaw(t, string(data))
+			text, err := Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(text) != string(data) {
+				t.Fatal(cmp.Diff(string(data), string(text)))
+			}
+			if text := s.Clone().String(); text != string(data) {
+				t.Fatal(cmp.Diff(string(data), text))
+			}
+		})
+	}
+}
+
+func TestRawLinesEdit(t *testing.T) {
+	tests := []struct {
+		Name string
+		Edit func(s *Session)
+		Data string
+	}{
+		{
+			Name: "set attribute",
+			Edit: func(s *Session) { s.MediaDescs[0].Attributes.Set("rtpmap", "8 PCMA/8000") },
+			Data: "m=audio 49170/1 RTP/AVP 0\na=rtpmap:8 PCMA/8000\nm=video",
+		},
+		{
+			Name: "add attributes",
+			Edit: func(s *Session) {
+				s.Attributes.Add("tool", "b2bua")
+				s.MediaDescs[0].Attributes.Add("ptime", "20")
+				s.MediaDescs[1].Attributes.Add("sendonly", "")
+			},
+			Data: "a=recvonly\r\na=tool:b2bua\r\nm=audio 49170/1 RTP/AVP 0\na=rtpmap:0 PCMU/8000\n" +
+				"a=ptime:20\r\nm=video 51372 RTP/AVP 99\r\na=rtpmap:99 h263-1998/90000\r\na=sendonly\r\n",
+		},
+		{
+			Name: "remove media",
+			Edit: func(s *Session) { s.MediaDescs = s.MediaDescs[1:] },
+			Data: "a=recvonly\r\nm=video 51372 RTP/AVP 99\r\n",
+		},
+		{
+			Name: "change time zones",
+			Edit: func(s *Session) { s.TimeZones = s.TimeZones[:1] },
+			Data: "r=7d 1h 0 25h\r\nz=2882844526 -3600\r\ny=0123456789\r\n",
+		},
+		{
+			Name: "add information",
+			Edit: func(s *Session) { s.MediaDescs[1].Information = "camera" },
+			Data: "m=video 51372 RTP/AVP 99\r\ni=camera\r\na=rtpmap:99",
+		},
+		{
+			Name: "change connection",
+			Edit: func(s *Session) { s.ConnectionData.ConnectionAddr = "224.2.17.13" },
+			Data: "s=SDP Seminar\r\nc=IN IP4 224.2.17.13\r\ni=A Seminar",
+		},
+		{
+			Name: "swap media",
+			Edit: func(s *Session) { s.MediaDescs[0], s.MediaDescs[1] = s.MediaDescs[1], s.MediaDescs[0] },
+			Data: "a=recvonly\r\nm=video 51372 RTP/AVP 99\r\na=rtpmap:99 h263-1998/90000\r\n" +
+				"m=audio 49170/1 RTP/AVP 0\na=rtpmap:0 PCMU/8000\n",
+		},
+		{
+			Name: "move attribute",
+			Edit: func(s *Session) {
+				s.MediaDescs[1].Attributes = append(s.MediaDescs[1].Attributes, s.Attributes[0])
+				s.Attributes = nil
+			},
+			Data: "y=0123456789\r\nm=audio 49170/1 RTP/AVP 0\na=rtpmap:0 PCMU/8000\nm=video 51372 RTP/AVP 99\r\n" +
+				"a=rtpmap:99 h263-1998/90000\r\na=recvonly\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			s := decodeRaw(t, rawTestData)
+			test.Edit(s)
+			text := s.String()
+			if !bytes.Contains([]byte(text), []byte(test.Data)) {
+				t.Fatalf("%q does not contain %q", text, test.Data)
+			}
+			res, err := NewDecoder(bytes.NewReader([]byte(text))).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Raw = nil
+			if !cmp.Equal(res, s) {
+				t.Fatal(cmp.Diff(res, s))
+			}
+		})
+	}
+}
+
+func TestRawLinesCompactTimes(t *testing.T) {
+	s := decodeRaw(t, rawTestData)
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SetCompactTimes(true)
+	if err := e.Encode(s); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != rawTestData {
+		t.Fatal(cmp.Diff(rawTestData, buf.String()))
+	}
+
+	s.Timings[0].RepeatTimes[0].Duration = 7200
+	buf.Reset()
+	if err := e.Encode(s); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("\r\nr=7d 2h 0 25h\r\n")) {
+		t.Fatalf("wrong repeat time: %q", buf.String())
+	}
+}
@@ -0,0 +1,245 @@
+package sdp
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func addFuzzSeeds(f *testing.F) {
+	for _, v := range unmarshalTests {
+		f.Add(v.Data)
+	}
+	for _, v := range unmarshalErrorTests {
+		f.Add(v.Data)
+	}
+	for _, v := range marshalTests {
+		f.Add(v.Data)
+	}
+	for _, data := range readCorpus(f) {
+		f.Add(string(data))
+	}
+	f.Add("v=0\no=- 0 0 IN IP4 0.0.0.0\ns=-\nc=IN IP4 0.0.0.0\nt=0 0\nm=audio\n")
+	f.Add("v=0\no=- 0 0 IN IP4 0.0.0.0\ns=-\nc=IN IP6 ::1/2\nt=0 0\nr=7d 1h 0 25h\nz=0 -1h\nk=prompt\n")
+}
+
+// FuzzRoundTrip checks that every session the Decoder accepts is encoded
+// to a text that decodes to the same session.
+func FuzzRoundTrip(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data string) {
+		s, err := NewDecoder(strings.NewReader(data)).Decode()
+		if err != nil {
+			return
+		}
+		assertRoundTrip(t, s)
+	})
+}
+
+func assertRoundTrip(t *testing.T, s *Session) {
+	t.Helper()
+
+	encoded, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res Session
+	if err := Unmarshal(encoded, &res); err != nil {
+		t.Fatalf("encoded session does not decode: %v\n%s", err, encoded)
+	}
+	if !cmp.Equal(&res, s) {
+		t.Fatalf("session changed after round trip:\n%s\n%v", encoded, cmp.Diff(&res, s))
+	}
+}
+
+func TestRandomRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		assertRoundTrip(t, randomSession(r))
+	}
+}
+
+var randomTokens = []string{"-", "0", "1", "a", "foo", "x-bar", "9e8f", "webrtc-datachannel", "*"}
+
+func randomToken(r *rand.Rand) string {
+	return randomTokens[r.Intn(len(randomTokens))]
+}
+
+func randomText(r *rand.Rand) string {
+	words := make([]string, 1+r.Intn(3))
+	for i := range words {
+		words[i] = randomToken(r)
+	}
+	return strings.Join(words, " ")
+}
+
+func randomStrings(r *rand.Rand, max int, gen func(r *rand.Rand) string) []string {
+	var res []string
+	for i := r.Intn(max + 1); i > 0; i-- {
+		res = append(res, gen(r))
+	}
+	return res
+}
+
+func randomConnection(r *rand.Rand) *Connection {
+	if r.Intn(2) == 0 {
+		return &Connection{
+			Nettype:        NetworkInternet,
+			Addrtype:       TypeIPv6,
+			ConnectionAddr: "ff15::101",
+			AddressesNum:   1 + r.Int63n(3),
+		}
+	}
+	connection := &Connection{
+		Nettype:        NetworkInternet,
+		Addrtype:       TypeIPv4,
+		ConnectionAddr: "224.2.1." + strconv.Itoa(r.Intn(256)),
+		AddressesNum:   1,
+	}
+	if r.Intn(2) == 0 {
+		connection.TTL = r.Int63n(256)
+		connection.AddressesNum = 1 + r.Int63n(3)
+	}
+	return connection
+}
+
+func randomConnections(r *rand.Rand, max int) []*Connection {
+	var res []*Connection
+	for i := r.Intn(max + 1); i > 0; i-- {
+		res = append(res, randomConnection(r))
+	}
+	return res
+}
+
+func randomBandwidths(r *rand.Rand) []*Bandwidth {
+	var res []*Bandwidth
+	for i := r.Intn(3); i > 0; i-- {
+		res = append(res, &Bandwidth{Type: []string{"AS", "CT", "TIAS"}[r.Intn(3)], Value: r.Intn(100000)})
+	}
+	return res
+}
+
+func randomEncryptionKeys(r *rand.Rand) []*EncryptionKey {
+	var res []*EncryptionKey
+	for i := r.Intn(2); i > 0; i-- {
+		key := &EncryptionKey{Method: "prompt", Value: propertyValue}
+		if r.Intn(2) == 0 {
+			key.Method, key.Value = "clear", randomToken(r)
+		}
+		res = append(res, key)
+	}
+	return res
+}
+
+func randomAttributes(r *rand.Rand) Attributes {
+	var res Attributes
+	for i := r.Intn(5); i > 0; i-- {
+		value := ""
+		if r.Intn(3) > 0 {
+			value = randomText(r)
+		}
+		res = append(res, NewAttribute([]string{"sendrecv", "rtpmap", "fmtp", "x-foo", "mid", "group"}[r.Intn(6)], value))
+	}
+	return res
+}
+
+func randomTimings(r *rand.Rand) []*Timing {
+	res := make([]*Timing, 1+r.Intn(2))
+	for i := range res {
+		timing := &Timing{}
+		if r.Intn(2) == 0 {
+			timing.Start = 3034423619 + r.Int63n(1000)
+			timing.Stop = timing.Start + r.Int63n(1000)
+		}
+		for j := r.Intn(3); j > 0; j-- {
+			repeat := &RepeatTime{Interval: 1 + r.Int63n(604800), Duration: 1 + r.Int63n(3600)}
+			for k := 1 + r.Intn(3); k > 0; k-- {
+				repeat.Offsets = append(repeat.Offsets, r.Int63n(90000))
+			}
+			timing.RepeatTimes = append(timing.RepeatTimes, repeat)
+		}
+		res[i] = timing
+	}
+	return res
+}
+
+func randomTimeZones(r *rand.Rand) []*TimeZone {
+	var res []*TimeZone
+	for i := r.Intn(3); i > 0; i-- {
+		res = append(res, &TimeZone{Time: 3034423619 + r.Int63n(1000), Offset: r.Int63n(7200) - 3600})
+	}
+	return res
+}
+
+func randomMediaDesc(r *rand.Rand, withConnection bool) *MediaDesc {
+	protos := [][]string{
+		{RTPproto, AVPproto},
+		{UDPproto, TLSproto, RTPproto, SAVPFproto},
+		{UDPproto, DTLSproto, SCTPproto},
+		{TCPproto, MSRPproto},
+		{UDPTLproto},
+	}
+	desc := &MediaDesc{
+		Media:          []string{"audio", "video", "text", "application", "message", ImageMedia}[r.Intn(6)],
+		Port:           r.Int63n(65536),
+		PortsNum:       1 + r.Int63n(2),
+		Proto:          protos[r.Intn(len(protos))],
+		Fmts:           append([]string{randomToken(r)}, randomStrings(r, 3, randomToken)...),
+		Connections:    randomConnections(r, 2),
+		Bandwidths:     randomBandwidths(r),
+		EncryptionKeys: randomEncryptionKeys(r),
+		Attributes:     randomAttributes(r),
+	}
+	if r.Intn(3) == 0 {
+		desc.Information = randomText(r)
+	}
+	for i := r.Intn(2); i > 0; i-- {
+		desc.ExtensionLines = append(desc.ExtensionLines, &ExtensionLine{Type: 'y', Value: randomText(r), After: MediaDescField})
+	}
+	if withConnection && desc.Connections == nil {
+		desc.Connections = []*Connection{randomConnection(r)}
+	}
+	return desc
+}
+
+// randomSession returns a valid session that the Encoder and the Decoder
+// must round-trip exactly.
+func randomSession(r *rand.Rand) *Session {
+	s := &Session{
+		Originator: &Origin{
+			Username:       randomToken(r),
+			SessID:         r.Int63(),
+			SessVersion:    r.Int63(),
+			Nettype:        NetworkInternet,
+			Addrtype:       TypeIPv4,
+			UnicastAddress: "127.0.0.1",
+		},
+		SessionName:    randomText(r),
+		Emails:         randomStrings(r, 2, randomText),
+		PhoneNumbers:   randomStrings(r, 2, randomText),
+		Bandwidths:     randomBandwidths(r),
+		Timings:        randomTimings(r),
+		TimeZones:      randomTimeZones(r),
+		EncryptionKeys: randomEncryptionKeys(r),
+		Attributes:     randomAttributes(r),
+	}
+	if r.Intn(2) == 0 {
+		s.Information = randomText(r)
+	}
+	if r.Intn(2) == 0 {
+		s.URI = "http://example.com/" + randomToken(r)
+	}
+	if r.Intn(2) == 0 {
+		s.ConnectionData = randomConnection(r)
+	}
+	for i := r.Intn(2); i > 0; i-- {
+		s.ExtensionLines = append(s.ExtensionLines, &ExtensionLine{Type: 'f', Value: randomText(r), After: SessionNameField})
+	}
+	for i := r.Intn(4); i > 0; i-- {
+		s.MediaDescs = append(s.MediaDescs, randomMediaDesc(r, s.ConnectionData == nil))
+	}
+	return s
+}
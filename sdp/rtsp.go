@@ -0,0 +1,354 @@
+package sdp
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attributes of session descriptions returned by RTSP servers (RFC 2326,
+// RFC 7826 and common extensions).
+const (
+	ControlAttribute     = "control"
+	RangeAttribute       = "range"
+	FramerateAttribute   = "framerate"
+	XDimensionsAttribute = "x-dimensions"
+	ToolAttribute        = "tool"
+)
+
+// Units of a=range.
+const (
+	RangeNPT         = "npt"
+	RangeSMPTE       = "smpte"
+	RangeSMPTE30Drop = "smpte-30-drop"
+	RangeSMPTE25     = "smpte-25"
+	RangeClock       = "clock"
+)
+
+// AggregateControl is the a=control value referring to the URL the
+// description was retrieved from.
+const AggregateControl = "*"
+
+const clockLayout = "20060102T150405.999999999Z"
+
+// Control returns the session-level a=control value, if any.
+func (s *Session) Control() string {
+	control, _ := s.Attributes.Get(ControlAttribute)
+	return strings.TrimSpace(control)
+}
+
+// Control returns the a=control value of the media description, if any.
+func (m *MediaDesc) Control() string {
+	control, _ := m.Attributes.Get(ControlAttribute)
+	return strings.TrimSpace(control)
+}
+
+// ControlURL returns the aggregate control URL of the session: its a=control
+// resolved against base, usually the Content-Base of the DESCRIBE response,
+// or base itself if there is none or it is "*".
+func (s *Session) ControlURL(base *url.URL) (*url.URL, error) {
+	return resolveControl(base, s.Control())
+}
+
+// MediaControlURL returns the control URL of the media description,
+// resolved against the aggregate control URL of the session.
+func (s *Session) MediaControlURL(m *MediaDesc, base *url.URL) (*url.URL, error) {
+	sessionURL, err := s.ControlURL(base)
+	if err != nil {
+		return nil, err
+	}
+	return resolveControl(sessionURL, m.Control())
+}
+
+// resolveControl resolves a control value against base. Like most RTSP
+// clients it treats base as a directory even without a trailing slash, since
+// servers commonly send e.g. "rtsp://camera/stream" with "trackID=1" meaning
+// "rtsp://camera/stream/trackID=1".
+func resolveControl(base *url.URL, control string) (*url.URL, error) {
+	if control == "" || control == AggregateControl {
+		res := *base
+		return &res, nil
+	}
+	ref, err := url.Parse(control)
+	if err != nil {
+		return nil, fmt.Errorf("wrong control format: %v", err)
+	}
+	if ref.IsAbs() {
+		return ref, nil
+	}
+	dir := *base
+	if !strings.HasSuffix(dir.Path, "/") {
+		dir.Path += "/"
+		if dir.RawPath != "" {
+			dir.RawPath += "/"
+		}
+	}
+	return dir.ResolveReference(ref), nil
+}
+
+// Range is the a=range attribute. Start and Stop of npt and smpte ranges are
+// offsets in the media, set if HasStart and HasStop are; an npt range
+// starting at "now" has Now set instead. StartTime and StopTime of clock
+// ranges are absolute times, zero if the range is open on that side.
+type Range struct {
+	Unit      string
+	Start     time.Duration
+	Stop      time.Duration
+	HasStart  bool
+	HasStop   bool
+	Now       bool
+	StartTime time.Time
+	StopTime  time.Time
+}
+
+// ParseRange parses an a=range value, e.g. "npt=0-34.4" or
+// "clock=19961108T142300Z-19961108T143520Z". Parameters after ';' are
+// ignored.
+func ParseRange(value string) (*Range, error) {
+	value, _, _ = strings.Cut(strings.TrimSpace(value), ";")
+	unit, value, ok := strings.Cut(value, "=")
+	if !ok {
+		return nil, fmt.Errorf("wrong range format: %v", value)
+	}
+	start, stop, ok := strings.Cut(value, "-")
+	if !ok || start == "" && stop == "" {
+		return nil, fmt.Errorf("wrong range format: %v", value)
+	}
+
+	res := &Range{Unit: unit, HasStart: start != "", HasStop: stop != ""}
+	var err error
+	switch unit {
+	case RangeNPT:
+		if start == "now" {
+			res.Now, res.HasStart = true, false
+		} else if res.HasStart {
+			res.Start, err = parseNPT(start)
+		}
+		if err == nil && res.HasStop {
+			res.Stop, err = parseNPT(stop)
+		}
+	case RangeSMPTE, RangeSMPTE30Drop, RangeSMPTE25:
+		if res.HasStart {
+			res.Start, err = parseSMPTE(start, smpteFrameRate(unit))
+		}
+		if err == nil && res.HasStop {
+			res.Stop, err = parseSMPTE(stop, smpteFrameRate(unit))
+		}
+	case RangeClock:
+		res.HasStart, res.HasStop = false, false
+		if start != "" {
+			res.StartTime, err = time.Parse(clockLayout, start)
+		}
+		if err == nil && stop != "" {
+			res.StopTime, err = time.Parse(clockLayout, stop)
+		}
+	default:
+		return nil, fmt.Errorf("wrong range format: unknown unit %v", unit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wrong range format: %v", err)
+	}
+	return res, nil
+}
+
+func (r *Range) String() string {
+	var start, stop string
+	switch r.Unit {
+	case RangeNPT:
+		if r.Now {
+			start = "now"
+		} else if r.HasStart {
+			start = formatSeconds(r.Start)
+		}
+		if r.HasStop {
+			stop = formatSeconds(r.Stop)
+		}
+	case RangeClock:
+		if !r.StartTime.IsZero() {
+			start = r.StartTime.UTC().Format(clockLayout)
+		}
+		if !r.StopTime.IsZero() {
+			stop = r.StopTime.UTC().Format(clockLayout)
+		}
+	default:
+		if r.HasStart {
+			start = formatSMPTE(r.Start, smpteFrameRate(r.Unit))
+		}
+		if r.HasStop {
+			stop = formatSMPTE(r.Stop, smpteFrameRate(r.Unit))
+		}
+	}
+	return r.Unit + "=" + start + "-" + stop
+}
+
+// Range returns the session-level a=range attribute, nil if there is none.
+func (s *Session) Range() (*Range, error) {
+	return attributeRange(s.Attributes)
+}
+
+// Range returns the a=range attribute of the media description, nil if there
+// is none.
+func (m *MediaDesc) Range() (*Range, error) {
+	return attributeRange(m.Attributes)
+}
+
+func attributeRange(attributes Attributes) (*Range, error) {
+	value, ok := attributes.Get(RangeAttribute)
+	if !ok {
+		return nil, nil
+	}
+	return ParseRange(value)
+}
+
+// parseNPT parses an npt time in seconds, "34.4", or in hours, minutes and
+// seconds, "0:00:34.4".
+func parseNPT(value string) (time.Duration, error) {
+	fields := strings.Split(value, ":")
+	if len(fields) == 1 {
+		return parseSeconds(value)
+	}
+	if len(fields) != 3 {
+		return 0, fmt.Errorf("wrong npt time: %v", value)
+	}
+	hours, err := strconv.ParseUint(fields[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("wrong npt time: %v", value)
+	}
+	minutes, err := strconv.ParseUint(fields[1], 10, 8)
+	if err != nil || minutes > 59 {
+		return 0, fmt.Errorf("wrong npt time: %v", value)
+	}
+	seconds, err := parseSeconds(fields[2])
+	if err != nil || seconds >= time.Minute {
+		return 0, fmt.Errorf("wrong npt time: %v", value)
+	}
+	rest := time.Duration(minutes)*time.Minute + seconds
+	if hours > uint64((math.MaxInt64-rest)/time.Hour) {
+		return 0, fmt.Errorf("npt time out of range: %v", value)
+	}
+	return time.Duration(hours)*time.Hour + rest, nil
+}
+
+// parseSeconds parses a decimal number of seconds without the rounding of
+// floating point numbers.
+func parseSeconds(value string) (time.Duration, error) {
+	whole, fraction, _ := strings.Cut(value, ".")
+	seconds, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil || len(fraction) > 9 {
+		return 0, fmt.Errorf("wrong seconds: %v", value)
+	}
+	res := time.Duration(seconds) * time.Second
+	if fraction != "" {
+		nanoseconds, err := strconv.ParseUint(fraction+strings.Repeat("0", 9-len(fraction)), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("wrong seconds: %v", value)
+		}
+		res += time.Duration(nanoseconds)
+	}
+	return res, nil
+}
+
+func formatSeconds(d time.Duration) string {
+	res := strconv.FormatInt(int64(d/time.Second), 10)
+	if fraction := d % time.Second; fraction != 0 {
+		res += strings.TrimRight(fmt.Sprintf(".%09d", fraction), "0")
+	}
+	return res
+}
+
+// smpteFrameRate returns the nominal frame rate of an smpte unit; drop-frame
+// time codes are counted at 30 frames per second as well.
+func smpteFrameRate(unit string) int64 {
+	if unit == RangeSMPTE25 {
+		return 25
+	}
+	return 30
+}
+
+// parseSMPTE parses an SMPTE time code, "10:07:33" or with frames and
+// subframes (hundredths of a frame) "10:07:33:05.01".
+func parseSMPTE(value string, frameRate int64) (time.Duration, error) {
+	fields := strings.Split(value, ":")
+	if len(fields) != 3 && len(fields) != 4 {
+		return 0, fmt.Errorf("wrong smpte time: %v", value)
+	}
+	if len(fields) == 4 {
+		frames, subframes, ok := strings.Cut(fields[3], ".")
+		fields[3] = frames
+		if ok {
+			fields = append(fields, subframes)
+		}
+	}
+
+	var values [5]int64
+	limits := [5]int64{99, 59, 59, frameRate - 1, 99}
+	for i, field := range fields {
+		v, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || len(field) > 2 || v < 0 || v > limits[i] {
+			return 0, fmt.Errorf("wrong smpte time: %v", value)
+		}
+		values[i] = v
+	}
+
+	res := time.Duration(values[0])*time.Hour + time.Duration(values[1])*time.Minute + time.Duration(values[2])*time.Second
+	return res + time.Duration(values[3]*100+values[4])*time.Second/time.Duration(frameRate*100), nil
+}
+
+func formatSMPTE(d time.Duration, frameRate int64) string {
+	seconds := int64(d / time.Second)
+	res := fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	// Frames are rounded to hundredths, which is what was parsed.
+	hundredths := (int64(d%time.Second)*frameRate*100 + int64(time.Second)/2) / int64(time.Second)
+	if hundredths == 0 {
+		return res
+	}
+	res += fmt.Sprintf(":%02d", hundredths/100)
+	if hundredths%100 != 0 {
+		res += fmt.Sprintf(".%02d", hundredths%100)
+	}
+	return res
+}
+
+// Framerate returns the a=framerate value of the media description, zero if
+// there is none.
+func (m *MediaDesc) Framerate() (float64, error) {
+	value, ok := m.Attributes.Get(FramerateAttribute)
+	if !ok {
+		return 0, nil
+	}
+	framerate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || framerate < 0 {
+		return 0, fmt.Errorf("wrong framerate format: %v", value)
+	}
+	return framerate, nil
+}
+
+// Dimensions returns the width and height of the a=x-dimensions attribute of
+// the media description, zero if there is none.
+func (m *MediaDesc) Dimensions() (width, height int, err error) {
+	value, ok := m.Attributes.Get(XDimensionsAttribute)
+	if !ok {
+		return 0, 0, nil
+	}
+	w, h, ok := strings.Cut(strings.TrimSpace(value), ",")
+	if ok {
+		width, err = strconv.Atoi(w)
+	}
+	if ok && err == nil {
+		height, err = strconv.Atoi(h)
+	}
+	if !ok || err != nil || width < 0 || height < 0 {
+		return 0, 0, fmt.Errorf("wrong x-dimensions format: %v", value)
+	}
+	return width, height, nil
+}
+
+// Tool returns the a=tool value, the name and version of the tool that
+// created the session description.
+func (s *Session) Tool() string {
+	tool, _ := s.Attributes.Get(ToolAttribute)
+	return strings.TrimSpace(tool)
+}
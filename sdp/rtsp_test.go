@@ -0,0 +1,197 @@
+package sdp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestControlURL(t *testing.T) {
+	tests := []struct {
+		Name           string
+		Base           string
+		SessionControl string
+		MediaControl   string
+		Session        string
+		Media          string
+	}{
+		{
+			Name:           "aggregate",
+			Base:           "rtsp://camera.example.com/stream/",
+			SessionControl: "*",
+			MediaControl:   "trackID=1",
+			Session:        "rtsp://camera.example.com/stream/",
+			Media:          "rtsp://camera.example.com/stream/trackID=1",
+		},
+		{
+			Name:         "base without trailing slash",
+			Base:         "rtsp://192.0.2.10:554/Streaming/Channels/101",
+			MediaControl: "trackID=1",
+			Session:      "rtsp://192.0.2.10:554/Streaming/Channels/101",
+			Media:        "rtsp://192.0.2.10:554/Streaming/Channels/101/trackID=1",
+		},
+		{
+			Name:           "relative session control",
+			Base:           "rtsp://camera.example.com/",
+			SessionControl: "live",
+			MediaControl:   "video",
+			Session:        "rtsp://camera.example.com/live",
+			Media:          "rtsp://camera.example.com/live/video",
+		},
+		{
+			Name:         "absolute media control",
+			Base:         "rtsp://camera.example.com/stream",
+			MediaControl: "rtsp://media.example.com/stream/video",
+			Session:      "rtsp://camera.example.com/stream",
+			Media:        "rtsp://media.example.com/stream/video",
+		},
+		{
+			Name:         "media aggregate control",
+			Base:         "rtsp://camera.example.com/stream",
+			MediaControl: "*",
+			Session:      "rtsp://camera.example.com/stream",
+			Media:        "rtsp://camera.example.com/stream",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			base, err := url.Parse(test.Base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := &Session{MediaDescs: []*MediaDesc{{}}}
+			if test.SessionControl != "" {
+				s.Attributes.Add(ControlAttribute, test.SessionControl)
+			}
+			s.MediaDescs[0].Attributes.Add(ControlAttribute, test.MediaControl)
+
+			sessionURL, err := s.ControlURL(base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sessionURL.String() != test.Session {
+				t.Errorf("wrong session url: %v", sessionURL)
+			}
+			mediaURL, err := s.MediaControlURL(s.MediaDescs[0], base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mediaURL.String() != test.Media {
+				t.Errorf("wrong media url: %v", mediaURL)
+			}
+			if base.String() != test.Base {
+				t.Errorf("base was modified: %v", base)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		Value  string
+		Range  *Range
+		String string
+	}{
+		{Value: "npt=0-", Range: &Range{Unit: RangeNPT, HasStart: true}},
+		{Value: "npt=now-", Range: &Range{Unit: RangeNPT, Now: true}},
+		{Value: "npt=0-34.4", Range: &Range{Unit: RangeNPT, HasStart: true, HasStop: true, Stop: 34400 * time.Millisecond}},
+		{
+			Value:  "npt=0:01:02.25-1:00:00",
+			Range:  &Range{Unit: RangeNPT, HasStart: true, HasStop: true, Start: 62250 * time.Millisecond, Stop: time.Hour},
+			String: "npt=62.25-3600",
+		},
+		{Value: "npt=-20.1", Range: &Range{Unit: RangeNPT, HasStop: true, Stop: 20100 * time.Millisecond}},
+		{
+			Value: "smpte=10:07:00-10:07:33:05.01",
+			Range: &Range{Unit: RangeSMPTE, HasStart: true, HasStop: true,
+				Start: 10*time.Hour + 7*time.Minute, Stop: 10*time.Hour + 7*time.Minute + 33*time.Second + 501*time.Second/3000},
+		},
+		{
+			Value: "smpte-25=0:00:01:12-",
+			Range: &Range{Unit: RangeSMPTE25, HasStart: true, Start: time.Second + 12*time.Second/25},
+		},
+		{
+			Value: "clock=19961108T142300Z-19961108T143520.5Z",
+			Range: &Range{Unit: RangeClock,
+				StartTime: time.Date(1996, time.November, 8, 14, 23, 0, 0, time.UTC),
+				StopTime:  time.Date(1996, time.November, 8, 14, 35, 20, 500000000, time.UTC)},
+		},
+		{
+			Value:  "npt=5-;time=19970123T143720Z",
+			Range:  &Range{Unit: RangeNPT, HasStart: true, Start: 5 * time.Second},
+			String: "npt=5-",
+		},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRange(test.Value)
+		if err != nil {
+			t.Errorf("%v: %v", test.Value, err)
+			continue
+		}
+		if !cmp.Equal(r, test.Range) {
+			t.Errorf("%v: %v", test.Value, cmp.Diff(r, test.Range))
+		}
+		expected := test.String
+		if expected == "" {
+			expected = test.Value
+		}
+		if r.String() != expected {
+			t.Errorf("%v formatted as %v", test.Value, r.String())
+		}
+	}
+
+	for _, value := range []string{"", "npt", "npt=-", "npt=a-", "npt=1:2-", "npt=0:60:00-",
+		"npt=5000000:00:00-", "npt=2562047:59:59-",
+		"smpte=10:07-", "smpte=10:07:00:30-", "smpte-25=10:07:00:25-", "clock=1996-", "frames=0-10"} {
+		if _, err := ParseRange(value); err == nil {
+			t.Errorf("%v: expected error", value)
+		}
+	}
+}
+
+func TestRTSPAttributes(t *testing.T) {
+	s := &Session{MediaDescs: []*MediaDesc{{}}}
+	m := s.MediaDescs[0]
+	if r, err := s.Range(); r != nil || err != nil {
+		t.Fatalf("unexpected range: %v, %v", r, err)
+	}
+	if framerate, err := m.Framerate(); framerate != 0 || err != nil {
+		t.Fatalf("unexpected framerate: %v, %v", framerate, err)
+	}
+
+	s.Attributes.Add(ToolAttribute, " LIVE555 Streaming Media v2020.01.01 ")
+	s.Attributes.Add(RangeAttribute, "npt=0-")
+	m.Attributes.Add(FramerateAttribute, "29.97")
+	m.Attributes.Add(XDimensionsAttribute, "1920,1080")
+	m.Attributes.Add(RangeAttribute, "npt=0-10")
+
+	if tool := s.Tool(); tool != "LIVE555 Streaming Media v2020.01.01" {
+		t.Errorf("wrong tool: %v", tool)
+	}
+	if r, err := s.Range(); err != nil || r.HasStop {
+		t.Errorf("wrong session range: %v, %v", r, err)
+	}
+	if r, err := m.Range(); err != nil || r.Stop != 10*time.Second {
+		t.Errorf("wrong media range: %v, %v", r, err)
+	}
+	if framerate, err := m.Framerate(); framerate != 29.97 || err != nil {
+		t.Errorf("wrong framerate: %v, %v", framerate, err)
+	}
+	if width, height, err := m.Dimensions(); width != 1920 || height != 1080 || err != nil {
+		t.Errorf("wrong dimensions: %v, %v, %v", width, height, err)
+	}
+
+	m.Attributes.Set(FramerateAttribute, "fast")
+	m.Attributes.Set(XDimensionsAttribute, "1920x1080")
+	if _, err := m.Framerate(); err == nil {
+		t.Error("framerate: expected error")
+	}
+	if _, _, err := m.Dimensions(); err == nil {
+		t.Error("dimensions: expected error")
+	}
+}
@@ -0,0 +1,125 @@
+package sdp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+//go:generate sh -c "go run ../cmd/sdptool schema > session.schema.json"
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonShapes maps types with custom JSON marshalers to the types describing
+// their JSON form.
+var jsonShapes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(Attribute{}):     reflect.TypeOf(attributeJSON{}),
+	reflect.TypeOf(EncryptionKey{}): reflect.TypeOf(encryptionKeyJSON{}),
+	reflect.TypeOf(ExtensionLine{}): reflect.TypeOf(extensionLineJSON{}),
+	reflect.TypeOf(MediaDesc{}):     reflect.TypeOf(mediaDescJSON{}),
+}
+
+// JSONSchema returns the JSON Schema document of the JSON form of Session.
+// The session.schema.json file in this package is generated from it.
+func JSONSchema() ([]byte, error) {
+	g := &schemaGenerator{defs: make(map[string]interface{})}
+	root := g.schema(reflect.TypeOf(Session{}))
+
+	schema := map[string]interface{}{
+		"$schema": jsonSchemaDialect,
+		"title":   "SDP session description",
+		"$defs":   g.defs,
+	}
+	for key, value := range root {
+		schema[key] = value
+	}
+
+	res, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(res, '\n'), nil
+}
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil
+			shape := t
+			if jsonShape, ok := jsonShapes[t]; ok {
+				shape = jsonShape
+			}
+			g.defs[name] = g.object(shape)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	g.fields(t, properties, &required)
+
+	res := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		res["required"] = required
+	}
+	return res
+}
+
+func (g *schemaGenerator) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && fieldType.Kind() == reflect.Struct {
+			g.fields(fieldType, properties, required)
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		name := options[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := g.schema(field.Type)
+		if inSet("string", options[1:]) {
+			schema = map[string]interface{}{"type": "string", "pattern": "^-?[0-9]+$"}
+		}
+		if field.Tag.Get("jsonschema") == "readonly" {
+			schema["readOnly"] = true
+		} else if !inSet("omitempty", options[1:]) {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
@@ -2,42 +2,42 @@
 package sdp
 
 type Origin struct {
-	Username       string
-	SessID         int64
-	SessVersion    int64
-	Nettype        string
-	Addrtype       string
-	UnicastAddress string
+	Username       string `json:"username"`
+	SessID         int64  `json:"sessionId,string"`
+	SessVersion    int64  `json:"sessionVersion,string"`
+	Nettype        string `json:"netType"`
+	Addrtype       string `json:"addrType"`
+	UnicastAddress string `json:"address"`
 }
 
 type Connection struct {
-	Nettype        string
-	Addrtype       string
-	ConnectionAddr string
-	TTL            int64
-	AddressesNum   int64
+	Nettype        string `json:"netType"`
+	Addrtype       string `json:"addrType"`
+	ConnectionAddr string `json:"address"`
+	TTL            int64  `json:"ttl,omitempty"`
+	AddressesNum   int64  `json:"addressCount"`
 }
 
 type Bandwidth struct {
-	Type  string
-	Value int
+	Type  string `json:"type"`
+	Value int    `json:"value"`
 }
 
 type Timing struct {
-	Start       int64
-	Stop        int64
-	RepeatTimes []*RepeatTime
+	Start       int64         `json:"start"`
+	Stop        int64         `json:"stop"`
+	RepeatTimes []*RepeatTime `json:"repeatTimes,omitempty"`
 }
 
 type RepeatTime struct {
-	Interval int64
-	Duration int64
-	Offsets  []int64
+	Interval int64   `json:"interval"`
+	Duration int64   `json:"duration"`
+	Offsets  []int64 `json:"offsets"`
 }
 
 type TimeZone struct {
-	Time   int64
-	Offset int64
+	Time   int64 `json:"time"`
+	Offset int64 `json:"offset"`
 }
 
 type EncryptionKey struct {
@@ -50,34 +50,49 @@ type Attribute struct {
 	Value string
 }
 
+// ExtensionLine is a line of a type this package does not interpret, e.g.
+// the 3GPP y= line or the obsolete f= line, kept so that it is written back.
+// After is the type of the line it followed, and the Encoder writes it after
+// the last line of that type; if After is zero it is written at the end of
+// the session or media section.
+type ExtensionLine struct {
+	Type  byte
+	Value string
+	After byte
+}
+
 type MediaDesc struct {
-	Media          string
-	Information    string
-	Port           int64
-	PortsNum       int64
-	Proto          []string
-	Fmts           []string
-	Attributes     []*Attribute
-	Bandwidths     []*Bandwidth
-	Connections    []*Connection
-	EncryptionKeys []*EncryptionKey
+	Media          string           `json:"media"`
+	Information    string           `json:"information,omitempty"`
+	Port           int64            `json:"port"`
+	PortsNum       int64            `json:"portCount"`
+	Proto          []string         `json:"proto"`
+	Fmts           []string         `json:"formats"`
+	Attributes     Attributes       `json:"attributes,omitempty"`
+	Bandwidths     []*Bandwidth     `json:"bandwidths,omitempty"`
+	Connections    []*Connection    `json:"connections,omitempty"`
+	EncryptionKeys []*EncryptionKey `json:"encryptionKeys,omitempty"`
+	ExtensionLines []*ExtensionLine `json:"extensionLines,omitempty"`
 }
 
 type Session struct {
-	Version        int
-	Information    string
-	Originator     *Origin
-	SessionName    string
-	URI            string
-	Emails         []string
-	PhoneNumbers   []string
-	ConnectionData *Connection
-	Bandwidths     []*Bandwidth
-	Timings        []*Timing
-	TimeZones      []*TimeZone
-	EncryptionKeys []*EncryptionKey
-	Attributes     []*Attribute
-	MediaDescs     []*MediaDesc
+	Version        int              `json:"version"`
+	Information    string           `json:"information,omitempty"`
+	Originator     *Origin          `json:"origin"`
+	SessionName    string           `json:"sessionName"`
+	URI            string           `json:"uri,omitempty"`
+	Emails         []string         `json:"emails,omitempty"`
+	PhoneNumbers   []string         `json:"phoneNumbers,omitempty"`
+	ConnectionData *Connection      `json:"connection,omitempty"`
+	Bandwidths     []*Bandwidth     `json:"bandwidths,omitempty"`
+	Timings        []*Timing        `json:"timings,omitempty"`
+	TimeZones      []*TimeZone      `json:"timeZones,omitempty"`
+	EncryptionKeys []*EncryptionKey `json:"encryptionKeys,omitempty"`
+	Attributes     Attributes       `json:"attributes,omitempty"`
+	MediaDescs     []*MediaDesc     `json:"media,omitempty"`
+	ExtensionLines []*ExtensionLine `json:"extensionLines,omitempty"`
+	// Raw is set by a Decoder in raw line mode.
+	Raw *RawLines `json:"-"`
 }
 
 const (
@@ -126,4 +141,5 @@ const (
 	AVPFproto  = "AVPF"
 	TCPproto   = "TCP"
 	MSRPproto  = "MSRP"
+	UDPTLproto = "udptl"
 )
@@ -0,0 +1,175 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	SSRCAttribute      = "ssrc"
+	SSRCGroupAttribute = "ssrc-group"
+	MsidAttribute      = "msid"
+)
+
+const (
+	SendRecvAttribute = "sendrecv"
+	SendOnlyAttribute = "sendonly"
+	RecvOnlyAttribute = "recvonly"
+	InactiveAttribute = "inactive"
+)
+
+// SourceAttribute is a media-level attribute of a single RTP source
+// (RFC 5576), e.g. "a=ssrc:1234 cname:user@example.com".
+type SourceAttribute struct {
+	SSRC  uint32
+	Name  string
+	Value string
+}
+
+func (a *SourceAttribute) String() string {
+	res := strconv.FormatUint(uint64(a.SSRC), 10) + " " + a.Name
+	if a.Value != "" {
+		res += ":" + a.Value
+	}
+	return res
+}
+
+// SourceGroup is a grouping of RTP sources (RFC 5576), e.g.
+// "a=ssrc-group:FID 1234 5678".
+type SourceGroup struct {
+	Semantics string
+	SSRCs     []uint32
+}
+
+func (g *SourceGroup) String() string {
+	fields := []string{g.Semantics}
+	for _, ssrc := range g.SSRCs {
+		fields = append(fields, strconv.FormatUint(uint64(ssrc), 10))
+	}
+	return strings.Join(fields, " ")
+}
+
+// Msid is the media stream and track identification (RFC 8830).
+type Msid struct {
+	Stream string `json:"stream"`
+	Track  string `json:"track,omitempty"`
+}
+
+func (m *Msid) String() string {
+	if m.Track == "" {
+		return m.Stream
+	}
+	return m.Stream + " " + m.Track
+}
+
+func parseMsid(value string) *Msid {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil
+	}
+	msid := &Msid{Stream: fields[0]}
+	if len(fields) > 1 {
+		msid.Track = fields[1]
+	}
+	return msid
+}
+
+func parseSSRC(value string) (uint32, error) {
+	ssrc, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("wrong ssrc: %v", value)
+	}
+	return uint32(ssrc), nil
+}
+
+func parseSourceAttribute(value string) (*SourceAttribute, error) {
+	fields := strings.SplitN(value, " ", 2)
+	ssrc, err := parseSSRC(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 1 || fields[1] == "" {
+		return nil, fmt.Errorf("wrong ssrc attribute format: %v", value)
+	}
+	attribute := &SourceAttribute{SSRC: ssrc, Name: fields[1]}
+	if index := strings.IndexByte(fields[1], ':'); index >= 0 {
+		attribute.Name, attribute.Value = fields[1][:index], fields[1][index+1:]
+	}
+	return attribute, nil
+}
+
+func parseSourceGroup(value string) (*SourceGroup, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("wrong ssrc-group format: %v", value)
+	}
+	group := &SourceGroup{Semantics: fields[0]}
+	for _, field := range fields[1:] {
+		ssrc, err := parseSSRC(field)
+		if err != nil {
+			return nil, err
+		}
+		group.SSRCs = append(group.SSRCs, ssrc)
+	}
+	return group, nil
+}
+
+// SourceAttributes returns the a=ssrc attributes of the media description.
+func (m *MediaDesc) SourceAttributes() ([]*SourceAttribute, error) {
+	var res []*SourceAttribute
+	for _, value := range m.Attributes.GetAll(SSRCAttribute) {
+		source, err := parseSourceAttribute(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, source)
+	}
+	return res, nil
+}
+
+// SourceGroups returns the a=ssrc-group attributes of the media description.
+func (m *MediaDesc) SourceGroups() ([]*SourceGroup, error) {
+	var res []*SourceGroup
+	for _, value := range m.Attributes.GetAll(SSRCGroupAttribute) {
+		group, err := parseSourceGroup(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, group)
+	}
+	return res, nil
+}
+
+// Msid returns the a=msid attribute of the media description, if any.
+func (m *MediaDesc) Msid() *Msid {
+	if value, ok := m.Attributes.Get(MsidAttribute); ok {
+		return parseMsid(value)
+	}
+	return nil
+}
+
+// Direction returns the direction attribute of the media description,
+// falling back to the session-level one and then to sendrecv.
+func (s *Session) Direction(m *MediaDesc) string {
+	if direction := findDirection(m.Attributes); direction != "" {
+		return direction
+	}
+	if direction := findDirection(s.Attributes); direction != "" {
+		return direction
+	}
+	return SendRecvAttribute
+}
+
+func findDirection(attributes Attributes) string {
+	for _, attribute := range attributes {
+		if isDirection(attribute.Name) {
+			return attribute.Name
+		}
+	}
+	return ""
+}
+
+func isDirection(name string) bool {
+	return inSet(name, []string{SendRecvAttribute, SendOnlyAttribute, RecvOnlyAttribute, InactiveAttribute})
+}
@@ -0,0 +1,219 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Media type and format of T.38 fax media descriptions
+// (m=image <port> udptl t38).
+const (
+	ImageMedia = "image"
+	T38Format  = "t38"
+)
+
+// Attributes of T.38 fax media descriptions (ITU-T T.38 Annex D).
+const (
+	T38FaxVersionAttribute         = "T38FaxVersion"
+	T38MaxBitRateAttribute         = "T38MaxBitRate"
+	T38FaxFillBitRemovalAttribute  = "T38FaxFillBitRemoval"
+	T38FaxTranscodingMMRAttribute  = "T38FaxTranscodingMMR"
+	T38FaxTranscodingJBIGAttribute = "T38FaxTranscodingJBIG"
+	T38FaxRateManagementAttribute  = "T38FaxRateManagement"
+	T38FaxMaxBufferAttribute       = "T38FaxMaxBuffer"
+	T38FaxMaxDatagramAttribute     = "T38FaxMaxDatagram"
+	T38FaxUDPECAttribute           = "T38FaxUdpEC"
+)
+
+// Values of a=T38FaxRateManagement.
+const (
+	T38LocalTCF       = "localTCF"
+	T38TransferredTCF = "transferredTCF"
+)
+
+// Values of a=T38FaxUdpEC.
+const (
+	T38UDPNoEC       = "t38UDPNoEC"
+	T38UDPRedundancy = "t38UDPRedundancy"
+	T38UDPFEC        = "t38UDPFEC"
+)
+
+var t38Attributes = []string{
+	T38FaxVersionAttribute,
+	T38MaxBitRateAttribute,
+	T38FaxFillBitRemovalAttribute,
+	T38FaxTranscodingMMRAttribute,
+	T38FaxTranscodingJBIGAttribute,
+	T38FaxRateManagementAttribute,
+	T38FaxMaxBufferAttribute,
+	T38FaxMaxDatagramAttribute,
+	T38FaxUDPECAttribute,
+}
+
+// T38Params is the typed view of the T.38 attributes of a media description.
+// MaxBitRate, MaxBuffer and MaxDatagram are zero and UDPEC is empty if the
+// attribute is absent.
+type T38Params struct {
+	Version         int
+	MaxBitRate      int
+	FillBitRemoval  bool
+	TranscodingMMR  bool
+	TranscodingJBIG bool
+	RateManagement  string
+	MaxBuffer       int
+	MaxDatagram     int
+	UDPEC           string
+}
+
+// IsT38 reports whether the media description is a T.38 fax stream.
+func (m *MediaDesc) IsT38() bool {
+	return m.Media == ImageMedia && inSet(T38Format, m.Fmts)
+}
+
+// T38Params returns the T.38 attributes of the media description.
+func (m *MediaDesc) T38Params() (*T38Params, error) {
+	var params T38Params
+
+	for _, attribute := range []struct {
+		name    string
+		integer *int
+		flag    *bool
+	}{
+		{name: T38FaxVersionAttribute, integer: &params.Version},
+		{name: T38MaxBitRateAttribute, integer: &params.MaxBitRate},
+		{name: T38FaxFillBitRemovalAttribute, flag: &params.FillBitRemoval},
+		{name: T38FaxTranscodingMMRAttribute, flag: &params.TranscodingMMR},
+		{name: T38FaxTranscodingJBIGAttribute, flag: &params.TranscodingJBIG},
+		{name: T38FaxMaxBufferAttribute, integer: &params.MaxBuffer},
+		{name: T38FaxMaxDatagramAttribute, integer: &params.MaxDatagram},
+	} {
+		value, ok := m.Attributes.Get(attribute.name)
+		if !ok {
+			continue
+		}
+		if attribute.integer != nil {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("wrong %v format: %v", attribute.name, value)
+			}
+			*attribute.integer = n
+			continue
+		}
+		// Early implementations send the flags with a value of 0 or 1.
+		switch strings.TrimSpace(value) {
+		case "", "1":
+			*attribute.flag = true
+		case "0":
+		default:
+			return nil, fmt.Errorf("wrong %v format: %v", attribute.name, value)
+		}
+	}
+
+	params.RateManagement, _ = m.Attributes.Get(T38FaxRateManagementAttribute)
+	params.RateManagement = strings.TrimSpace(params.RateManagement)
+	params.UDPEC, _ = m.Attributes.Get(T38FaxUDPECAttribute)
+	params.UDPEC = strings.TrimSpace(params.UDPEC)
+
+	return &params, nil
+}
+
+// SetT38Params replaces the T.38 attributes of the media description.
+func (m *MediaDesc) SetT38Params(p *T38Params) {
+	m.Attributes.Remove(t38Attributes...)
+	m.Attributes = append(m.Attributes, p.Attributes()...)
+}
+
+// Attributes returns the T.38 attributes in the order of T.38 Annex D.
+func (p *T38Params) Attributes() Attributes {
+	var res Attributes
+	res.Add(T38FaxVersionAttribute, strconv.Itoa(p.Version))
+	if p.MaxBitRate > 0 {
+		res.Add(T38MaxBitRateAttribute, strconv.Itoa(p.MaxBitRate))
+	}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{T38FaxFillBitRemovalAttribute, p.FillBitRemoval},
+		{T38FaxTranscodingMMRAttribute, p.TranscodingMMR},
+		{T38FaxTranscodingJBIGAttribute, p.TranscodingJBIG},
+	} {
+		if flag.set {
+			res.Add(flag.name, "")
+		}
+	}
+	if p.RateManagement != "" {
+		res.Add(T38FaxRateManagementAttribute, p.RateManagement)
+	}
+	if p.MaxBuffer > 0 {
+		res.Add(T38FaxMaxBufferAttribute, strconv.Itoa(p.MaxBuffer))
+	}
+	if p.MaxDatagram > 0 {
+		res.Add(T38FaxMaxDatagramAttribute, strconv.Itoa(p.MaxDatagram))
+	}
+	if p.UDPEC != "" {
+		res.Add(T38FaxUDPECAttribute, p.UDPEC)
+	}
+	return res
+}
+
+// NewT38MediaDesc returns an m=image <port> udptl t38 media description.
+func NewT38MediaDesc(port int64, p *T38Params) *MediaDesc {
+	m := &MediaDesc{
+		Media:    ImageMedia,
+		Port:     port,
+		PortsNum: 1,
+		Proto:    []string{UDPTLproto},
+		Fmts:     []string{T38Format},
+	}
+	m.SetT38Params(p)
+	return m
+}
+
+// t38ECSchemes are the error correction schemes in order, a peer supporting
+// one is assumed to support the ones before it. Unknown schemes are taken as
+// no error correction.
+var t38ECSchemes = []string{"", T38UDPRedundancy, T38UDPFEC}
+
+func t38ECLevel(scheme string) int {
+	for i, value := range t38ECSchemes {
+		if value == scheme {
+			return i
+		}
+	}
+	return 0
+}
+
+// NegotiateT38 builds the answer parameters for offered T.38 parameters
+// given the local capabilities. The version, bit rate, transcoding options
+// and error correction are lowered to what both sides support, the rate
+// management is the offered one, as T.38 requires, even if the local side
+// prefers another, and MaxBuffer and MaxDatagram are the local ones, since
+// they describe the receiver.
+func NegotiateT38(offer, local *T38Params) *T38Params {
+	answer := *local
+	if offer.Version < answer.Version {
+		answer.Version = offer.Version
+	}
+	if offer.MaxBitRate > 0 && (answer.MaxBitRate == 0 || offer.MaxBitRate < answer.MaxBitRate) {
+		answer.MaxBitRate = offer.MaxBitRate
+	}
+	answer.FillBitRemoval = offer.FillBitRemoval && local.FillBitRemoval
+	answer.TranscodingMMR = offer.TranscodingMMR && local.TranscodingMMR
+	answer.TranscodingJBIG = offer.TranscodingJBIG && local.TranscodingJBIG
+	if offer.RateManagement != "" {
+		answer.RateManagement = offer.RateManagement
+	}
+
+	level := t38ECLevel(offer.UDPEC)
+	if localLevel := t38ECLevel(local.UDPEC); localLevel < level {
+		level = localLevel
+	}
+	answer.UDPEC = t38ECSchemes[level]
+	if level == 0 && (offer.UDPEC == T38UDPNoEC || local.UDPEC == T38UDPNoEC) {
+		answer.UDPEC = T38UDPNoEC
+	}
+
+	return &answer
+}
@@ -0,0 +1,129 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const t38Offer = "v=0\r\n" +
+	"o=- 1 2 IN IP4 192.0.2.1\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 192.0.2.1\r\n" +
+	"t=0 0\r\n" +
+	"m=image 4000 udptl t38\r\n" +
+	"a=T38FaxVersion:0\r\n" +
+	"a=T38MaxBitRate:14400\r\n" +
+	"a=T38FaxFillBitRemoval:0\r\n" +
+	"a=T38FaxTranscodingMMR\r\n" +
+	"a=T38FaxRateManagement:transferredTCF\r\n" +
+	"a=T38FaxMaxBuffer:262\r\n" +
+	"a=T38FaxMaxDatagram:176\r\n" +
+	"a=T38FaxUdpEC:t38UDPRedundancy\r\n"
+
+func TestT38Params(t *testing.T) {
+	s, err := NewDecoder(strings.NewReader(t38Offer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := s.MediaDescs[0]
+	if !m.IsT38() {
+		t.Fatal("not t38")
+	}
+	params, err := m.T38Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &T38Params{
+		MaxBitRate:     14400,
+		TranscodingMMR: true,
+		RateManagement: T38TransferredTCF,
+		MaxBuffer:      262,
+		MaxDatagram:    176,
+		UDPEC:          T38UDPRedundancy,
+	}
+	if diff := cmp.Diff(expected, params); diff != "" {
+		t.Fatal(diff)
+	}
+
+	s.MediaDescs[0] = NewT38MediaDesc(5000, params)
+	data, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedText := "m=image 5000/1 udptl t38\n" +
+		"a=T38FaxVersion:0\n" +
+		"a=T38MaxBitRate:14400\n" +
+		"a=T38FaxTranscodingMMR\n" +
+		"a=T38FaxRateManagement:transferredTCF\n" +
+		"a=T38FaxMaxBuffer:262\n" +
+		"a=T38FaxMaxDatagram:176\n" +
+		"a=T38FaxUdpEC:t38UDPRedundancy\n"
+	if !strings.HasSuffix(string(data), expectedText) {
+		t.Fatalf("wrong media description:\n%s", data)
+	}
+
+	for _, v := range []struct {
+		attributes []string
+		err        string
+	}{
+		{[]string{"T38FaxVersion:x"}, "wrong T38FaxVersion format: x"},
+		{[]string{"T38MaxBitRate:-1"}, "wrong T38MaxBitRate format: -1"},
+		{[]string{"T38FaxTranscodingJBIG:yes"}, "wrong T38FaxTranscodingJBIG format: yes"},
+		{[]string{"T38FaxMaxDatagram:x", "T38FaxFillBitRemoval:x", "T38MaxBitRate:x"}, "wrong T38MaxBitRate format: x"},
+	} {
+		m := &MediaDesc{}
+		for _, attribute := range v.attributes {
+			name, value, _ := strings.Cut(attribute, ":")
+			m.Attributes.Add(name, value)
+		}
+		if _, err := m.T38Params(); err == nil || err.Error() != v.err {
+			t.Fatalf("%v: wrong error: %v", v.attributes, err)
+		}
+	}
+}
+
+func TestNegotiateT38(t *testing.T) {
+	tests := []struct {
+		name     string
+		offer    T38Params
+		local    T38Params
+		expected T38Params
+	}{
+		{
+			name: "lower",
+			offer: T38Params{Version: 3, MaxBitRate: 33600, FillBitRemoval: true, TranscodingJBIG: true,
+				RateManagement: T38TransferredTCF, MaxBuffer: 2000, MaxDatagram: 1400, UDPEC: T38UDPFEC},
+			local: T38Params{Version: 0, MaxBitRate: 14400, TranscodingJBIG: true,
+				MaxBuffer: 262, MaxDatagram: 176, UDPEC: T38UDPRedundancy},
+			expected: T38Params{Version: 0, MaxBitRate: 14400, TranscodingJBIG: true,
+				RateManagement: T38TransferredTCF, MaxBuffer: 262, MaxDatagram: 176, UDPEC: T38UDPRedundancy},
+		},
+		{
+			name:     "offer lower",
+			offer:    T38Params{Version: 0, MaxBitRate: 9600, UDPEC: T38UDPNoEC},
+			local:    T38Params{Version: 2, MaxBitRate: 14400, FillBitRemoval: true, UDPEC: T38UDPFEC},
+			expected: T38Params{Version: 0, MaxBitRate: 9600, UDPEC: T38UDPNoEC},
+		},
+		{
+			name:     "no bit rate",
+			offer:    T38Params{Version: 1, RateManagement: T38LocalTCF, UDPEC: T38UDPRedundancy},
+			local:    T38Params{Version: 1, MaxBitRate: 14400, RateManagement: T38LocalTCF, UDPEC: T38UDPFEC},
+			expected: T38Params{Version: 1, MaxBitRate: 14400, RateManagement: T38LocalTCF, UDPEC: T38UDPRedundancy},
+		},
+		{
+			name:     "offered rate management",
+			offer:    T38Params{RateManagement: T38TransferredTCF},
+			local:    T38Params{RateManagement: T38LocalTCF, UDPEC: T38UDPRedundancy},
+			expected: T38Params{RateManagement: T38TransferredTCF},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := cmp.Diff(&test.expected, NegotiateT38(&test.offer, &test.local)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
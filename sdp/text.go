@@ -0,0 +1,240 @@
+package sdp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+// The text form of a session and a media description is their complete SDP
+// text. The text form of every other type is the value of its line, without
+// the "x=" prefix and the line ending, e.g. "IN IP4 224.2.1.1/127/1" for a
+// Connection.
+
+func marshalText(write func(e *Encoder)) []byte {
+	var e Encoder
+	write(&e)
+	return e.buf
+}
+
+func (s *Session) MarshalText() ([]byte, error) {
+	return Marshal(s)
+}
+
+func (s *Session) UnmarshalText(text []byte) error {
+	res, err := NewDecoder(bytes.NewReader(text)).Decode()
+	if err != nil {
+		return err
+	}
+	*s = *res
+	return nil
+}
+
+// String returns the text of the session, without the o= line if there is
+// no originator.
+func (s *Session) String() string {
+	return string(AppendSDP(nil, s))
+}
+
+// MarshalText returns the m= line followed by the lines of the media
+// description.
+func (m *MediaDesc) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.encodeMediaDesc(m) }), nil
+}
+
+// UnmarshalText parses a single media description, which must start with its
+// m= line.
+func (m *MediaDesc) UnmarshalText(text []byte) error {
+	d := NewDecoder(nil)
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if lineNum > 1 {
+			if isExtensionLine(line) {
+				if err := d.parseExtensionLine(line); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.parseMediaLine(line, lineNum); err != nil {
+				return err
+			}
+			d.lastField = line[0]
+			continue
+		}
+		if len(line) < 2 || line[0] != MediaDescField || line[1] != '=' {
+			return fmt.Errorf("wrong media discription format: no media line")
+		}
+		if err := parseMediaDesc(line[2:], nextMediaDesc(&d.s.MediaDescs)); err != nil {
+			return err
+		}
+		d.lastField = MediaDescField
+	}
+
+	if scanner.Err() != nil {
+		return fmt.Errorf("error while reading media description: %v", scanner.Err())
+	}
+	if d.s.MediaDescs == nil {
+		return fmt.Errorf("wrong media discription format: no media line")
+	}
+
+	*m = *d.s.MediaDescs[0]
+	return nil
+}
+
+func (m *MediaDesc) String() string {
+	text, _ := m.MarshalText()
+	return string(text)
+}
+
+func (o *Origin) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeOriginator(o) }), nil
+}
+
+func (o *Origin) UnmarshalText(text []byte) error {
+	var res Origin
+	if err := parseOriginator(string(text), &res); err != nil {
+		return err
+	}
+	*o = res
+	return nil
+}
+
+func (o *Origin) String() string {
+	text, _ := o.MarshalText()
+	return string(text)
+}
+
+func (c *Connection) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeConnection(c) }), nil
+}
+
+func (c *Connection) UnmarshalText(text []byte) error {
+	var res Connection
+	if err := parseConnection(string(text), &res); err != nil {
+		return err
+	}
+	*c = res
+	return nil
+}
+
+func (c *Connection) String() string {
+	text, _ := c.MarshalText()
+	return string(text)
+}
+
+func (b *Bandwidth) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeBandwidth(b) }), nil
+}
+
+func (b *Bandwidth) UnmarshalText(text []byte) error {
+	var res Bandwidth
+	if err := parseBandwidth(string(text), &res); err != nil {
+		return err
+	}
+	*b = res
+	return nil
+}
+
+func (b *Bandwidth) String() string {
+	text, _ := b.MarshalText()
+	return string(text)
+}
+
+// MarshalText returns the t= line value. Repeat times are separate r= lines
+// and are not part of it.
+func (t *Timing) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeTiming(t) }), nil
+}
+
+// UnmarshalText parses a t= line value. It clears the repeat times.
+func (t *Timing) UnmarshalText(text []byte) error {
+	var res Timing
+	if err := parseTiming(string(text), &res); err != nil {
+		return err
+	}
+	*t = res
+	return nil
+}
+
+func (t *Timing) String() string {
+	text, _ := t.MarshalText()
+	return string(text)
+}
+
+func (r *RepeatTime) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeRepeatTime(r) }), nil
+}
+
+func (r *RepeatTime) UnmarshalText(text []byte) error {
+	var res RepeatTime
+	if err := parseRepeatTime(string(text), &res); err != nil {
+		return err
+	}
+	*r = res
+	return nil
+}
+
+func (r *RepeatTime) String() string {
+	text, _ := r.MarshalText()
+	return string(text)
+}
+
+// MarshalText returns a single adjustment of a z= line, e.g. "2882844526 -1h"
+// is returned as "2882844526 -3600".
+func (z *TimeZone) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeTimeZone(z) }), nil
+}
+
+// UnmarshalText parses a single adjustment of a z= line.
+func (z *TimeZone) UnmarshalText(text []byte) error {
+	res, err := parseTimeZones(string(text), nil)
+	if err != nil {
+		return err
+	}
+	if len(res) != 1 {
+		return fmt.Errorf("wrong time zone format")
+	}
+	*z = *res[0]
+	return nil
+}
+
+func (z *TimeZone) String() string {
+	text, _ := z.MarshalText()
+	return string(text)
+}
+
+func (k *EncryptionKey) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeEncryptionKey(k) }), nil
+}
+
+func (k *EncryptionKey) UnmarshalText(text []byte) error {
+	var res EncryptionKey
+	parseEncryptionKey(string(text), &res)
+	*k = res
+	return nil
+}
+
+func (k *EncryptionKey) String() string {
+	text, _ := k.MarshalText()
+	return string(text)
+}
+
+func (a *Attribute) MarshalText() ([]byte, error) {
+	return marshalText(func(e *Encoder) { e.writeAttribute(a) }), nil
+}
+
+func (a *Attribute) UnmarshalText(text []byte) error {
+	var res Attribute
+	if err := parseAttribute(string(text), &res); err != nil {
+		return err
+	}
+	*a = res
+	return nil
+}
+
+func (a *Attribute) String() string {
+	text, _ := a.MarshalText()
+	return string(text)
+}
@@ -0,0 +1,114 @@
+package sdp
+
+import (
+	"encoding"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type textValue interface {
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+	String() string
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Text  string
+		Value textValue
+	}{
+		{Name: "origin", Text: "jdoe 2890844526 2890842807 IN IP4 10.47.16.5", Value: &Origin{}},
+		{Name: "connection", Text: "IN IP4 224.2.17.12/127/2", Value: &Connection{}},
+		{Name: "bandwidth", Text: "AS:2000", Value: &Bandwidth{}},
+		{Name: "timing", Text: "2873397496 2873404696", Value: &Timing{}},
+		{Name: "repeat time", Text: "604800 3600 0 90000", Value: &RepeatTime{}},
+		{Name: "time zone", Text: "2882844526 -3600", Value: &TimeZone{}},
+		{Name: "encryption key", Text: "clear:secret", Value: &EncryptionKey{}},
+		{Name: "encryption key without value", Text: "prompt", Value: &EncryptionKey{}},
+		{Name: "attribute", Text: "rtpmap:99 h263-1998/90000", Value: &Attribute{}},
+		{Name: "property attribute", Text: "recvonly", Value: &Attribute{}},
+		{
+			Name:  "media description",
+			Text:  "m=video 51372/1 RTP/AVP 99\nc=IN IP4 224.2.17.12/127/1\na=rtpmap:99 h263-1998/90000\n",
+			Value: &MediaDesc{},
+		},
+		{Name: "media description with extension line", Text: "m=audio 9/1 RTP/AVP 0\ny=custom\na=sendrecv\n", Value: &MediaDesc{}},
+		{Name: "session", Text: marshalTests[0].Data, Value: &Session{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			if err := test.Value.UnmarshalText([]byte(test.Text)); err != nil {
+				t.Fatal(err)
+			}
+			text, err := test.Value.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(text) != test.Text {
+				t.Fatal(cmp.Diff(string(text), test.Text))
+			}
+			if test.Value.String() != test.Text {
+				t.Fatalf("wrong string: %v", test.Value.String())
+			}
+		})
+	}
+}
+
+func TestUnmarshalTextErrors(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Text  string
+		Value encoding.TextUnmarshaler
+	}{
+		{Name: "origin", Text: "jdoe 2890844526 IN IP4 10.47.16.5", Value: &Origin{}},
+		{Name: "connection", Text: "IN IP4", Value: &Connection{}},
+		{Name: "bandwidth", Text: "AS", Value: &Bandwidth{}},
+		{Name: "timing", Text: "0", Value: &Timing{}},
+		{Name: "repeat time", Text: "7d 1h", Value: &RepeatTime{}},
+		{Name: "time zone", Text: "2882844526 -1h 2898848070 0", Value: &TimeZone{}},
+		{Name: "attribute", Text: "", Value: &Attribute{}},
+		{Name: "attribute value", Text: ":value", Value: &Attribute{}},
+		{Name: "media description", Text: "a=recvonly\n", Value: &MediaDesc{}},
+		{Name: "media description line", Text: "m=audio 9 RTP/AVP 0\nv=0\n", Value: &MediaDesc{}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			if err := test.Value.UnmarshalText([]byte(test.Text)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestTimingAndSessionUnmarshalText(t *testing.T) {
+	timing := &Timing{RepeatTimes: []*RepeatTime{{Interval: 1, Duration: 1}}}
+	if err := timing.UnmarshalText([]byte("0 0")); err != nil {
+		t.Fatal(err)
+	}
+	if timing.RepeatTimes != nil {
+		t.Fatalf("repeat times were not cleared: %v", timing.RepeatTimes)
+	}
+
+	var s Session
+	if err := s.UnmarshalText([]byte(strings.Replace(marshalTests[0].Data, "v=0", "v=x", 1))); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSessionWithoutOriginator(t *testing.T) {
+	s := &Session{SessionName: "-"}
+	if text := fmt.Sprint(s); text != "v=0\ns=-\n" {
+		t.Fatalf("wrong string: %q", text)
+	}
+	if _, err := s.MarshalText(); err == nil {
+		t.Fatal("session without originator was marshaled")
+	}
+}
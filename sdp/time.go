@@ -0,0 +1,253 @@
+package sdp
+
+import (
+	"sort"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1 January 1900,
+// to the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// NTPToTime returns the time of NTP seconds as used by t= and z= lines. Zero
+// is returned as the zero time, as it means unbounded in a t= line.
+func NTPToTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds-ntpEpochOffset, 0).UTC()
+}
+
+// TimeToNTP returns t in NTP seconds, the zero time as zero.
+func TimeToNTP(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix() + ntpEpochOffset
+}
+
+func secondsToDuration(seconds int64) time.Duration {
+	return time.Duration(seconds) * time.Second
+}
+
+func durationToSeconds(d time.Duration) int64 {
+	return int64(d / time.Second)
+}
+
+// NewTiming returns the timing of a session active from start to stop, either
+// of which may be the zero time for an unbounded session.
+func NewTiming(start, stop time.Time) *Timing {
+	return &Timing{Start: TimeToNTP(start), Stop: TimeToNTP(stop)}
+}
+
+// StartTime returns the start time, the zero time if the session is
+// permanent.
+func (t *Timing) StartTime() time.Time {
+	return NTPToTime(t.Start)
+}
+
+// StopTime returns the stop time, the zero time if the session is unbounded.
+func (t *Timing) StopTime() time.Time {
+	return NTPToTime(t.Stop)
+}
+
+func NewRepeatTime(interval, duration time.Duration, offsets ...time.Duration) *RepeatTime {
+	res := &RepeatTime{Interval: durationToSeconds(interval), Duration: durationToSeconds(duration)}
+	for _, offset := range offsets {
+		res.Offsets = append(res.Offsets, durationToSeconds(offset))
+	}
+	return res
+}
+
+// IntervalDuration returns the repeat interval.
+func (r *RepeatTime) IntervalDuration() time.Duration {
+	return secondsToDuration(r.Interval)
+}
+
+// ActiveDuration returns how long each repetition is active.
+func (r *RepeatTime) ActiveDuration() time.Duration {
+	return secondsToDuration(r.Duration)
+}
+
+// OffsetDurations returns the offsets of the repetitions from the start of
+// each interval.
+func (r *RepeatTime) OffsetDurations() []time.Duration {
+	res := make([]time.Duration, 0, len(r.Offsets))
+	for _, offset := range r.Offsets {
+		res = append(res, secondsToDuration(offset))
+	}
+	return res
+}
+
+func NewTimeZone(adjustment time.Time, offset time.Duration) *TimeZone {
+	return &TimeZone{Time: TimeToNTP(adjustment), Offset: durationToSeconds(offset)}
+}
+
+// AdjustmentTime returns the time from which the offset applies.
+func (z *TimeZone) AdjustmentTime() time.Time {
+	return NTPToTime(z.Time)
+}
+
+// OffsetDuration returns the offset applied to repetitions from the
+// adjustment time on.
+func (z *TimeZone) OffsetDuration() time.Duration {
+	return secondsToDuration(z.Offset)
+}
+
+// Interval is a period in which a session is active, from Start up to but not
+// including Stop. A zero Start or Stop means the interval is unbounded on
+// that side.
+type Interval struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// Contains reports whether the session is active at t during the interval.
+func (i Interval) Contains(t time.Time) bool {
+	return (i.Start.IsZero() || !t.Before(i.Start)) && (i.Stop.IsZero() || t.Before(i.Stop))
+}
+
+// IntervalIterator expands the t=, r= and z= lines of a session into the
+// intervals in which it is active.
+type IntervalIterator struct {
+	sequences []*intervalSequence
+}
+
+// Intervals returns an iterator over the intervals of the session that end
+// after from, in order of their start time. Each t= line without r= lines is
+// one interval; each r= line repeats the session from the start of its t=
+// line until its stop, with the repetitions shifted by the z= adjustments.
+func (s *Session) Intervals(from time.Time) *IntervalIterator {
+	res := &IntervalIterator{}
+	fromNTP := TimeToNTP(from)
+	for _, timing := range s.Timings {
+		if timing.Start == 0 || len(timing.RepeatTimes) == 0 {
+			res.sequences = append(res.sequences, &intervalSequence{timing: timing, from: fromNTP})
+			continue
+		}
+		for _, repeat := range timing.RepeatTimes {
+			res.sequences = append(res.sequences, newRepeatSequence(timing, repeat, s.TimeZones, fromNTP))
+		}
+	}
+	return res
+}
+
+// Next returns the next interval, or false if there are no more.
+func (it *IntervalIterator) Next() (Interval, bool) {
+	var next *intervalSequence
+	var nextStart int64
+	for _, sequence := range it.sequences {
+		start, _, ok := sequence.head()
+		if ok && (next == nil || start < nextStart) {
+			next, nextStart = sequence, start
+		}
+	}
+	if next == nil {
+		return Interval{}, false
+	}
+
+	start, stop, _ := next.head()
+	next.advance()
+	return Interval{Start: NTPToTime(start), Stop: NTPToTime(stop)}, true
+}
+
+// IsActiveAt reports whether the session is active at t.
+func (s *Session) IsActiveAt(t time.Time) bool {
+	interval, ok := s.Intervals(t).Next()
+	return ok && interval.Contains(t)
+}
+
+// intervalSequence yields the intervals of a t= line, repeated by one of its
+// r= lines if repeat is set, skipping those that end before from.
+type intervalSequence struct {
+	timing  *Timing
+	repeat  *RepeatTime
+	offsets []int64
+	zones   []*TimeZone
+	from    int64
+
+	period int64
+	offset int
+	done   bool
+}
+
+func newRepeatSequence(timing *Timing, repeat *RepeatTime, zones []*TimeZone, from int64) *intervalSequence {
+	res := &intervalSequence{timing: timing, repeat: repeat, zones: zones, from: from}
+	res.offsets = append(res.offsets, repeat.Offsets...)
+	if len(res.offsets) == 0 {
+		res.offsets = append(res.offsets, 0)
+	}
+	sort.Slice(res.offsets, func(i, j int) bool { return res.offsets[i] < res.offsets[j] })
+
+	// Start a period early enough not to miss an interval that ends after
+	// from, whatever the offsets and adjustments.
+	if repeat.Interval > 0 && from > timing.Start {
+		slack := repeat.Duration + res.offsets[len(res.offsets)-1]
+		for _, zone := range zones {
+			if zone.Offset > 0 {
+				slack += zone.Offset
+			}
+		}
+		if period := (from - timing.Start - slack) / repeat.Interval; period > 0 {
+			res.period = period - 1
+		}
+	}
+	return res
+}
+
+// head returns the current interval in NTP seconds.
+func (q *intervalSequence) head() (start, stop int64, ok bool) {
+	for !q.done {
+		start, stop, ok = q.current()
+		if !ok {
+			q.done = true
+			break
+		}
+		if q.from == 0 || stop == 0 || stop > q.from {
+			return start, stop, true
+		}
+		q.advance()
+	}
+	return 0, 0, false
+}
+
+func (q *intervalSequence) current() (start, stop int64, ok bool) {
+	if q.repeat == nil {
+		return q.timing.Start, q.timing.Stop, true
+	}
+
+	base := q.timing.Start + q.period*q.repeat.Interval + q.offsets[q.offset]
+	if q.timing.Stop != 0 && base >= q.timing.Stop {
+		return 0, 0, false
+	}
+	start = base + q.adjustment(base)
+	stop = start + q.repeat.Duration
+	if q.timing.Stop != 0 && stop > q.timing.Stop {
+		stop = q.timing.Stop
+	}
+	return start, stop, true
+}
+
+// adjustment returns the offset of the last z= adjustment at or before base.
+// Adjustments are not cumulative: each replaces the previous one.
+func (q *intervalSequence) adjustment(base int64) int64 {
+	var res, at int64
+	for _, zone := range q.zones {
+		if zone.Time <= base && zone.Time >= at {
+			res, at = zone.Offset, zone.Time
+		}
+	}
+	return res
+}
+
+func (q *intervalSequence) advance() {
+	if q.repeat == nil || q.repeat.Interval <= 0 && q.offset+1 == len(q.offsets) {
+		q.done = true
+		return
+	}
+	q.offset++
+	if q.offset == len(q.offsets) {
+		q.offset = 0
+		q.period++
+	}
+}
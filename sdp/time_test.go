@@ -0,0 +1,209 @@
+package sdp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNTPTime(t *testing.T) {
+	unix := time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if got := NTPToTime(ntpEpochOffset); !got.Equal(unix) {
+		t.Fatalf("wrong time: %v", got)
+	}
+	if got := TimeToNTP(unix); got != ntpEpochOffset {
+		t.Fatalf("wrong NTP seconds: %v", got)
+	}
+	if !NTPToTime(0).IsZero() || TimeToNTP(time.Time{}) != 0 {
+		t.Fatal("zero is not unbounded")
+	}
+
+	start := time.Date(1996, time.February, 28, 0, 0, 0, 0, time.UTC)
+	timing := NewTiming(start, time.Time{})
+	if timing.Start != 3034454400 || timing.Stop != 0 || !timing.StartTime().Equal(start) {
+		t.Fatalf("wrong timing: %+v", timing)
+	}
+
+	repeat := NewRepeatTime(7*24*time.Hour, time.Hour, 0, 25*time.Hour)
+	if !cmp.Equal(repeat, &RepeatTime{Interval: 604800, Duration: 3600, Offsets: []int64{0, 90000}}) {
+		t.Fatalf("wrong repeat time: %+v", repeat)
+	}
+	if repeat.IntervalDuration() != 7*24*time.Hour || repeat.ActiveDuration() != time.Hour {
+		t.Fatalf("wrong durations: %v %v", repeat.IntervalDuration(), repeat.ActiveDuration())
+	}
+	if !cmp.Equal(repeat.OffsetDurations(), []time.Duration{0, 25 * time.Hour}) {
+		t.Fatalf("wrong offsets: %v", repeat.OffsetDurations())
+	}
+
+	zone := NewTimeZone(start, -time.Hour)
+	if zone.Offset != -3600 || zone.OffsetDuration() != -time.Hour || !zone.AdjustmentTime().Equal(start) {
+		t.Fatalf("wrong time zone: %+v", zone)
+	}
+}
+
+func collectIntervals(s *Session, from time.Time, limit int) []Interval {
+	var res []Interval
+	it := s.Intervals(from)
+	for len(res) < limit {
+		interval, ok := it.Next()
+		if !ok {
+			break
+		}
+		res = append(res, interval)
+	}
+	return res
+}
+
+func TestIntervals(t *testing.T) {
+	day := int64(86400)
+	start := int64(3034423619)
+	at := func(seconds int64) time.Time { return NTPToTime(seconds) }
+
+	tests := []struct {
+		Name      string
+		Session   *Session
+		From      time.Time
+		Intervals []Interval
+	}{
+		{
+			Name:      "permanent",
+			Session:   &Session{Timings: []*Timing{{}}},
+			Intervals: []Interval{{}},
+		},
+		{
+			Name: "bounded",
+			Session: &Session{Timings: []*Timing{
+				{Start: start + day, Stop: start + 2*day},
+				{Start: start, Stop: start + day},
+			}},
+			Intervals: []Interval{
+				{Start: at(start), Stop: at(start + day)},
+				{Start: at(start + day), Stop: at(start + 2*day)},
+			},
+		},
+		{
+			Name: "repeated with offsets",
+			Session: &Session{Timings: []*Timing{{Start: start, Stop: start + 10*day, RepeatTimes: []*RepeatTime{
+				{Interval: 7 * day, Duration: 3600, Offsets: []int64{90000, 0}},
+			}}}},
+			Intervals: []Interval{
+				{Start: at(start), Stop: at(start + 3600)},
+				{Start: at(start + 90000), Stop: at(start + 90000 + 3600)},
+				{Start: at(start + 7*day), Stop: at(start + 7*day + 3600)},
+				{Start: at(start + 7*day + 90000), Stop: at(start + 7*day + 90000 + 3600)},
+			},
+		},
+		{
+			Name: "repeated from",
+			Session: &Session{Timings: []*Timing{{Start: start, RepeatTimes: []*RepeatTime{
+				{Interval: day, Duration: 3600},
+			}}}},
+			From: at(start + 1000*day + 1800),
+			Intervals: []Interval{
+				{Start: at(start + 1000*day), Stop: at(start + 1000*day + 3600)},
+				{Start: at(start + 1001*day), Stop: at(start + 1001*day + 3600)},
+			},
+		},
+		{
+			Name: "time zones",
+			Session: &Session{
+				Timings: []*Timing{{Start: start, Stop: start + 5*day, RepeatTimes: []*RepeatTime{
+					{Interval: day, Duration: 3600},
+				}}},
+				TimeZones: []*TimeZone{{Time: start + 2*day, Offset: -3600}, {Time: start + 4*day, Offset: 0}},
+			},
+			Intervals: []Interval{
+				{Start: at(start), Stop: at(start + 3600)},
+				{Start: at(start + day), Stop: at(start + day + 3600)},
+				{Start: at(start + 2*day - 3600), Stop: at(start + 2*day)},
+				{Start: at(start + 3*day - 3600), Stop: at(start + 3*day)},
+				{Start: at(start + 4*day), Stop: at(start + 4*day + 3600)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			intervals := collectIntervals(test.Session, test.From, len(test.Intervals)+1)
+			if len(intervals) > len(test.Intervals) && test.Session.Timings[0].Stop != 0 {
+				t.Fatalf("unexpected interval: %v", intervals[len(test.Intervals)])
+			}
+			if len(intervals) > len(test.Intervals) {
+				intervals = intervals[:len(test.Intervals)]
+			}
+			if !cmp.Equal(intervals, test.Intervals) {
+				t.Fatal(cmp.Diff(intervals, test.Intervals))
+			}
+		})
+	}
+}
+
+func TestIsActiveAt(t *testing.T) {
+	data := "v=0\r\n" +
+		"o=jdoe 2890844526 2890842807 IN IP4 10.47.16.5\r\n" +
+		"s=SDP Seminar\r\n" +
+		"c=IN IP4 224.2.17.12/127\r\n" +
+		"t=3034423619 3042462419\r\n" +
+		"r=7d 1h 0 25h\r\n"
+	s, err := NewDecoder(bytes.NewReader([]byte(data))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intervals := collectIntervals(s, time.Time{}, 100); len(intervals) != 28 {
+		t.Fatalf("wrong number of intervals: %v", len(intervals))
+	}
+
+	start := NTPToTime(3034423619)
+	tests := []struct {
+		Time   time.Time
+		Active bool
+	}{
+		{Time: start.Add(-time.Second), Active: false},
+		{Time: start, Active: true},
+		{Time: start.Add(time.Hour), Active: false},
+		{Time: start.Add(25*time.Hour + time.Minute), Active: true},
+		{Time: start.Add(13*7*24*time.Hour + 25*time.Hour), Active: true},
+		{Time: start.Add(14 * 7 * 24 * time.Hour), Active: false},
+	}
+	for _, test := range tests {
+		if active := s.IsActiveAt(test.Time); active != test.Active {
+			t.Errorf("active at %v: %v, expected %v", test.Time, active, test.Active)
+		}
+	}
+
+	text, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "t=3034423619 3042462419\nr=604800 3600 0 90000\n"; !bytes.HasSuffix(text, []byte(expected)) {
+		t.Fatalf("wrong repeat time lines: %q", text)
+	}
+}
+
+func TestTimeShorthands(t *testing.T) {
+	data := "v=0\n" +
+		"o=- 0 1 IN IP4 127.0.0.1\n" +
+		"s=-\n" +
+		"c=IN IP4 127.0.0.1\n" +
+		"t=35121d 35122d\n" +
+		"r=1d 90m 0 -1h\n" +
+		"z=35121d -1h\n"
+	s, err := NewDecoder(bytes.NewReader([]byte(data))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timing := &Timing{Start: 35121 * 86400, Stop: 35122 * 86400, RepeatTimes: []*RepeatTime{
+		{Interval: 86400, Duration: 5400, Offsets: []int64{0, -3600}},
+	}}
+	if !cmp.Equal(s.Timings, []*Timing{timing}) {
+		t.Fatal(cmp.Diff(s.Timings, []*Timing{timing}))
+	}
+	zones := []*TimeZone{{Time: 35121 * 86400, Offset: -3600}}
+	if !cmp.Equal(s.TimeZones, zones) {
+		t.Fatal(cmp.Diff(s.TimeZones, zones))
+	}
+}
@@ -1,9 +1,9 @@
 package sdp
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -41,73 +41,115 @@ type Decoder struct {
 	s            *Session
 	currentLevel level
 	currentStage stage
+	lastField    byte
+	raw          *RawLines
 }
 
 func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{r: r, s: &Session{}, currentLevel: initLevel, currentStage: initStage}
 }
 
+// Decode reads the reader to EOF and parses the whole input as a single
+// session description. The input is held in memory, so callers reading from
+// untrusted sources should bound it, e.g. with io.LimitReader.
 func (d *Decoder) Decode() (*Session, error) {
+	data, err := io.ReadAll(d.r)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading from reader: %v", err)
+	}
+
+	if err := d.decode(string(data)); err != nil {
+		return nil, err
+	}
+	return d.s, nil
+}
+
+// Unmarshal parses the session description in data into s. Slices of s and
+// the values they point to are reused, so once s has grown to the size of
+// the descriptions, decoding into it only allocates the single copy of data
+// that the strings in s share; data itself is not retained. Nothing obtained
+// from s before the call may be kept, and its contents are undefined if an
+// error is returned.
+func Unmarshal(data []byte, s *Session) error {
+	s.reset()
+	d := Decoder{s: s, currentLevel: initLevel, currentStage: initStage}
+	return d.decode(string(data))
+}
+
+func (d *Decoder) decode(text string) error {
 	var err error
 
-	scanner := bufio.NewScanner(d.r)
 	lineNum := 1
 
-	flags := &flags{false, false, false}
+	flags := &flags{}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	for len(text) > 0 {
+		line, rawLine := text, text
+		if index := strings.IndexByte(text, '\n'); index >= 0 {
+			line, rawLine, text = text[:index], text[:index+1], text[index+1:]
+		} else {
+			text = ""
+		}
+		line = strings.TrimSuffix(line, "\r")
 
 		if len(line) == 0 {
-			return nil, fmt.Errorf("wrong sdp file format: line %v is empty", lineNum)
+			return fmt.Errorf("wrong sdp file format: line %v is empty", lineNum)
+		}
+		if strings.IndexAny(line, "\x00\r") >= 0 {
+			return fmt.Errorf("wrong sdp file format: line %v contains NUL or CR", lineNum)
 		}
 
-		if line[0] == MediaDescField {
+		isExtension := isExtensionLine(line)
+		if isExtension {
+			err = d.parseExtensionLine(line)
+		} else if line[0] == MediaDescField {
 			d.currentStage = initStage
 
 			if len(line) < 2 {
-				return nil, fmt.Errorf("wrong sdp file format: medialine %v is empty", lineNum)
+				return fmt.Errorf("wrong sdp file format: medialine %v is empty", lineNum)
 			}
 
-			mediaDesc, mediaErr := d.parseMediaDesc(line[2:], lineNum)
-			if mediaErr != nil {
-				err = mediaErr
-			} else {
-				d.s.MediaDescs = append(d.s.MediaDescs, mediaDesc)
-			}
-		} else if d.s.MediaDescs != nil {
+			err = parseMediaDesc(line[2:], nextMediaDesc(&d.s.MediaDescs))
+		} else if len(d.s.MediaDescs) > 0 {
 			err = d.parseMediaLine(line, lineNum)
 		} else {
 			d.currentLevel = sessionLevel
 			err = d.parseSessionLine(line, lineNum, flags)
 		}
 		if err != nil {
-			return nil, fmt.Errorf("error while parsing: %v", err)
+			return fmt.Errorf("error while parsing: %v", err)
+		}
+		if !isExtension {
+			d.lastField = line[0]
+		}
+		if d.raw != nil {
+			d.keepRawLine(rawLine, line[0], isExtension)
 		}
 
 		lineNum += 1
 	}
 
-	if scanner.Err() != nil {
-		return nil, fmt.Errorf("error while reading from reader: %v", scanner.Err())
+	if !flags.setConnectionData {
+		d.s.ConnectionData = nil
 	}
 
-	if d.s.ConnectionData == nil {
-		for _, mediaDesc := range d.s.MediaDescs {
-			if mediaDesc.Connections == nil {
-				return nil, fmt.Errorf("a session description MUST contain either at least one c= field in each media description or a single c= field at the session level")
-			}
-		}
+	if !d.s.hasConnections() {
+		return fmt.Errorf("a session description MUST contain either at least one c= field in each media description or a single c= field at the session level")
 	}
 
 	if !checkFlags(flags) {
-		return nil, fmt.Errorf("not all required fields are set")
+		return fmt.Errorf("not all required fields are set")
 	}
 
-	return d.s, nil
+	d.s.trim()
+	if d.raw != nil {
+		d.finishRawLines()
+	}
+
+	return nil
 }
 
-func (d *Decoder) parseVersion(value string) (int, error) {
+func parseVersion(value string) (int, error) {
 	version, err := strconv.Atoi(value)
 	if err != nil {
 		return 0, fmt.Errorf("wrong version number: %v", value)
@@ -115,11 +157,11 @@ func (d *Decoder) parseVersion(value string) (int, error) {
 	return version, nil
 }
 
-func (d *Decoder) parseTime(value string) (num int64, err error) {
+func parseTime(value string) (num int64, err error) {
 	if len(value) == 0 {
 		return 0, fmt.Errorf("error while parsing time: empty time line")
 	}
-	multiplyer := d.timeShorthandToSeconds(value[len(value)-1])
+	multiplyer := timeShorthandToSeconds(value[len(value)-1])
 	if multiplyer > 0 {
 		num, err = strconv.ParseInt(value[:len(value)-1], 10, 64)
 	} else {
@@ -129,10 +171,13 @@ func (d *Decoder) parseTime(value string) (num int64, err error) {
 	if err != nil {
 		return 0, fmt.Errorf("error while parsing time: %v", err)
 	}
+	if num > math.MaxInt64/multiplyer || num < math.MinInt64/multiplyer {
+		return 0, fmt.Errorf("error while parsing time: %v is out of range", value)
+	}
 	return num * multiplyer, nil
 }
 
-func (d *Decoder) timeShorthandToSeconds(b byte) int64 {
+func timeShorthandToSeconds(b byte) int64 {
 	switch b {
 	case DayShorthand:
 		return 86400
@@ -147,42 +192,35 @@ func (d *Decoder) timeShorthandToSeconds(b byte) int64 {
 	}
 }
 
-func (d *Decoder) parseEncryptionKey(value string) (*EncryptionKey, error) {
-	var key EncryptionKey
+func parseEncryptionKey(value string, key *EncryptionKey) {
+	method, keyValue, ok := strings.Cut(value, ":")
+	key.Method = method
 
-	fields := strings.Split(value, ":")
-	key.Method = fields[0]
-
-	if len(fields) == 1 {
-		key.Value = " "
-	} else if len(fields) == 2 {
-		key.Value = fields[1]
+	if !ok {
+		key.Value = propertyValue
 	} else {
-		return nil, fmt.Errorf("wrong encryption key format")
+		key.Value = keyValue
 	}
-
-	return &key, nil
 }
 
-func (d *Decoder) parseAttribute(value string) (*Attribute, error) {
-	var att Attribute
-
-	fields := strings.Split(value, ":")
-	att.Name = fields[0]
+func parseAttribute(value string, att *Attribute) error {
+	name, attValue, ok := strings.Cut(value, ":")
+	if name == "" {
+		return fmt.Errorf("wrong attribute format: empty name")
+	}
+	att.Name = name
 
-	if len(fields) == 1 {
-		att.Value = " "
-	} else if len(fields) == 2 {
-		att.Value = fields[1]
+	if !ok {
+		att.Value = propertyValue
 	} else {
-		return nil, fmt.Errorf("wrong attribute format")
+		att.Value = attValue
 	}
 
-	return &att, nil
+	return nil
 }
 
 func (d *Decoder) parseURI(value string) (string, error) {
-	if d.s.MediaDescs != nil {
+	if len(d.s.MediaDescs) > 0 {
 		return "", fmt.Errorf("URI must be specified before the first media field")
 	}
 
@@ -194,7 +232,7 @@ func (d *Decoder) parseURI(value string) (string, error) {
 }
 
 func (d *Decoder) parseEmail(value string) (string, error) {
-	if d.s.MediaDescs != nil {
+	if len(d.s.MediaDescs) > 0 {
 		return "", fmt.Errorf("email must be specified before the first media field")
 	}
 
@@ -202,149 +240,154 @@ func (d *Decoder) parseEmail(value string) (string, error) {
 }
 
 func (d *Decoder) parsePhoneNumber(value string) (string, error) {
-	if d.s.MediaDescs != nil {
+	if len(d.s.MediaDescs) > 0 {
 		return "", fmt.Errorf("phone number must be specified before the first media field")
 	}
 
 	return value, nil
 }
 
-func (d *Decoder) parseOriginator(value string) (*Origin, error) {
+func parseOriginator(value string, origin *Origin) error {
 	var err error
+	var fields [6]string
 
-	fields := strings.Split(value, " ")
-	if len(fields) != 6 {
-		return nil, fmt.Errorf("wrong originator format")
+	if !splitFields(value, ' ', fields[:]) {
+		return fmt.Errorf("wrong originator format")
 	}
 
-	var origin Origin
 	origin.Username = fields[0]
 	origin.SessID, err = strconv.ParseInt(fields[1], 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("wrong originator.sess-id format")
+		return fmt.Errorf("wrong originator.sess-id format")
 	}
 	origin.SessVersion, err = strconv.ParseInt(fields[2], 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("wrong originator.sess-version format")
+		return fmt.Errorf("wrong originator.sess-version format")
 	}
 	origin.Nettype = fields[3]
 	origin.Addrtype = fields[4]
 	origin.UnicastAddress = fields[5]
 
-	return &origin, nil
+	return nil
 }
 
-func (d *Decoder) parseConnection(value string) (*Connection, error) {
+func parseConnection(value string, connection *Connection) error {
 	var err error
+	var fields [3]string
 
-	fields := strings.Split(value, " ")
-	if len(fields) != 3 {
-		return nil, fmt.Errorf("wrong connection format")
+	if !splitFields(value, ' ', fields[:]) {
+		return fmt.Errorf("wrong connection format")
 	}
 
-	var connection Connection
-	connection.Nettype = fields[0]
-	connection.Addrtype = fields[1]
+	*connection = Connection{Nettype: fields[0], Addrtype: fields[1]}
 
-	fields = strings.Split(fields[2], "/")
-	connection.ConnectionAddr = fields[0]
+	address, rest, hasTTL := strings.Cut(fields[2], "/")
+	connection.ConnectionAddr = address
 
 	if connection.Addrtype == TypeIPv4 {
-		if len(fields) > 1 {
-			connection.TTL, err = strconv.ParseInt(fields[1], 10, 64)
+		connection.AddressesNum = 1
+		if hasTTL {
+			ttl, addressesNum, hasAddressesNum := strings.Cut(rest, "/")
+			addressesNum, _, _ = strings.Cut(addressesNum, "/")
+			connection.TTL, err = strconv.ParseInt(ttl, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("wrong connection.TTL format")
+				return fmt.Errorf("wrong connection.TTL format")
 			}
-		}
-		if len(fields) > 2 {
-			connection.AddressesNum, err = strconv.ParseInt(fields[2], 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("wrong connection.addresses-num format")
+			if hasAddressesNum {
+				connection.AddressesNum, err = parseAddressesNum(addressesNum)
+				if err != nil {
+					return err
+				}
 			}
-		} else {
-			connection.AddressesNum = 1
 		}
 	} else if connection.Addrtype == TypeIPv6 {
-		if len(fields) > 1 {
-			connection.AddressesNum, err = strconv.ParseInt(fields[1], 10, 64)
+		connection.AddressesNum = 1
+		if hasTTL {
+			addressesNum, _, _ := strings.Cut(rest, "/")
+			connection.AddressesNum, err = parseAddressesNum(addressesNum)
 			if err != nil {
-				return nil, fmt.Errorf("wrong connection.addresses-num format")
+				return err
 			}
-		} else {
-			connection.AddressesNum = 1
 		}
 	}
 
-	return &connection, nil
+	return nil
 }
 
-func (d *Decoder) parseBandwidth(value string) (*Bandwidth, error) {
+func parseAddressesNum(value string) (int64, error) {
+	addressesNum, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || addressesNum < 1 {
+		return 0, fmt.Errorf("wrong connection.addresses-num format")
+	}
+	return addressesNum, nil
+}
+
+func parseBandwidth(value string, bandwidth *Bandwidth) error {
 	var err error
-	var bandwidth Bandwidth
+	var fields [2]string
 
-	fields := strings.Split(value, ":")
-	if len(fields) != 2 {
-		return nil, fmt.Errorf("wrong bandwidth format")
+	if !splitFields(value, ':', fields[:]) {
+		return fmt.Errorf("wrong bandwidth format")
 	}
 
 	bandwidth.Type = fields[0]
 	bandwidth.Value, err = strconv.Atoi(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("wrong bandwidth format")
+		return fmt.Errorf("wrong bandwidth format")
 	}
 
-	return &bandwidth, nil
+	return nil
 }
 
-func (d *Decoder) parseTiming(value string) (*Timing, error) {
-	var timing Timing
+func parseTiming(value string, timing *Timing) error {
 	var err error
+	var fields [2]string
 
-	fields := strings.Split(value, " ")
-	if len(fields) != 2 {
-		return nil, fmt.Errorf("wrong timing format")
+	if !splitFields(value, ' ', fields[:]) {
+		return fmt.Errorf("wrong timing format")
 	}
 
-	timing.Start, err = strconv.ParseInt(fields[0], 10, 64)
+	timing.Start, err = parseTime(fields[0])
 	if err != nil {
-		return nil, fmt.Errorf("wrong timing format")
+		return fmt.Errorf("wrong timing format")
 	}
 
-	timing.Stop, err = strconv.ParseInt(fields[1], 10, 64)
+	timing.Stop, err = parseTime(fields[1])
 	if err != nil {
-		return nil, fmt.Errorf("wrong timing format")
+		return fmt.Errorf("wrong timing format")
 	}
 
-	return &timing, err
+	return nil
 }
 
-func (d *Decoder) parseTimeZones(value string) ([]*TimeZone, error) {
-	var timeZones []*TimeZone
+func parseTimeZones(value string, timeZones []*TimeZone) ([]*TimeZone, error) {
 	var err error
 
-	fields := strings.Split(value, " ")
+	for {
+		var zoneTime, offset string
+		var ok bool
 
-	if len(fields)%2 != 0 {
-		return nil, fmt.Errorf("wrong time zone format")
-	}
-
-	for i := 0; i < len(fields); i += 2 {
-		var timeZone TimeZone
+		zoneTime, value, ok = strings.Cut(value, " ")
+		if !ok {
+			return nil, fmt.Errorf("wrong time zone format")
+		}
+		offset, value, ok = strings.Cut(value, " ")
 
-		timeZone.Time, err = strconv.ParseInt(fields[i], 10, 64)
+		timeZone := nextTimeZone(&timeZones)
+		timeZone.Time, err = parseTime(zoneTime)
 		if err != nil {
 			return nil, fmt.Errorf("error while parsing time zone: %v", err)
 		}
 
-		timeZone.Offset, err = d.parseTime(fields[i+1])
+		timeZone.Offset, err = parseTime(offset)
 		if err != nil {
 			return nil, fmt.Errorf("error while parsing time zone: %v", err)
 		}
 
-		timeZones = append(timeZones, &timeZone)
+		if !ok {
+			return timeZones, nil
+		}
 	}
-
-	return timeZones, nil
 }
 
 func (d *Decoder) parseSessionName(value string) (string, error) {
@@ -357,44 +400,50 @@ func (d *Decoder) parseSessionName(value string) (string, error) {
 	return value, nil
 }
 
-func (d *Decoder) parseRepeatTime(value string) (*RepeatTime, error) {
+func parseRepeatTime(value string, repeat *RepeatTime) error {
 	var err error
-	var repeat RepeatTime
+	var interval, duration string
+	var ok bool
 
-	fields := strings.Split(value, " ")
-	if len(fields) < 3 {
-		return nil, fmt.Errorf("wrong repeat time format")
+	interval, value, ok = strings.Cut(value, " ")
+	if ok {
+		duration, value, ok = strings.Cut(value, " ")
+	}
+	if !ok {
+		return fmt.Errorf("wrong repeat time format")
 	}
 
-	repeat.Interval, err = d.parseTime(fields[0])
+	repeat.Interval, err = parseTime(interval)
 	if err != nil {
-		return nil, fmt.Errorf("error while parsing time: %v", err)
+		return fmt.Errorf("error while parsing time: %v", err)
 	}
 
-	repeat.Duration, err = d.parseTime(fields[1])
+	repeat.Duration, err = parseTime(duration)
 	if err != nil {
-		return nil, fmt.Errorf("error while parsing time: %v", err)
+		return fmt.Errorf("error while parsing time: %v", err)
 	}
 
-	for i := 2; i < len(fields); i += 1 {
-		offset, err := d.parseTime(fields[i])
+	for ok {
+		var field string
+		field, value, ok = strings.Cut(value, " ")
+		offset, err := parseTime(field)
 		if err != nil {
-			return nil, fmt.Errorf("error while parsing time: %v", err)
+			return fmt.Errorf("error while parsing time: %v", err)
 		}
 		repeat.Offsets = append(repeat.Offsets, offset)
 	}
 
-	return &repeat, nil
+	return nil
 }
 
-func (d *Decoder) parseMedia(value string) (string, error) {
-	if !inSet(value, []string{"audio", "video", "text", "application", "message"}) {
+func parseMedia(value string) (string, error) {
+	if !inSet(value, []string{"audio", "video", "text", "application", "message", ImageMedia}) {
 		return "", fmt.Errorf("wrong media: %v", value)
 	}
 	return value, nil
 }
 
-func (d *Decoder) parsePort(value string) (int64, error) {
+func parsePort(value string) (int64, error) {
 	port, err := strconv.ParseInt(value, 10, 64)
 
 	if err != nil {
@@ -408,13 +457,17 @@ func (d *Decoder) parsePort(value string) (int64, error) {
 	return port, nil
 }
 
-func (d *Decoder) parsePortsNum(value string) (int64, error) {
+func parsePortsNum(value string) (int64, error) {
 	portsNum, err := strconv.ParseInt(value, 10, 64)
 
 	if err != nil {
 		return 0, fmt.Errorf("error while parsing ports num: %v", err)
 	}
 
+	if portsNum < 1 {
+		return 0, fmt.Errorf("error while parsing ports num: %v is not positive", portsNum)
+	}
+
 	return portsNum, nil
 }
 
@@ -427,49 +480,64 @@ func inSet(key string, values []string) bool {
 	return false
 }
 
-func (d *Decoder) parseMediaDesc(line string, lineNum int) (*MediaDesc, error) {
-	var mediaDesc MediaDesc
+func parseMediaDesc(line string, mediaDesc *MediaDesc) error {
 	var err error
 
-	fields := strings.Split(line, " ")
-
-	mediaDesc.Media, err = d.parseMedia(fields[0])
+	media, line, _ := strings.Cut(line, " ")
+	mediaDesc.Media, err = parseMedia(media)
 	if err != nil {
-		return nil, fmt.Errorf("wrong media discription format: %v", err)
+		return fmt.Errorf("wrong media discription format: %v", err)
 	}
 
-	parts := strings.Split(fields[1], "/")
-	mediaDesc.Port, err = d.parsePort(parts[0])
+	ports, line, _ := strings.Cut(line, " ")
+	port, portsNum, hasPortsNum := strings.Cut(ports, "/")
+	mediaDesc.Port, err = parsePort(port)
 	if err != nil {
-		return nil, fmt.Errorf("wrong media discription format: %v", err)
+		return fmt.Errorf("wrong media discription format: %v", err)
 	}
 
-	if len(parts) > 1 {
-		mediaDesc.PortsNum, err = d.parsePortsNum(parts[1])
+	if hasPortsNum {
+		if strings.IndexByte(portsNum, '/') >= 0 {
+			return fmt.Errorf("wrong media discription format")
+		}
+
+		mediaDesc.PortsNum, err = parsePortsNum(portsNum)
 
 		if err != nil {
-			return nil, fmt.Errorf("wrong media discription format: %v", err)
+			return fmt.Errorf("wrong media discription format: %v", err)
 		}
 	} else {
 		mediaDesc.PortsNum = 1
 	}
 
-	if len(parts) > 2 {
-		return nil, fmt.Errorf("wrong media discription format")
+	protos, line, hasFmts := strings.Cut(line, " ")
+	mediaDesc.Proto, err = parseProto(mediaDesc.Proto, protos)
+	if err != nil {
+		return fmt.Errorf("wrong media discription format: %v", err)
 	}
 
-	for _, proto := range strings.Split(fields[2], "/") {
-		if !inSet(proto, []string{UDPproto, RTPproto, AVPproto, SAVPproto, SAVPFproto,
-			TLSproto, DTLSproto, SCTPproto, AVPFproto, TCPproto, MSRPproto}) {
-			return nil, fmt.Errorf("wrong media discription format: wrong protocol format")
-		}
-		mediaDesc.Proto = append(mediaDesc.Proto, proto)
+	for ok := hasFmts; ok; {
+		var format string
+		format, line, ok = strings.Cut(line, " ")
+		mediaDesc.Fmts = append(mediaDesc.Fmts, format)
 	}
 
-	fields = fields[3:]
-	mediaDesc.Fmts = append(mediaDesc.Fmts, fields...)
+	return nil
+}
 
-	return &mediaDesc, nil
+// parseProto appends the parts of a transport protocol, e.g.
+// "UDP/TLS/RTP/SAVPF", to res.
+func parseProto(res []string, value string) ([]string, error) {
+	for ok := true; ok; {
+		var proto string
+		proto, value, ok = strings.Cut(value, "/")
+		if !inSet(proto, []string{UDPproto, RTPproto, AVPproto, SAVPproto, SAVPFproto,
+			TLSproto, DTLSproto, SCTPproto, AVPFproto, TCPproto, MSRPproto, UDPTLproto}) {
+			return nil, fmt.Errorf("wrong protocol format")
+		}
+		res = append(res, proto)
+	}
+	return res, nil
 }
 
 func (d *Decoder) parseInforamtion(line string) string {
@@ -501,49 +569,65 @@ func (d *Decoder) parseMediaLine(line string, lineNum int) error {
 			return d.failOnOrder()
 		}
 		d.currentStage = connectionDataStage
-		connectionData, connErr := d.parseConnection(value)
-		if connErr != nil {
-			err = connErr
-		} else {
-			media.Connections = append(media.Connections, connectionData)
-		}
+		err = parseConnection(value, nextConnection(&media.Connections))
 	case BandwidthField:
 		if !d.isLessEqualStage(bandwidthStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = bandwidthStage
-		bandwidth, err := d.parseBandwidth(value)
-		if err == nil {
-			media.Bandwidths = append(media.Bandwidths, bandwidth)
-		}
+		err = parseBandwidth(value, nextBandwidth(&media.Bandwidths))
 	case EncryptionKeyField:
 		if !d.isLessEqualStage(encryptionKeyStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = encryptionKeyStage
-		key, keyErr := d.parseEncryptionKey(value)
-		if keyErr != nil {
-			err = keyErr
-		} else {
-			media.EncryptionKeys = append(media.EncryptionKeys, key)
-		}
+		parseEncryptionKey(value, nextEncryptionKey(&media.EncryptionKeys))
 	case AttributeField:
 		if !d.isLessEqualStage(attributesStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = attributesStage
-		attribute, attErr := d.parseAttribute(value)
-		if attErr != nil {
-			err = attErr
-		} else {
-			d.s.MediaDescs[len(d.s.MediaDescs)-1].Attributes = append(d.s.MediaDescs[len(d.s.MediaDescs)-1].Attributes, attribute)
-		}
+		err = parseAttribute(value, nextAttribute(&media.Attributes))
 	default:
 		return fmt.Errorf("unknown parameter type, line %v", lineNum)
 	}
 	return err
 }
 
+// isExtensionLine reports whether the line has a type letter that is not
+// one of the fields of RFC 8866.
+func isExtensionLine(line string) bool {
+	if len(line) < 2 || line[1] != '=' {
+		return false
+	}
+	key := line[0]
+	if (key < 'a' || key > 'z') && (key < 'A' || key > 'Z') {
+		return false
+	}
+	return strings.IndexByte("vosiuepcbtrzkam", key) < 0
+}
+
+// parseExtensionLine keeps a line of an unknown type without changing the
+// stage, so it may appear anywhere after v=.
+func (d *Decoder) parseExtensionLine(line string) error {
+	extension := &ExtensionLine{Type: line[0], Value: line[2:], After: d.lastField}
+	if extension.After == RepeatTimeField {
+		// r= fields belong to the preceding t= field.
+		extension.After = TimingField
+	}
+
+	if len(d.s.MediaDescs) > 0 {
+		media := d.s.MediaDescs[len(d.s.MediaDescs)-1]
+		media.ExtensionLines = append(media.ExtensionLines, extension)
+		return nil
+	}
+	if d.lastField == 0 {
+		return d.failOnOrder()
+	}
+	d.s.ExtensionLines = append(d.s.ExtensionLines, extension)
+	return nil
+}
+
 func (d *Decoder) isLessStage(stage stage) bool {
 	return d.currentStage < stage
 }
@@ -576,14 +660,17 @@ func (d *Decoder) parseSessionLine(line string, lineNum int, flags *flags) error
 			return d.failOnOrder()
 		}
 		d.currentStage = versionStage
-		d.s.Version, err = d.parseVersion(value)
+		d.s.Version, err = parseVersion(value)
 		flags.setVersion = true
 	case OriginField:
 		if !d.isLessStage(originStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = originStage
-		d.s.Originator, err = d.parseOriginator(value)
+		if d.s.Originator == nil {
+			d.s.Originator = &Origin{}
+		}
+		err = parseOriginator(value, d.s.Originator)
 		flags.setOriginator = true
 	case SessionNameField:
 		if !d.isLessStage(sessionNameStage) {
@@ -625,80 +712,63 @@ func (d *Decoder) parseSessionLine(line string, lineNum int, flags *flags) error
 			return d.failOnOrder()
 		}
 		d.currentStage = connectionDataStage
-		if d.s.ConnectionData != nil {
+		if flags.setConnectionData {
 			err = fmt.Errorf("multiple connection data descriptions per session")
 		} else {
-			d.s.ConnectionData, err = d.parseConnection(value)
+			if d.s.ConnectionData == nil {
+				d.s.ConnectionData = &Connection{}
+			}
+			err = parseConnection(value, d.s.ConnectionData)
+			flags.setConnectionData = true
 		}
 	case BandwidthField:
 		if !d.isLessEqualStage(bandwidthStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = bandwidthStage
-		bandwidth, bandErr := d.parseBandwidth(value)
-		if bandErr != nil {
+		if err := parseBandwidth(value, nextBandwidth(&d.s.Bandwidths)); err != nil {
 			return err
 		}
-		d.s.Bandwidths = append(d.s.Bandwidths, bandwidth)
-
 	case TimeZoneField:
 		if !d.isLessEqualStage(timeZoneStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = timeZoneStage
-		timeZones, tzErr := d.parseTimeZones(value)
+		timeZones, tzErr := parseTimeZones(value, d.s.TimeZones)
 		if tzErr != nil {
 			err = tzErr
 		} else {
-			d.s.TimeZones = append(d.s.TimeZones, timeZones...)
+			d.s.TimeZones = timeZones
 		}
 	case EncryptionKeyField:
 		if !d.isLessEqualStage(encryptionKeyStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = encryptionKeyStage
-		key, keyErr := d.parseEncryptionKey(value)
-		if keyErr != nil {
-			err = keyErr
-		} else {
-			d.s.EncryptionKeys = append(d.s.EncryptionKeys, key)
-		}
+		parseEncryptionKey(value, nextEncryptionKey(&d.s.EncryptionKeys))
 	case AttributeField:
 		if !d.isLessEqualStage(attributesStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = attributesStage
-		attribute, attErr := d.parseAttribute(value)
-		if attErr != nil {
-			err = attErr
-		} else {
-			d.s.Attributes = append(d.s.Attributes, attribute)
-		}
+		err = parseAttribute(value, nextAttribute(&d.s.Attributes))
 	case TimingField:
-		if !d.isLessEqualStage(timingStage) {
+		// Each t= field may be followed by its own r= fields.
+		if !d.isLessEqualStage(repeatTimeStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = timingStage
-		timing, timingErr := d.parseTiming(value)
-		if timingErr != nil {
-			return err
-		} else {
-			d.s.Timings = append(d.s.Timings, timing)
-		}
+		err = parseTiming(value, nextTiming(&d.s.Timings))
 	case RepeatTimeField:
 		if !d.isLessEqualStage(repeatTimeStage) {
 			return d.failOnOrder()
 		}
 		d.currentStage = repeatTimeStage
-		repeat, repErr := d.parseRepeatTime(value)
-		if repErr != nil {
-			err = repErr
+		if len(d.s.Timings) == 0 {
+			err = fmt.Errorf("r= should not be specified before t=")
 		} else {
-			if len(d.s.Timings) == 0 {
-				err = fmt.Errorf("r= should not be specified before t=")
-			} else {
-				d.s.Timings[len(d.s.Timings)-1].RepeatTimes = append(d.s.Timings[len(d.s.Timings)-1].RepeatTimes, repeat)
-			}
+			timing := d.s.Timings[len(d.s.Timings)-1]
+			err = parseRepeatTime(value, nextRepeatTime(&timing.RepeatTimes))
 		}
 	default:
 		return fmt.Errorf("unknown parameter type, line %v", lineNum)
@@ -709,8 +779,216 @@ func (d *Decoder) parseSessionLine(line string, lineNum int, flags *flags) error
 
 type flags struct {
 	setVersion, setSessionName, setOriginator bool
+	setConnectionData                         bool
+}
+
+// splitFields splits value around sep into fields and reports whether there
+// are exactly len(fields) of them.
+func splitFields(value string, sep byte, fields []string) bool {
+	last := len(fields) - 1
+	for i := 0; i < last; i++ {
+		index := strings.IndexByte(value, sep)
+		if index < 0 {
+			return false
+		}
+		fields[i], value = value[:index], value[index+1:]
+	}
+	if strings.IndexByte(value, sep) >= 0 {
+		return false
+	}
+	fields[last] = value
+	return true
 }
 
 func checkFlags(flags *flags) bool {
 	return flags.setOriginator && flags.setSessionName && flags.setVersion
 }
+
+// reset empties the session for Unmarshal, keeping its slices and the
+// values they point to for reuse.
+func (s *Session) reset() {
+	*s = Session{
+		Originator:     s.Originator,
+		ConnectionData: s.ConnectionData,
+		Emails:         s.Emails[:0],
+		PhoneNumbers:   s.PhoneNumbers[:0],
+		Bandwidths:     s.Bandwidths[:0],
+		Timings:        s.Timings[:0],
+		TimeZones:      s.TimeZones[:0],
+		EncryptionKeys: s.EncryptionKeys[:0],
+		Attributes:     s.Attributes[:0],
+		MediaDescs:     s.MediaDescs[:0],
+		ExtensionLines: s.ExtensionLines[:0],
+	}
+}
+
+// trim sets the slices left empty after decoding to nil, as if the session
+// was decoded from scratch.
+func (s *Session) trim() {
+	if len(s.Emails) == 0 {
+		s.Emails = nil
+	}
+	if len(s.PhoneNumbers) == 0 {
+		s.PhoneNumbers = nil
+	}
+	if len(s.Bandwidths) == 0 {
+		s.Bandwidths = nil
+	}
+	if len(s.Timings) == 0 {
+		s.Timings = nil
+	}
+	for _, timing := range s.Timings {
+		if len(timing.RepeatTimes) == 0 {
+			timing.RepeatTimes = nil
+		}
+	}
+	if len(s.TimeZones) == 0 {
+		s.TimeZones = nil
+	}
+	if len(s.EncryptionKeys) == 0 {
+		s.EncryptionKeys = nil
+	}
+	if len(s.Attributes) == 0 {
+		s.Attributes = nil
+	}
+	if len(s.MediaDescs) == 0 {
+		s.MediaDescs = nil
+	}
+	if len(s.ExtensionLines) == 0 {
+		s.ExtensionLines = nil
+	}
+	for _, desc := range s.MediaDescs {
+		if len(desc.Proto) == 0 {
+			desc.Proto = nil
+		}
+		if len(desc.Fmts) == 0 {
+			desc.Fmts = nil
+		}
+		if len(desc.Attributes) == 0 {
+			desc.Attributes = nil
+		}
+		if len(desc.Bandwidths) == 0 {
+			desc.Bandwidths = nil
+		}
+		if len(desc.Connections) == 0 {
+			desc.Connections = nil
+		}
+		if len(desc.EncryptionKeys) == 0 {
+			desc.EncryptionKeys = nil
+		}
+		if len(desc.ExtensionLines) == 0 {
+			desc.ExtensionLines = nil
+		}
+	}
+}
+
+// nextMediaDesc appends an empty media description to descs, reusing the one
+// left in its spare capacity by a previous Unmarshal.
+func nextMediaDesc(descs *[]*MediaDesc) *MediaDesc {
+	n := len(*descs)
+	if n < cap(*descs) && (*descs)[:n+1][n] != nil {
+		*descs = (*descs)[:n+1]
+		desc := (*descs)[n]
+		*desc = MediaDesc{
+			Proto:          desc.Proto[:0],
+			Fmts:           desc.Fmts[:0],
+			Attributes:     desc.Attributes[:0],
+			Bandwidths:     desc.Bandwidths[:0],
+			Connections:    desc.Connections[:0],
+			EncryptionKeys: desc.EncryptionKeys[:0],
+			ExtensionLines: desc.ExtensionLines[:0],
+		}
+		return desc
+	}
+	desc := &MediaDesc{}
+	*descs = append(*descs, desc)
+	return desc
+}
+
+// nextAttribute is nextMediaDesc for attributes.
+func nextAttribute(attributes *Attributes) *Attribute {
+	n := len(*attributes)
+	if n < cap(*attributes) && (*attributes)[:n+1][n] != nil {
+		*attributes = (*attributes)[:n+1]
+		return (*attributes)[n]
+	}
+	attribute := &Attribute{}
+	*attributes = append(*attributes, attribute)
+	return attribute
+}
+
+// nextConnection is nextMediaDesc for connections.
+func nextConnection(connections *[]*Connection) *Connection {
+	n := len(*connections)
+	if n < cap(*connections) && (*connections)[:n+1][n] != nil {
+		*connections = (*connections)[:n+1]
+		return (*connections)[n]
+	}
+	connection := &Connection{}
+	*connections = append(*connections, connection)
+	return connection
+}
+
+// nextTiming is nextMediaDesc for timings.
+func nextTiming(timings *[]*Timing) *Timing {
+	n := len(*timings)
+	if n < cap(*timings) && (*timings)[:n+1][n] != nil {
+		*timings = (*timings)[:n+1]
+		timing := (*timings)[n]
+		*timing = Timing{RepeatTimes: timing.RepeatTimes[:0]}
+		return timing
+	}
+	timing := &Timing{}
+	*timings = append(*timings, timing)
+	return timing
+}
+
+// nextRepeatTime is nextMediaDesc for repeat times.
+func nextRepeatTime(repeats *[]*RepeatTime) *RepeatTime {
+	n := len(*repeats)
+	if n < cap(*repeats) && (*repeats)[:n+1][n] != nil {
+		*repeats = (*repeats)[:n+1]
+		repeat := (*repeats)[n]
+		*repeat = RepeatTime{Offsets: repeat.Offsets[:0]}
+		return repeat
+	}
+	repeat := &RepeatTime{}
+	*repeats = append(*repeats, repeat)
+	return repeat
+}
+
+// nextTimeZone is nextMediaDesc for time zones.
+func nextTimeZone(timeZones *[]*TimeZone) *TimeZone {
+	n := len(*timeZones)
+	if n < cap(*timeZones) && (*timeZones)[:n+1][n] != nil {
+		*timeZones = (*timeZones)[:n+1]
+		return (*timeZones)[n]
+	}
+	timeZone := &TimeZone{}
+	*timeZones = append(*timeZones, timeZone)
+	return timeZone
+}
+
+// nextBandwidth is nextMediaDesc for bandwidths.
+func nextBandwidth(bandwidths *[]*Bandwidth) *Bandwidth {
+	n := len(*bandwidths)
+	if n < cap(*bandwidths) && (*bandwidths)[:n+1][n] != nil {
+		*bandwidths = (*bandwidths)[:n+1]
+		return (*bandwidths)[n]
+	}
+	bandwidth := &Bandwidth{}
+	*bandwidths = append(*bandwidths, bandwidth)
+	return bandwidth
+}
+
+// nextEncryptionKey is nextMediaDesc for encryption keys.
+func nextEncryptionKey(keys *[]*EncryptionKey) *EncryptionKey {
+	n := len(*keys)
+	if n < cap(*keys) && (*keys)[:n+1][n] != nil {
+		*keys = (*keys)[:n+1]
+		return (*keys)[n]
+	}
+	key := &EncryptionKey{}
+	*keys = append(*keys, key)
+	return key
+}
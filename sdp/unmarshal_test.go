@@ -1,7 +1,10 @@
 package sdp
 
 import (
+	"bytes"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -40,6 +43,64 @@ a=rtpmap:8 PCMA/8000
 `,
 		Session: nil,
 	},
+
+	{
+		Name: "Media line without port",
+		Data: `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+m=audio
+`,
+	},
+
+	{
+		Name: "Media line without protocol",
+		Data: `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+m=audio 9
+`,
+	},
+
+	{
+		Name: "Zero number of ports",
+		Data: `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+m=audio 9/0 RTP/AVP 0
+`,
+	},
+
+	{
+		Name: "Wrong media bandwidth",
+		Data: `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+m=audio 9 RTP/AVP 0
+b=AS
+`,
+	},
+
+	{
+		Name: "Repeat interval out of range",
+		Data: "v=0\no=- 0 1 IN IP4 127.0.0.1\ns=-\nc=IN IP4 127.0.0.1\nt=0 0\nr=106751991167301d 1h\n",
+	},
+	{
+		Name: "CR inside a line",
+		Data: "v=0\no=- 0 1 IN IP4 127.0.0.1\ns=a\rb\nc=IN IP4 127.0.0.1\nt=0 0\n",
+	},
+	{
+		Name: "Attribute without name",
+		Data: "v=0\no=- 0 1 IN IP4 127.0.0.1\ns=-\nc=IN IP4 127.0.0.1\nt=0 0\na=:x\n",
+	},
 }
 
 var unmarshalTests = []*testVector{
@@ -322,7 +383,148 @@ func TestUnmarshal(t *testing.T) {
 }
 
 func FuzzDecode(f *testing.F) {
+	addFuzzSeeds(f)
 	f.Fuzz(func(t *testing.T, data string) {
 		NewDecoder(strings.NewReader(data)).Decode()
+
+		var s Session
+		Unmarshal([]byte(data), &s)
+		Unmarshal([]byte(data), &s)
 	})
 }
+
+func readCorpus(tb testing.TB) map[string][]byte {
+	files, err := filepath.Glob("testdata/*.sdp")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	corpus := make(map[string][]byte, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		corpus[filepath.Base(file)] = data
+	}
+	return corpus
+}
+
+func TestUnmarshalReuse(t *testing.T) {
+	var reused Session
+
+	corpus := readCorpus(t)
+	for _, name := range []string{
+		"chrome-offer.sdp", "firefox-offer.sdp", "seminar.sdp", "safari-offer.sdp", "chrome-offer.sdp", "seminar.sdp",
+	} {
+		expected, err := NewDecoder(bytes.NewReader(corpus[name])).Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Unmarshal(corpus[name], &reused); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(&reused, expected) {
+			t.Fatalf("%v: %v", name, cmp.Diff(&reused, expected))
+		}
+	}
+
+	for _, v := range unmarshalTests {
+		var s Session
+		if err := Unmarshal([]byte(v.Data), &s); err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(&s, v.Session) {
+			t.Fatalf("%v: %v", v.Name, cmp.Diff(&s, v.Session))
+		}
+	}
+
+	for _, name := range []string{"chrome-offer.sdp", "seminar.sdp"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			if err := Unmarshal(corpus[name], &reused); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs > 1 {
+			t.Fatalf("%v: too many allocations when reusing a session: %v", name, allocs)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for name, data := range readCorpus(b) {
+		data := data
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for name, data := range readCorpus(b) {
+		data := data
+		b.Run(name, func(b *testing.B) {
+			var s Session
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if err := Unmarshal(data, &s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestExtensionLines(t *testing.T) {
+	data := `v=0
+o=- 0 1 IN IP4 127.0.0.1
+s=-
+f=obsolete
+c=IN IP4 127.0.0.1
+t=0 0
+r=7d 1h 0
+y=session
+a=sendrecv
+m=audio 9 RTP/AVP 0
+y=0123456789
+a=rtcp-mux
+x=last
+`
+	s, err := NewDecoder(strings.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ExtensionLine{
+		{Type: 'f', Value: "obsolete", After: SessionNameField},
+		{Type: 'y', Value: "session", After: TimingField},
+	}
+	if !cmp.Equal(s.ExtensionLines, expected) {
+		t.Fatal(cmp.Diff(s.ExtensionLines, expected))
+	}
+	expected = []*ExtensionLine{
+		{Type: 'y', Value: "0123456789", After: MediaDescField},
+		{Type: 'x', Value: "last", After: AttributeField},
+	}
+	if !cmp.Equal(s.MediaDescs[0].ExtensionLines, expected) {
+		t.Fatal(cmp.Diff(s.MediaDescs[0].ExtensionLines, expected))
+	}
+
+	encoded, err := Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := strings.NewReplacer("7d 1h", "604800 3600", "m=audio 9 ", "m=audio 9/1 ").Replace(data); string(encoded) != res {
+		t.Fatal(cmp.Diff(string(encoded), res))
+	}
+
+	if _, err := NewDecoder(strings.NewReader("y=first\n" + data)).Decode(); err == nil {
+		t.Fatal("error was expected for an extension line before v=")
+	}
+}
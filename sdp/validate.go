@@ -0,0 +1,301 @@
+package sdp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type ValidationProfile int
+
+const (
+	// ProfileRFC8866 checks the syntax rules of the base SDP specification.
+	ProfileRFC8866 ValidationProfile = iota
+	// ProfileJSEP additionally checks what WebRTC endpoints require
+	// (RFC 8829): ICE credentials, DTLS fingerprint and setup role, mid and
+	// rtcp-mux on every media description.
+	ProfileJSEP
+	// ProfileSIP additionally checks the offer/answer rules of RFC 3264 as
+	// used by SIP endpoints.
+	ProfileSIP
+)
+
+const (
+	IceUfragAttribute    = "ice-ufrag"
+	IcePwdAttribute      = "ice-pwd"
+	FingerprintAttribute = "fingerprint"
+	SetupAttribute       = "setup"
+	RTCPMuxAttribute     = "rtcp-mux"
+	BundleOnlyAttribute  = "bundle-only"
+)
+
+const (
+	iceUfragMinLength = 4
+	icePwdMinLength   = 22
+	iceMaxLength      = 256
+	maxPort           = 65535
+)
+
+// SessionLevel is the Media index of violations found outside media
+// descriptions.
+const SessionLevel = -1
+
+// ValidationError is a single rule violation. Media is the index of the
+// offending media description or SessionLevel, Field is the type of the
+// offending line and Attribute the attribute name for a= lines.
+type ValidationError struct {
+	Media     int
+	Field     byte
+	Attribute string
+	Message   string
+}
+
+func (e *ValidationError) Error() string {
+	var where string
+	if e.Media == SessionLevel {
+		where = "session"
+	} else {
+		where = "media " + strconv.Itoa(e.Media)
+	}
+	if e.Field != 0 {
+		where += " " + string(e.Field) + "="
+	}
+	if e.Attribute != "" {
+		where += e.Attribute
+	}
+	return where + ": " + e.Message
+}
+
+// ValidationErrors is the list of all violations found in a session.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+type validator struct {
+	s    *Session
+	errs ValidationErrors
+}
+
+func (v *validator) fail(media int, field byte, attribute string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Media:     media,
+		Field:     field,
+		Attribute: attribute,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks the session against the rules of the given profile and
+// returns ValidationErrors with every violation found, or nil.
+func (s *Session) Validate(profile ValidationProfile) error {
+	v := &validator{s: s}
+
+	v.validateBase()
+	switch profile {
+	case ProfileJSEP:
+		v.validateJSEP()
+	case ProfileSIP:
+		v.validateSIP()
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *validator) validateBase() {
+	s := v.s
+
+	if s.Version != 0 {
+		v.fail(SessionLevel, VersionField, "", "unsupported version %v", s.Version)
+	}
+	if s.Originator == nil {
+		v.fail(SessionLevel, OriginField, "", "origin is required")
+	} else {
+		if s.Originator.Username == "" || strings.ContainsAny(s.Originator.Username, " \t") {
+			v.fail(SessionLevel, OriginField, "", "wrong username %q", s.Originator.Username)
+		}
+		if s.Originator.Nettype == "" || s.Originator.Addrtype == "" || s.Originator.UnicastAddress == "" {
+			v.fail(SessionLevel, OriginField, "", "network type, address type and address are required")
+		}
+	}
+	if s.SessionName == "" {
+		v.fail(SessionLevel, SessionNameField, "", "session name is required")
+	}
+	if len(s.Timings) == 0 {
+		v.fail(SessionLevel, TimingField, "", "at least one timing is required")
+	}
+	for _, timing := range s.Timings {
+		if timing.Stop != 0 && timing.Stop < timing.Start {
+			v.fail(SessionLevel, TimingField, "", "stop time %v is before start time %v", timing.Stop, timing.Start)
+		}
+		for _, repeat := range timing.RepeatTimes {
+			if repeat.Interval <= 0 || repeat.Duration <= 0 {
+				v.fail(SessionLevel, RepeatTimeField, "", "repeat interval and duration must be positive")
+			}
+		}
+	}
+
+	if s.ConnectionData != nil {
+		v.validateConnection(SessionLevel, s.ConnectionData)
+	}
+	if !s.hasConnections() {
+		v.fail(SessionLevel, ConnectionDataField, "",
+			"a c= field is required at the session level or in every media description")
+	}
+	v.validateBandwidths(SessionLevel, s.Bandwidths)
+	v.validateAttributes(SessionLevel, s.Attributes)
+
+	for i, desc := range s.MediaDescs {
+		if desc.Media == "" {
+			v.fail(i, MediaDescField, "", "media type is required")
+		}
+		if desc.Port < 0 || desc.Port > maxPort {
+			v.fail(i, MediaDescField, "", "port %v out of range", desc.Port)
+		}
+		if len(desc.Proto) == 0 {
+			v.fail(i, MediaDescField, "", "transport protocol is required")
+		}
+		if len(desc.Fmts) == 0 {
+			v.fail(i, MediaDescField, "", "at least one format is required")
+		}
+		for _, connection := range desc.Connections {
+			v.validateConnection(i, connection)
+		}
+		v.validateBandwidths(i, desc.Bandwidths)
+		v.validateAttributes(i, desc.Attributes)
+	}
+}
+
+// hasConnections reports whether there is either a session-level c= field
+// or a c= field in every media description.
+func (s *Session) hasConnections() bool {
+	if s.ConnectionData != nil {
+		return true
+	}
+	for _, desc := range s.MediaDescs {
+		if len(desc.Connections) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *validator) validateConnection(media int, connection *Connection) {
+	if connection.Nettype == "" || connection.Addrtype == "" || connection.ConnectionAddr == "" {
+		v.fail(media, ConnectionDataField, "", "network type, address type and address are required")
+	}
+	if connection.TTL < 0 || connection.TTL > 255 {
+		v.fail(media, ConnectionDataField, "", "ttl %v out of range", connection.TTL)
+	}
+	if connection.TTL > 0 && connection.Addrtype == TypeIPv6 {
+		v.fail(media, ConnectionDataField, "", "ttl must not be present for IP6 addresses")
+	}
+}
+
+func (v *validator) validateBandwidths(media int, bandwidths []*Bandwidth) {
+	for _, bandwidth := range bandwidths {
+		if bandwidth.Type == "" || bandwidth.Value < 0 {
+			v.fail(media, BandwidthField, "", "wrong bandwidth %v:%v", bandwidth.Type, bandwidth.Value)
+		}
+	}
+}
+
+func (v *validator) validateAttributes(media int, attributes Attributes) {
+	for _, attribute := range attributes {
+		if attribute.Name == "" || strings.ContainsAny(attribute.Name, " \t:") {
+			v.fail(media, AttributeField, attribute.Name, "wrong attribute name %q", attribute.Name)
+		}
+	}
+}
+
+// attribute returns the media-level attribute, falling back to the
+// session-level one.
+func (v *validator) attribute(desc *MediaDesc, name string) (string, bool) {
+	if value, ok := desc.Attributes.Get(name); ok {
+		return value, ok
+	}
+	return v.s.Attributes.Get(name)
+}
+
+func (v *validator) validateJSEP() {
+	mids := make(map[string]bool)
+	// The transport attributes of a BUNDLE group are only required in its
+	// tagged m-section, the first one of the group (RFC 8843, section 7).
+	bundled := make(map[string]bool)
+	for _, group := range v.s.Groups(BundleSemantics) {
+		for i := 1; i < len(group); i++ {
+			bundled[group[i]] = true
+		}
+	}
+
+	for i, desc := range v.s.MediaDescs {
+		mid := desc.Mid()
+		if mid == "" {
+			v.fail(i, AttributeField, MidAttribute, "mid is required")
+		} else if mids[mid] {
+			v.fail(i, AttributeField, MidAttribute, "duplicate mid %v", mid)
+		}
+		mids[mid] = true
+
+		if desc.Port == 0 && !desc.Attributes.Has(BundleOnlyAttribute) || mid != "" && bundled[mid] {
+			continue
+		}
+
+		ufrag, ok := v.attribute(desc, IceUfragAttribute)
+		if !ok {
+			v.fail(i, AttributeField, IceUfragAttribute, "ice-ufrag is required")
+		} else if len(ufrag) < iceUfragMinLength || len(ufrag) > iceMaxLength {
+			v.fail(i, AttributeField, IceUfragAttribute, "ice-ufrag length must be between %v and %v",
+				iceUfragMinLength, iceMaxLength)
+		}
+		pwd, ok := v.attribute(desc, IcePwdAttribute)
+		if !ok {
+			v.fail(i, AttributeField, IcePwdAttribute, "ice-pwd is required")
+		} else if len(pwd) < icePwdMinLength || len(pwd) > iceMaxLength {
+			v.fail(i, AttributeField, IcePwdAttribute, "ice-pwd length must be between %v and %v",
+				icePwdMinLength, iceMaxLength)
+		}
+		if _, ok := v.attribute(desc, FingerprintAttribute); !ok {
+			v.fail(i, AttributeField, FingerprintAttribute, "fingerprint is required")
+		}
+		if setup, ok := v.attribute(desc, SetupAttribute); !ok {
+			v.fail(i, AttributeField, SetupAttribute, "setup is required")
+		} else if !inSet(setup, []string{"active", "passive", "actpass"}) {
+			v.fail(i, AttributeField, SetupAttribute, "wrong setup role %v", setup)
+		}
+		if inSet(RTPproto, desc.Proto) && !desc.Attributes.Has(RTCPMuxAttribute) {
+			v.fail(i, AttributeField, RTCPMuxAttribute, "rtcp-mux is required")
+		}
+	}
+
+	for _, group := range v.s.Groups(BundleSemantics) {
+		for _, mid := range group {
+			if !mids[mid] {
+				v.fail(SessionLevel, AttributeField, GroupAttribute, "unknown mid %v in BUNDLE group", mid)
+			}
+		}
+	}
+}
+
+func (v *validator) validateSIP() {
+	if len(v.s.Timings) != 1 || v.s.Timings[0].Start != 0 || v.s.Timings[0].Stop != 0 {
+		v.fail(SessionLevel, TimingField, "", "a single t=0 0 timing is required")
+	}
+
+	for i, desc := range v.s.MediaDescs {
+		if !inSet(RTPproto, desc.Proto) {
+			continue
+		}
+		if _, err := desc.Codecs(); err != nil {
+			v.fail(i, MediaDescField, "", "%v", err)
+		}
+	}
+}
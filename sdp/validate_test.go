@@ -0,0 +1,126 @@
+package sdp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const jsepOffer = `v=0
+o=- 4611731400430051336 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0 1
+m=audio 9 UDP/TLS/RTP/SAVPF 111
+c=IN IP4 0.0.0.0
+a=ice-ufrag:EsAw
+a=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1
+a=fingerprint:sha-256 D2:FA:0E:C3:22:59:5E:14:95:69:92:3D:13:B4:84:24:2C:C2:A2:C0:3E:FD:34:8E:5E:EA:6F:AF:52:CE:E6:0F
+a=setup:actpass
+a=mid:0
+a=sendrecv
+a=rtcp-mux
+a=rtpmap:111 opus/48000/2
+m=application 9 UDP/DTLS/SCTP webrtc-datachannel
+c=IN IP4 0.0.0.0
+a=ice-ufrag:EsAw
+a=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1
+a=fingerprint:sha-256 D2:FA:0E:C3:22:59:5E:14:95:69:92:3D:13:B4:84:24:2C:C2:A2:C0:3E:FD:34:8E:5E:EA:6F:AF:52:CE:E6:0F
+a=setup:actpass
+a=mid:1
+a=sctp-port:5000
+`
+
+func TestValidate(t *testing.T) {
+	s, err := NewDecoder(strings.NewReader(jsepOffer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, profile := range []ValidationProfile{ProfileRFC8866, ProfileJSEP} {
+		if err := s.Validate(profile); err != nil {
+			t.Fatalf("profile %v: %v", profile, err)
+		}
+	}
+
+	s.MediaDescs[0].Attributes.Remove(RTCPMuxAttribute, IcePwdAttribute)
+	s.MediaDescs[1].Attributes.Set(SetupAttribute, "holdconn")
+	s.MediaDescs[1].Attributes.Remove(MidAttribute)
+
+	err = s.Validate(ProfileJSEP)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("validation errors were expected, got %v", err)
+	}
+
+	expected := ValidationErrors{
+		{Media: 0, Field: AttributeField, Attribute: IcePwdAttribute, Message: "ice-pwd is required"},
+		{Media: 0, Field: AttributeField, Attribute: RTCPMuxAttribute, Message: "rtcp-mux is required"},
+		{Media: 1, Field: AttributeField, Attribute: MidAttribute, Message: "mid is required"},
+		{Media: 1, Field: AttributeField, Attribute: SetupAttribute, Message: "wrong setup role holdconn"},
+		{Media: SessionLevel, Field: AttributeField, Attribute: GroupAttribute, Message: "unknown mid 1 in BUNDLE group"},
+	}
+	if !cmp.Equal(errs, expected) {
+		t.Fatal(cmp.Diff(errs, expected))
+	}
+	if errs[0].Error() != "media 0 a=ice-pwd: ice-pwd is required" {
+		t.Fatalf("wrong error message: %v", errs[0].Error())
+	}
+}
+
+func TestValidateBundledOffer(t *testing.T) {
+	// In a subsequent offer the bundled m-sections leave out the transport
+	// attributes of the tagged one.
+	s, err := NewDecoder(strings.NewReader(jsepOffer)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MediaDescs[1].Attributes.Remove(IceUfragAttribute, IcePwdAttribute, FingerprintAttribute, SetupAttribute)
+	if err := s.Validate(ProfileJSEP); err != nil {
+		t.Fatal(err)
+	}
+
+	s.MediaDescs[0].Attributes.Remove(FingerprintAttribute)
+	expected := ValidationErrors{
+		{Media: 0, Field: AttributeField, Attribute: FingerprintAttribute, Message: "fingerprint is required"},
+	}
+	var errs ValidationErrors
+	if err := s.Validate(ProfileJSEP); !errors.As(err, &errs) || !cmp.Equal(errs, expected) {
+		t.Fatalf("wrong errors: %v", err)
+	}
+}
+
+func TestValidateBase(t *testing.T) {
+	s := &Session{
+		Version:    1,
+		MediaDescs: []*MediaDesc{{Media: "audio", Port: 70000, Proto: []string{RTPproto, AVPproto}}},
+	}
+
+	var errs ValidationErrors
+	if !errors.As(s.Validate(ProfileRFC8866), &errs) {
+		t.Fatal("validation errors were expected")
+	}
+	if len(errs) != 7 {
+		t.Fatalf("wrong number of errors: %v", errs)
+	}
+
+	sip := &Session{
+		Originator:     &Origin{Username: "-", Nettype: NetworkInternet, Addrtype: TypeIPv4, UnicastAddress: "10.0.0.1"},
+		SessionName:    "-",
+		ConnectionData: &Connection{Nettype: NetworkInternet, Addrtype: TypeIPv4, ConnectionAddr: "10.0.0.1"},
+		Timings:        []*Timing{{Start: 0, Stop: 0}},
+		MediaDescs: []*MediaDesc{{
+			Media: "audio", Port: 4000, Proto: []string{RTPproto, AVPproto}, Fmts: []string{"0", "101"},
+			Attributes: Attributes{{Name: "rtpmap", Value: "101 telephone-event/8000"}},
+		}},
+	}
+	if err := sip.Validate(ProfileSIP); err != nil {
+		t.Fatal(err)
+	}
+	sip.MediaDescs[0].Fmts = append(sip.MediaDescs[0].Fmts, "102")
+	if err := sip.Validate(ProfileSIP); err == nil {
+		t.Fatal("missing rtpmap error was expected")
+	}
+}
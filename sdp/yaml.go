@@ -0,0 +1,69 @@
+package sdp
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The YAML form of a session is its JSON form written as block YAML: the
+// same keys in the same order. Strings are double-quoted, so that values
+// such as the session id stay strings. UnmarshalYAML reads any YAML document
+// with the structure of the JSON form.
+
+const yamlIndent = 2
+
+// MarshalYAML returns the YAML form of the session.
+func MarshalYAML(s *Session) ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, so it is read as a node tree with the key order kept
+	// and written back in block style.
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	setBlockStyle(&root)
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(yamlIndent)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// setBlockStyle writes the non-empty mappings and sequences of a node in
+// block style, and the keys of mappings unquoted where YAML allows it.
+func setBlockStyle(node *yaml.Node) {
+	if (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && len(node.Content) > 0 {
+		node.Style &^= yaml.FlowStyle
+	}
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			child.Style = 0
+		}
+		setBlockStyle(child)
+	}
+}
+
+// UnmarshalYAML parses the YAML form of a session.
+func UnmarshalYAML(data []byte, s *Session) error {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	// The YAML values are converted to JSON, which decodes the session.
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, s)
+}
@@ -0,0 +1,132 @@
+package sdp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestYAMLRoundTrip(t *testing.T) {
+	for _, v := range append(unmarshalTests, &testVector{Name: "JSEP offer", Data: jsepOffer}) {
+		v := v
+		t.Run(v.Name, func(t *testing.T) {
+			sess, err := NewDecoder(strings.NewReader(v.Data)).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := MarshalYAML(sess)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var res Session
+			if err := UnmarshalYAML(data, &res); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(&res, sess) {
+				t.Fatal(cmp.Diff(&res, sess))
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	var sess Session
+	err := UnmarshalYAML([]byte(`version: 0
+origin: {username: alice, sessionId: "1", sessionVersion: "2", netType: IN, addrType: IP4, address: 192.0.2.1}
+sessionName: call
+media:
+- media: audio
+  port: 9
+  portCount: 0
+  proto: [RTP, AVP]
+  formats: ["0"]
+`), &sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Session{
+		Originator: &Origin{
+			Username: "alice", SessID: 1, SessVersion: 2,
+			Nettype: "IN", Addrtype: "IP4", UnicastAddress: "192.0.2.1",
+		},
+		SessionName: "call",
+		MediaDescs:  []*MediaDesc{{Media: "audio", Port: 9, Proto: []string{"RTP", "AVP"}, Fmts: []string{"0"}}},
+	}
+	if !cmp.Equal(&sess, expected) {
+		t.Fatal(cmp.Diff(&sess, expected))
+	}
+
+	for _, data := range []string{"version: [", "version: x", "media: 1"} {
+		if err := UnmarshalYAML([]byte(data), &Session{}); err == nil {
+			t.Fatalf("%q: error was expected", data)
+		}
+	}
+}
+
+func TestYAMLForm(t *testing.T) {
+	sess, err := NewDecoder(strings.NewReader(`v=0
+o=- 4611731400430051336 2 IN IP4 127.0.0.1
+s=-
+c=IN IP4 127.0.0.1
+t=0 0
+m=audio 9 RTP/AVP 0
+a=mid:a
+a=sendonly
+a=x-unknown:value: "quoted"
+`)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MarshalYAML(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `version: 0
+origin:
+  username: "-"
+  sessionId: "4611731400430051336"
+  sessionVersion: "2"
+  netType: "IN"
+  addrType: "IP4"
+  address: "127.0.0.1"
+sessionName: "-"
+connection:
+  netType: "IN"
+  addrType: "IP4"
+  address: "127.0.0.1"
+  addressCount: 1
+timings:
+  - start: 0
+    stop: 0
+media:
+  - media: "audio"
+    port: 9
+    portCount: 1
+    proto:
+      - "RTP"
+      - "AVP"
+    formats:
+      - "0"
+    attributes:
+      - name: "mid"
+        value: "a"
+      - name: "sendonly"
+      - name: "x-unknown"
+        value: "value: \"quoted\""
+    mid: "a"
+    direction: "sendonly"
+    codecs:
+      - payloadType: 0
+        name: "PCMU"
+        clockRate: 8000
+        channels: 1
+`
+	if string(data) != expected {
+		t.Fatal(cmp.Diff(string(data), expected))
+	}
+}
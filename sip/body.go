@@ -0,0 +1,158 @@
+// Package sip handles session descriptions in the bodies of SIP messages
+// (RFC 3261) and the offer/answer exchange they take part in (RFC 6337).
+package sip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const (
+	SDPContentType       = "application/sdp"
+	MultipartContentType = "multipart/mixed"
+)
+
+// ErrNoSDP is returned by ExtractSDP if the body has no application/sdp part.
+var ErrNoSDP = errors.New("sip: no application/sdp body")
+
+// Part is a body or a part of a multipart body. Header holds the headers of
+// the part other than Content-Type, e.g. Content-Disposition.
+type Part struct {
+	ContentType string
+	Header      textproto.MIMEHeader
+	Content     []byte
+}
+
+// ParseBody returns the parts of a body with the given Content-Type. A
+// multipart body, even nested, is flattened into its parts, any other body is
+// a single part.
+func ParseBody(contentType string, body []byte) ([]*Part, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("wrong content type %q: %v", contentType, err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return []*Part{{ContentType: contentType, Content: body}}, nil
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, fmt.Errorf("wrong content type %q: no boundary", contentType)
+	}
+	var res []*Part
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("wrong multipart body: %v", err)
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("wrong multipart body: %v", err)
+		}
+
+		header := textproto.MIMEHeader(part.Header)
+		partType := header.Get("Content-Type")
+		if partType == "" {
+			// The default of RFC 2046.
+			partType = "text/plain"
+		}
+		header.Del("Content-Type")
+		if len(header) == 0 {
+			header = nil
+		}
+
+		parts, err := ParseBody(partType, content)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 1 && parts[0].Header == nil {
+			parts[0].Header = header
+		}
+		res = append(res, parts...)
+	}
+}
+
+// ExtractSDP decodes the first application/sdp part of a body with the given
+// Content-Type.
+func ExtractSDP(contentType string, body []byte) (*sdp.Session, error) {
+	parts, err := ParseBody(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if isSDP(part.ContentType) {
+			return sdp.NewDecoder(bytes.NewReader(part.Content)).Decode()
+		}
+	}
+	return nil, ErrNoSDP
+}
+
+func isSDP(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == SDPContentType
+}
+
+// Body is the body of a SIP message with its Content-Type.
+type Body struct {
+	ContentType string
+	Content     []byte
+}
+
+// NewBody returns the body carrying the session description, as a
+// multipart/mixed body if there are other parts, e.g. ISUP, after it.
+func NewBody(s *sdp.Session, parts ...*Part) (*Body, error) {
+	content, err := sdp.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return &Body{ContentType: SDPContentType, Content: content}, nil
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	parts = append([]*Part{{ContentType: SDPContentType, Content: content}}, parts...)
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader, len(part.Header)+1)
+		header.Set("Content-Type", part.ContentType)
+		for name, values := range part.Header {
+			header[name] = values
+		}
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(part.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	contentType := mime.FormatMediaType(MultipartContentType, map[string]string{"boundary": w.Boundary()})
+	return &Body{ContentType: contentType, Content: buf.Bytes()}, nil
+}
+
+func (b *Body) ContentLength() int {
+	return len(b.Content)
+}
+
+// Header returns the Content-Type and Content-Length header fields of the
+// body, each ending with CRLF.
+func (b *Body) Header() string {
+	return "Content-Type: " + b.ContentType + "\r\n" +
+		"Content-Length: " + strconv.Itoa(b.ContentLength()) + "\r\n"
+}
@@ -0,0 +1,147 @@
+package sip
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+const testSDP = "v=0\r\n" +
+	"o=- 1 1 IN IP4 192.0.2.1\r\n" +
+	"s=-\r\n" +
+	"c=IN IP4 192.0.2.1\r\n" +
+	"t=0 0\r\n" +
+	"m=audio 49170 RTP/AVP 8\r\n" +
+	"a=rtpmap:8 PCMA/8000\r\n"
+
+// testISUP is an IAM with bytes that are not text.
+const testISUP = "\x01\x00\x49\x00\x00\x03\x02\x00\x07\x04\x10\x00\x33\x63\x21\x43\x00\x00\x03"
+
+func testSession(t *testing.T, text string) *sdp.Session {
+	t.Helper()
+	s, err := sdp.NewDecoder(strings.NewReader(text)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestExtractSDP(t *testing.T) {
+	expected := testSession(t, testSDP)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"sdp", "application/sdp", testSDP},
+		{"case", "Application/SDP", testSDP},
+		{"multipart", "multipart/mixed;boundary=unique-boundary-1",
+			"--unique-boundary-1\r\n" +
+				"Content-Type: application/ISUP;version=itu-t92+\r\n" +
+				"Content-Disposition: signal;handling=optional\r\n" +
+				"\r\n" +
+				testISUP + "\r\n" +
+				"--unique-boundary-1\r\n" +
+				"Content-Type: application/sdp\r\n" +
+				"\r\n" +
+				testSDP + "\r\n" +
+				"--unique-boundary-1--\r\n"},
+		{"nested", `multipart/mixed; boundary="outer"`,
+			"--outer\r\n" +
+				"Content-Type: application/pidf+xml\r\n" +
+				"\r\n" +
+				"<presence/>\r\n" +
+				"--outer\r\n" +
+				"Content-Type: multipart/alternative; boundary=inner\r\n" +
+				"\r\n" +
+				"--inner\r\n" +
+				"Content-Type: application/sdp\r\n" +
+				"\r\n" +
+				testSDP + "\r\n" +
+				"--inner--\r\n" +
+				"\r\n" +
+				"--outer--\r\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ExtractSDP(test.contentType, []byte(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, s); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestExtractSDPErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"no sdp", "application/pidf+xml", "<presence/>"},
+		{"no content type", "", testSDP},
+		{"no boundary", "multipart/mixed", testSDP},
+		{"wrong multipart", "multipart/mixed;boundary=b", "--b\r\nContent-Type: application/sdp\r\n\r\n" + testSDP},
+		{"wrong sdp", "application/sdp", "v=1\r\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ExtractSDP(test.contentType, []byte(test.body)); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+	if _, err := ExtractSDP("text/plain", nil); !errors.Is(err, ErrNoSDP) {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestNewBody(t *testing.T) {
+	s := testSession(t, testSDP)
+	content, err := sdp.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := NewBody(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("Content-Type: application/sdp\r\nContent-Length: %d\r\n", len(content))
+	if body.Header() != expected {
+		t.Fatalf("wrong header: %q", body.Header())
+	}
+	if string(body.Content) != string(content) {
+		t.Fatalf("wrong content: %q", body.Content)
+	}
+
+	isup := &Part{
+		ContentType: "application/ISUP;version=itu-t92+",
+		Header:      map[string][]string{"Content-Disposition": {"signal;handling=optional"}},
+		Content:     []byte(testISUP),
+	}
+	body, err = NewBody(s, isup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body.ContentType, "multipart/mixed; boundary=") {
+		t.Fatalf("wrong content type: %v", body.ContentType)
+	}
+	parts, err := ParseBody(body.ContentType, body.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedParts := []*Part{
+		{ContentType: "application/sdp", Content: content},
+		isup,
+	}
+	if diff := cmp.Diff(expectedParts, parts); diff != "" {
+		t.Fatal(diff)
+	}
+}
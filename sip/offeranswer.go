@@ -0,0 +1,278 @@
+package sip
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+// Message is a SIP message of a dialog that can carry a session description.
+type Message int
+
+const (
+	Invite Message = iota
+	// Provisional is a 1xx response to INVITE sent unreliably, e.g. 183.
+	Provisional
+	// ReliableProvisional is a 1xx response to INVITE sent reliably
+	// (RFC 3262).
+	ReliableProvisional
+	// InviteSuccess is a 2xx response to INVITE.
+	InviteSuccess
+	Ack
+	Prack
+	// PrackSuccess is a 2xx response to PRACK.
+	PrackSuccess
+	Update
+	// UpdateSuccess is a 2xx response to UPDATE.
+	UpdateSuccess
+	// UpdateFailure is a non-2xx final response to UPDATE, e.g. 491, which
+	// withdraws its offer.
+	UpdateFailure
+	// InviteFailure is a non-2xx final response to INVITE, e.g. 488 or 491
+	// to a re-INVITE, which withdraws its offer.
+	InviteFailure
+)
+
+var messageNames = map[Message]string{
+	Invite:              "INVITE",
+	Provisional:         "unreliable 1xx to INVITE",
+	ReliableProvisional: "reliable 1xx to INVITE",
+	InviteSuccess:       "2xx to INVITE",
+	Ack:                 "ACK",
+	Prack:               "PRACK",
+	PrackSuccess:        "2xx to PRACK",
+	Update:              "UPDATE",
+	UpdateSuccess:       "2xx to UPDATE",
+	UpdateFailure:       "failure response to UPDATE",
+	InviteFailure:       "failure response to INVITE",
+}
+
+func (m Message) String() string {
+	if name, ok := messageNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Message(%d)", int(m))
+}
+
+// Role is the part a session description plays in an offer/answer exchange.
+type Role int
+
+const (
+	// NoRole is the role of a missing description, or of one that repeats
+	// the last offer or answer.
+	NoRole Role = iota
+	Offer
+	Answer
+)
+
+// OfferAnswer tracks the offer/answer exchanges of a dialog (RFC 6337) from
+// the messages of both sides. The zero value is a dialog before its INVITE.
+type OfferAnswer struct {
+	// Offer and Answer are the last offer and answer.
+	Offer  *sdp.Session
+	Answer *sdp.Session
+
+	// pending is the message with the offer that is not answered yet.
+	pending    Message
+	hasPending bool
+	// offerless is set from an INVITE without an offer until a response to
+	// it makes the offer.
+	offerless bool
+	// early is the answer sent in an unreliable 1xx, which later responses
+	// to the INVITE must repeat.
+	early []byte
+
+	offerText  []byte
+	answerText []byte
+	// agreed are the offer and answer of the last completed exchange, which
+	// a failure response restores when it withdraws an offer.
+	agreed struct {
+		offer, answer         *sdp.Session
+		offerText, answerText []byte
+	}
+}
+
+// Pending returns the message with the offer that is not answered yet.
+func (o *OfferAnswer) Pending() (Message, bool) {
+	return o.pending, o.hasPending
+}
+
+// Process returns the role of the session description s of a message, nil if
+// the message has none. It returns an error if the message breaks the
+// offer/answer rules, e.g. a new offer before the last one is answered or a 2xx without
+// the answer to the offer of the INVITE. A message that is rejected does not
+// change the state.
+func (o *OfferAnswer) Process(m Message, s *sdp.Session) (Role, error) {
+	switch m {
+	case Invite:
+		if s == nil {
+			if o.hasPending {
+				return NoRole, fmt.Errorf("%v while the offer of %v is not answered", m, o.pending)
+			}
+			o.offerless = true
+			return NoRole, nil
+		}
+		return o.offer(m, s)
+	case Provisional:
+		switch {
+		case s == nil:
+			return NoRole, nil
+		case o.isPending(Invite):
+			text, err := o.checkEarly(m, s)
+			if err != nil {
+				return NoRole, err
+			}
+			o.early = text
+			o.Answer, o.answerText = s, text
+			return Answer, nil
+		case o.offerless:
+			return NoRole, fmt.Errorf("offer in an %v", m)
+		}
+	case ReliableProvisional:
+		switch {
+		case s == nil:
+			return NoRole, nil
+		case o.isPending(Invite):
+			text, err := o.checkEarly(m, s)
+			if err != nil {
+				return NoRole, err
+			}
+			return o.answer(s, text), nil
+		case o.offerless:
+			return o.offer(m, s)
+		}
+	case InviteSuccess:
+		switch {
+		case o.isPending(Invite):
+			if s == nil {
+				return NoRole, o.missingAnswer(m)
+			}
+			text, err := o.checkEarly(m, s)
+			if err != nil {
+				return NoRole, err
+			}
+			return o.answer(s, text), nil
+		case o.offerless:
+			if s == nil {
+				return NoRole, fmt.Errorf("%v without an offer to the INVITE without one", m)
+			}
+			return o.offer(m, s)
+		}
+	case Ack, PrackSuccess, UpdateSuccess:
+		request := map[Message]Message{Ack: InviteSuccess, PrackSuccess: Prack, UpdateSuccess: Update}[m]
+		if o.isPending(request) {
+			if s == nil {
+				return NoRole, o.missingAnswer(m)
+			}
+			return o.marshalAnswer(s)
+		}
+	case Prack:
+		if o.isPending(ReliableProvisional) {
+			if s == nil {
+				return NoRole, o.missingAnswer(m)
+			}
+			return o.marshalAnswer(s)
+		}
+		if s != nil && !o.repeats(s) {
+			return o.offer(m, s)
+		}
+	case Update:
+		if s != nil {
+			return o.offer(m, s)
+		}
+	case UpdateFailure:
+		if o.isPending(Update) {
+			o.withdraw()
+		}
+		return NoRole, nil
+	case InviteFailure:
+		// The offer of a reliable 1xx to an INVITE without one ends with
+		// the INVITE as well.
+		if o.isPending(Invite) || o.isPending(ReliableProvisional) {
+			o.withdraw()
+		}
+		o.offerless = false
+		o.early = nil
+		return NoRole, nil
+	default:
+		return NoRole, fmt.Errorf("unknown message %v", m)
+	}
+	if s != nil && !o.repeats(s) {
+		return NoRole, fmt.Errorf("session description in %v is neither an offer nor an answer", m)
+	}
+	return NoRole, nil
+}
+
+func (o *OfferAnswer) isPending(m Message) bool {
+	return o.hasPending && o.pending == m
+}
+
+func (o *OfferAnswer) missingAnswer(m Message) error {
+	return fmt.Errorf("%v without the answer to the offer of %v", m, o.pending)
+}
+
+func (o *OfferAnswer) offer(m Message, s *sdp.Session) (Role, error) {
+	if o.hasPending {
+		return NoRole, fmt.Errorf("offer in %v while the offer of %v is not answered", m, o.pending)
+	}
+	if o.offerless && m != ReliableProvisional && m != InviteSuccess {
+		return NoRole, fmt.Errorf("offer in %v before the offer to the INVITE without one", m)
+	}
+	text, err := sdp.Marshal(s)
+	if err != nil {
+		return NoRole, err
+	}
+	o.Offer, o.offerText = s, text
+	o.pending, o.hasPending = m, true
+	o.offerless = false
+	o.early = nil
+	return Offer, nil
+}
+
+func (o *OfferAnswer) answer(s *sdp.Session, text []byte) Role {
+	o.Answer, o.answerText = s, text
+	o.hasPending = false
+	o.agreed.offer, o.agreed.offerText = o.Offer, o.offerText
+	o.agreed.answer, o.agreed.answerText = s, text
+	return Answer
+}
+
+// withdraw drops the pending offer, and an early answer to it, for the last
+// agreed offer and answer.
+func (o *OfferAnswer) withdraw() {
+	o.Offer, o.offerText = o.agreed.offer, o.agreed.offerText
+	o.Answer, o.answerText = o.agreed.answer, o.agreed.answerText
+	o.hasPending = false
+}
+
+func (o *OfferAnswer) marshalAnswer(s *sdp.Session) (Role, error) {
+	text, err := sdp.Marshal(s)
+	if err != nil {
+		return NoRole, err
+	}
+	return o.answer(s, text), nil
+}
+
+// checkEarly returns the text of an answer to the offer of the INVITE, which
+// must be the same as an answer sent in an unreliable 1xx before.
+func (o *OfferAnswer) checkEarly(m Message, s *sdp.Session) ([]byte, error) {
+	text, err := sdp.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	if o.early != nil && !bytes.Equal(text, o.early) {
+		return nil, fmt.Errorf("answer in %v differs from the answer in an earlier %v", m, Provisional)
+	}
+	return text, nil
+}
+
+// repeats reports whether a description is the same as the last offer or
+// answer, which e.g. 1xx and 2xx responses to the same INVITE may repeat.
+func (o *OfferAnswer) repeats(s *sdp.Session) bool {
+	text, err := sdp.Marshal(s)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(text, o.offerText) || bytes.Equal(text, o.answerText)
+}
@@ -0,0 +1,172 @@
+package sip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nostressdev/webrtc/sdp"
+)
+
+type step struct {
+	message Message
+	// sdp is "offer", "answer" or "other", the description of the message,
+	// or empty if it has none.
+	sdp string
+	// role is the expected role, or -1 if the message must be rejected.
+	role Role
+}
+
+func TestOfferAnswer(t *testing.T) {
+	descriptions := map[string]*sdp.Session{
+		"offer":  testSession(t, testSDP),
+		"answer": testSession(t, strings.Replace(testSDP, "192.0.2.1", "198.51.100.1", -1)),
+		"other":  testSession(t, strings.Replace(testSDP, "49170", "50000", -1)),
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"basic", []step{
+			{Invite, "offer", Offer},
+			{Provisional, "", NoRole},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+		}},
+		{"early media", []step{
+			{Invite, "offer", Offer},
+			{Provisional, "answer", Answer},
+			{Provisional, "answer", Answer},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+		}},
+		{"changed early answer", []step{
+			{Invite, "offer", Offer},
+			{Provisional, "answer", Answer},
+			{InviteSuccess, "other", -1},
+			{InviteSuccess, "", -1},
+			{InviteSuccess, "answer", Answer},
+		}},
+		{"reliable answer", []step{
+			{Invite, "offer", Offer},
+			{ReliableProvisional, "answer", Answer},
+			{Prack, "", NoRole},
+			{PrackSuccess, "", NoRole},
+			{InviteSuccess, "answer", NoRole},
+			{InviteSuccess, "", NoRole},
+			{Ack, "", NoRole},
+		}},
+		{"offerless invite", []step{
+			{Invite, "", NoRole},
+			{Provisional, "offer", -1},
+			{Update, "offer", -1},
+			{InviteSuccess, "", -1},
+			{InviteSuccess, "offer", Offer},
+			{Ack, "", -1},
+			{Ack, "answer", Answer},
+		}},
+		{"offerless invite with reliable offer", []step{
+			{Invite, "", NoRole},
+			{ReliableProvisional, "offer", Offer},
+			{InviteSuccess, "other", -1},
+			{Prack, "answer", Answer},
+			{PrackSuccess, "", NoRole},
+			{InviteSuccess, "offer", NoRole},
+		}},
+		{"update", []step{
+			{Invite, "offer", Offer},
+			{ReliableProvisional, "answer", Answer},
+			{Update, "other", Offer},
+			{Update, "offer", -1},
+			{InviteSuccess, "offer", -1},
+			{UpdateSuccess, "", -1},
+			{UpdateSuccess, "answer", Answer},
+			{InviteSuccess, "", NoRole},
+			{Ack, "offer", -1},
+		}},
+		{"update glare", []step{
+			{Invite, "offer", Offer},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+			{Update, "other", Offer},
+			{Update, "offer", -1},
+			{UpdateFailure, "", NoRole},
+			{Update, "offer", Offer},
+			{UpdateSuccess, "answer", Answer},
+		}},
+		{"reinvite", []step{
+			{Invite, "offer", Offer},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+			{Invite, "other", Offer},
+			{Update, "offer", -1},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+		}},
+		{"reinvite glare", []step{
+			{Invite, "offer", Offer},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+			{Invite, "other", Offer},
+			{InviteFailure, "", NoRole},
+			// The withdrawn offer is not the last offer any more.
+			{Ack, "other", -1},
+			{Invite, "offer", Offer},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+		}},
+		{"failed invite without offer", []step{
+			{Invite, "", NoRole},
+			{ReliableProvisional, "offer", Offer},
+			{InviteFailure, "", NoRole},
+			{Invite, "other", Offer},
+			{InviteSuccess, "answer", Answer},
+			{Ack, "", NoRole},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var o OfferAnswer
+			for i, step := range test.steps {
+				role, err := o.Process(step.message, descriptions[step.sdp])
+				if step.role < 0 {
+					if err == nil {
+						t.Fatalf("step %v: %v was accepted", i, step.message)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %v: %v", i, err)
+				}
+				if role != step.role {
+					t.Fatalf("step %v: wrong role of %v: %v", i, step.message, role)
+				}
+			}
+			if _, pending := o.Pending(); pending {
+				t.Fatal("offer is not answered")
+			}
+		})
+	}
+}
+
+func TestWithdrawnOffer(t *testing.T) {
+	offer := testSession(t, testSDP)
+	answer := testSession(t, strings.Replace(testSDP, "192.0.2.1", "198.51.100.1", -1))
+	other := testSession(t, strings.Replace(testSDP, "49170", "50000", -1))
+
+	var o OfferAnswer
+	for _, step := range []struct {
+		message Message
+		s       *sdp.Session
+	}{
+		{Invite, offer}, {InviteSuccess, answer}, {Ack, nil},
+		{Invite, other}, {InviteFailure, nil},
+		{Update, other}, {UpdateFailure, nil},
+	} {
+		if _, err := o.Process(step.message, step.s); err != nil {
+			t.Fatal(err)
+		}
+		if step.s == nil && (o.Offer != offer || o.Answer != answer) {
+			t.Fatalf("%v: the last agreed offer and answer were not restored", step.message)
+		}
+	}
+}